@@ -0,0 +1,86 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"flag"
+	"fmt"
+
+	"github.com/cicconee/weather-app/internal/admin"
+	"github.com/cicconee/weather-app/internal/nws"
+	"github.com/cicconee/weather-app/internal/pool"
+	"github.com/cicconee/weather-app/internal/state"
+)
+
+// run executes the subcommand name with args.
+func run(db *sql.DB, name string, args []string) error {
+	switch name {
+	case "ingest-state":
+		return ingestState(db, args)
+	case "create-admin":
+		return createAdmin(db, args)
+	default:
+		return fmt.Errorf("unknown command %q", name)
+	}
+}
+
+// ingestState saves a state and its zones to the
+// database without starting the server.
+//
+// Usage: weather-app ingest-state <state>
+func ingestState(db *sql.DB, args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("usage: weather-app ingest-state <state>")
+	}
+
+	pool := pool.New(10, 100)
+	pool.Start()
+
+	states, err := state.New(nws.NewClient("", nwsTimeout), db, pool)
+	if err != nil {
+		return err
+	}
+	states.InlineGeometry = inlineGeometry
+	states.MaxZones = maxZones
+	result, err := states.Save(context.Background(), args[0])
+	if err != nil {
+		return fmt.Errorf("failed to ingest state %q: %w", args[0], err)
+	}
+
+	fmt.Printf("saved %s: %d/%d zones written\n", result.State, len(result.Writes), result.TotalZones())
+	for _, f := range result.Fails {
+		fmt.Printf("  failed %s (%s %s)\n", f.URI, f.Type, f.Code)
+	}
+
+	return nil
+}
+
+// createAdmin creates a approved admin without starting
+// the server. This is how the first admin of a
+// deployment is created.
+//
+// Usage: weather-app create-admin -username <name> -password <password> [-superadmin]
+func createAdmin(db *sql.DB, args []string) error {
+	fs := flag.NewFlagSet("create-admin", flag.ContinueOnError)
+	username := fs.String("username", "", "the username of the admin")
+	password := fs.String("password", "", "the password of the admin")
+	superadmin := fs.Bool("superadmin", false, "allow the admin to manage other admins")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if *username == "" || *password == "" {
+		return fmt.Errorf("usage: weather-app create-admin -username <name> -password <password> [-superadmin]")
+	}
+
+	// Creating a admin does not sign tokens, so no
+	// secret is needed.
+	admins := admin.New(nil, db)
+	if err := admins.Create(context.Background(), *username, *password, *superadmin); err != nil {
+		return fmt.Errorf("failed to create admin %q: %w", *username, err)
+	}
+
+	fmt.Printf("created admin %s\n", *username)
+
+	return nil
+}
@@ -1,30 +1,69 @@
 package main
 
 import (
+	"context"
 	"database/sql"
 	"flag"
 	"fmt"
 	"log"
+	"strings"
 	"time"
 
 	"github.com/cicconee/weather-app/internal/admin"
 	"github.com/cicconee/weather-app/internal/alert"
+	"github.com/cicconee/weather-app/internal/database"
 	"github.com/cicconee/weather-app/internal/forecast"
 	"github.com/cicconee/weather-app/internal/nws"
 	"github.com/cicconee/weather-app/internal/pool"
 	"github.com/cicconee/weather-app/internal/server"
 	"github.com/cicconee/weather-app/internal/state"
-	"github.com/go-chi/chi/v5"
 	_ "github.com/lib/pq"
 )
 
-var port string
-
-// TODO: Make secretKey a environment variable.
-var secretKey = "secret-key"
+var (
+	port           string
+	secureCookie   bool
+	exposeUpstream bool
+	nwsTimeout     time.Duration
+	hourlyTimeout  time.Duration
+	readOnly       bool
+	inlineGeometry bool
+	maxZones       int
+	includePast    bool
+	refreshWithin  time.Duration
+	refreshEvery   time.Duration
+	skyCover       bool
+	alertStatus    string
+	alertScope     string
+	alertAreas     string
+	allowReset     bool
+	rateLimit      float64
+	rateBurst      int
+	noForecastTTL  time.Duration
+	forceRefresh   time.Duration
+)
 
 func main() {
 	flag.StringVar(&port, "p", "8080", "the port the server should listen on")
+	flag.BoolVar(&secureCookie, "secure-cookie", false, "mark the admin cookie as Secure (enable when served over TLS)")
+	flag.BoolVar(&exposeUpstream, "expose-upstream", false, "include NWS API errors in error responses for admins")
+	flag.DurationVar(&nwsTimeout, "nws-timeout", nws.DefaultTimeout, "the time limit for requests to the NWS API")
+	flag.DurationVar(&hourlyTimeout, "nws-hourly-timeout", 0, "the time limit for hourly forecast requests to the NWS API (0 uses -nws-timeout)")
+	flag.BoolVar(&readOnly, "read-only", false, "serve only stored data and disable all writes")
+	flag.BoolVar(&inlineGeometry, "inline-zone-geometry", false, "get zone geometry with the zones of a state instead of a request per zone")
+	flag.IntVar(&maxZones, "max-zones", state.DefaultMaxZones, "the most zones a state may have when it is saved or synced")
+	flag.BoolVar(&includePast, "include-past-periods", false, "include forecast periods that already ended (for debugging)")
+	flag.DurationVar(&refreshWithin, "forecast-refresh-within", 10*time.Minute, "refresh forecasts in the background this close to expiring (0 disables)")
+	flag.DurationVar(&refreshEvery, "forecast-refresh-interval", 0, "how often to refresh stored forecasts within -forecast-refresh-within of expiring before they are requested (0 disables)")
+	flag.DurationVar(&noForecastTTL, "no-forecast-ttl", forecast.DefaultNoForecastTTL, "how long to remember points without a forecast before asking the NWS API again (negative disables)")
+	flag.DurationVar(&forceRefresh, "force-refresh-interval", forecast.DefaultForceRefreshInterval, "the least time between refreshes of a forecast forced by a Cache-Control: no-cache request (negative disables)")
+	flag.BoolVar(&skyCover, "sky-cover", false, "include the hourly sky cover in forecasts (an extra NWS API request each time a forecast expires)")
+	flag.StringVar(&alertStatus, "alert-status", nws.AlertStatusActual, "the status of alerts to sync (actual, exercise, system, test, draft); only actual alerts are served")
+	flag.StringVar(&alertScope, "alert-scope", string(nws.AlertScopeArea), "how alerts are scoped (area, zone, region); area syncs the stored states")
+	flag.StringVar(&alertAreas, "alert-areas", "", "comma separated zone or region codes to sync alerts for when -alert-scope is zone or region")
+	flag.BoolVar(&allowReset, "allow-reset", false, "allow superadmins to delete all forecast, alert, and zone data (test environments only)")
+	flag.Float64Var(&rateLimit, "rate-limit", 0, "the requests per second each client IP may make to the public routes (0 disables)")
+	flag.IntVar(&rateBurst, "rate-burst", 10, "the requests a client IP may make at once before -rate-limit applies")
 	flag.Parse()
 
 	psqlInfo := fmt.Sprintf("postgres://%s:%s@%s:%s/%s?sslmode=disable", "weather_app", "password", "0.0.0.0", "5432", "weather_app_db")
@@ -33,20 +72,85 @@ func main() {
 		log.Fatalln(err)
 	}
 
+	if err := database.EnsureSpatialIndexes(context.Background(), db, log.Default()); err != nil {
+		log.Fatalln(err)
+	}
+
+	// Run the subcommand if one is given, otherwise
+	// start the server.
+	if flag.NArg() > 0 {
+		if err := run(db, flag.Arg(0), flag.Args()[1:]); err != nil {
+			log.Fatalln(err)
+		}
+		return
+	}
+
+	secret, err := loadSecret()
+	if err != nil {
+		log.Fatalln(err)
+	}
+
 	// Create a Pool with 10 workers each
 	// with a channel size of 100.
 	pool := pool.New(10, 100)
 	pool.Start()
 
-	srv := server.Server{
+	metrics := &nws.CallCounter{}
+	client := nws.NewClient("", nwsTimeout)
+	client.Metrics = metrics
+	if hourlyTimeout > 0 {
+		client.Timeouts = map[string]time.Duration{nws.EndpointHourly: hourlyTimeout}
+	}
+
+	states, err := state.New(client, db, pool)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	states.InlineGeometry = inlineGeometry
+	states.MaxZones = maxZones
+
+	alerts := alert.New(client, db)
+	alerts.Status = alertStatus
+	alerts.Scope, err = nws.ParseAlertScope(alertScope)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	if alertAreas != "" {
+		alerts.Areas = strings.Split(alertAreas, ",")
+	}
+
+	forecasts := forecast.New(client, db)
+	forecasts.IncludePast = includePast
+	forecasts.RefreshWithin = refreshWithin
+	forecasts.SkyCover = skyCover
+	forecasts.NoForecastTTL = noForecastTTL
+	forecasts.ForceRefreshInterval = forceRefresh
+	forecasts.Pool = pool
+
+	cfg := server.Config{
 		Addr:      port,
-		Router:    chi.NewRouter(),
 		Interval:  10 * time.Second,
-		Logger:    log.Default(),
-		States:    state.New(nws.DefaultClient, db, pool),
-		Alerts:    alert.New(nws.DefaultClient, db),
-		Forecasts: forecast.New(nws.DefaultClient, db),
-		Admins:    admin.New([]byte(secretKey), db),
+		States:    states,
+		Alerts:    alerts,
+		Forecasts: forecasts,
+		Admins:    admin.New(secret, db),
+		Metrics:   metrics,
+		Freshness: forecast.NewFreshnessGauge(db),
+
+		SecureCookie:         secureCookie,
+		ExposeUpstreamErrors: exposeUpstream,
+		ReadOnly:             readOnly,
+		RefreshInterval:      refreshEvery,
+		RateLimit:            rateLimit,
+		RateBurst:            rateBurst,
+	}
+	if allowReset {
+		cfg.Resetter = database.NewResetter(db)
+	}
+
+	srv, err := server.New(cfg)
+	if err != nil {
+		log.Fatalln(err)
 	}
 	if err := srv.Start(); err != nil {
 		log.Println(err)
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+)
+
+// MinSecretLength is the minimum length in bytes of the
+// secret used to sign admin access tokens.
+const MinSecretLength = 32
+
+// loadSecret returns the secret used to sign admin access
+// tokens. The secret is read from the file at JWT_SECRET_FILE
+// if set, otherwise from JWT_SECRET. An error is returned if
+// neither is set or the secret is shorter than MinSecretLength.
+func loadSecret() ([]byte, error) {
+	var secret []byte
+
+	if path := os.Getenv("JWT_SECRET_FILE"); path != "" {
+		b, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("reading JWT_SECRET_FILE: %w", err)
+		}
+		secret = bytes.TrimSpace(b)
+	} else {
+		secret = []byte(os.Getenv("JWT_SECRET"))
+	}
+
+	if len(secret) == 0 {
+		return nil, fmt.Errorf("JWT_SECRET or JWT_SECRET_FILE must be set")
+	}
+
+	if len(secret) < MinSecretLength {
+		return nil, fmt.Errorf("JWT secret must be at least %d bytes", MinSecretLength)
+	}
+
+	return secret, nil
+}
@@ -7,4 +7,5 @@ require (
 	github.com/golang-jwt/jwt/v4 v4.5.0
 	github.com/lib/pq v1.10.9
 	golang.org/x/crypto v0.11.0
+	golang.org/x/sync v0.3.0
 )
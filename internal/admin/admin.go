@@ -11,20 +11,32 @@ import (
 	"golang.org/x/crypto/bcrypt"
 )
 
+// PasswordCost is the bcrypt cost used when hashing
+// admin passwords. Hashes stored with a lower cost are
+// upgraded the next time the admin logs in.
+const PasswordCost = 14
+
 type Account struct {
-	ID       int
-	Approved bool
+	ID         int
+	Approved   bool
+	Superadmin bool
 }
 
 func (a *Account) IsApproved() bool {
 	return a.Approved
 }
 
+func (a *Account) IsSuperadmin() bool {
+	return a.Superadmin
+}
+
 type AdminEntity struct {
 	ID           int
 	Username     string
 	PasswordHash string
 	Approved     bool
+	Superadmin   bool
+	Deactivated  bool
 	CreatedAt    time.Time
 }
 
@@ -49,7 +61,7 @@ func (a *AdminEntity) SetPasswordHash(password string) error {
 		}
 	}
 
-	passwordHash, err := bcrypt.GenerateFromPassword([]byte(password), 14)
+	passwordHash, err := bcrypt.GenerateFromPassword([]byte(password), PasswordCost)
 	if err != nil {
 		return err
 	}
@@ -64,14 +76,37 @@ func (a *AdminEntity) CheckPasswordHash(p string) bool {
 	return err == nil
 }
 
+// NeedsRehash reports if the PasswordHash was hashed
+// with a cost lower than PasswordCost.
+func (a *AdminEntity) NeedsRehash() bool {
+	cost, err := bcrypt.Cost([]byte(a.PasswordHash))
+	if err != nil {
+		return false
+	}
+
+	return cost < PasswordCost
+}
+
 func (a *AdminEntity) IsApproved() bool {
 	return a.Approved
 }
 
+func (a *AdminEntity) IsSuperadmin() bool {
+	return a.Superadmin
+}
+
+// IsDeactivated reports if this admin has been soft deleted.
+// A deactivated admin cannot login and their tokens are
+// rejected.
+func (a *AdminEntity) IsDeactivated() bool {
+	return a.Deactivated
+}
+
 func (a *AdminEntity) Account() Account {
 	return Account{
-		ID:       a.ID,
-		Approved: a.Approved,
+		ID:         a.ID,
+		Approved:   a.Approved,
+		Superadmin: a.Superadmin,
 	}
 }
 
@@ -81,33 +116,61 @@ func (s *AdminEntity) Scan(scanner func(...any) error) error {
 		&s.Username,
 		&s.PasswordHash,
 		&s.Approved,
+		&s.Superadmin,
+		&s.Deactivated,
 		&s.CreatedAt,
 	)
 }
 
 func (s *AdminEntity) Select(ctx context.Context, db *sql.DB) error {
-	query := `SELECT id, username, password_hash, approved, created_at
-			  FROM admins WHERE id = $1`
+	query := `SELECT id, username, password_hash, approved, superadmin, deactivated, 
+			  created_at FROM admins WHERE id = $1`
 
 	return s.Scan(db.QueryRowContext(ctx, query, s.ID).Scan)
 }
 
 func (s *AdminEntity) SelectWhereUsername(ctx context.Context, db *sql.DB) error {
-	query := `SELECT id, username, password_hash, approved, created_at
-			  FROM admins WHERE username = $1`
+	query := `SELECT id, username, password_hash, approved, superadmin, deactivated, 
+			  created_at FROM admins WHERE username = $1`
 
 	return s.Scan(db.QueryRowContext(ctx, query, s.Username).Scan)
 }
 
 func (s *AdminEntity) Insert(ctx context.Context, db *sql.DB) error {
-	query := `INSERT INTO admins(username, password_hash, approved, created_at) 
-			  VALUES($1, $2, $3, $4)`
+	query := `INSERT INTO admins(username, password_hash, approved, superadmin, created_at) 
+			  VALUES($1, $2, $3, $4, $5)`
 
 	_, err := db.ExecContext(ctx, query,
 		s.Username,
 		s.PasswordHash,
 		s.Approved,
+		s.Superadmin,
 		s.CreatedAt)
 
 	return err
 }
+
+// UpdatePasswordHash writes the PasswordHash of this admin
+// to the database. ID must be set before calling this func.
+func (s *AdminEntity) UpdatePasswordHash(ctx context.Context, db *sql.DB) error {
+	query := `UPDATE admins SET password_hash = $1 WHERE id = $2`
+
+	_, err := db.ExecContext(ctx, query, s.PasswordHash, s.ID)
+	return err
+}
+
+// UpdateDeactivated writes the Deactivated field of this admin
+// to the database. ID must be set before calling this func.
+func (s *AdminEntity) UpdateDeactivated(ctx context.Context, db *sql.DB) error {
+	query := `UPDATE admins SET deactivated = $1 WHERE id = $2`
+
+	_, err := db.ExecContext(ctx, query, s.Deactivated, s.ID)
+	return err
+}
+
+// Delete deletes this admin from the database. ID must be set
+// before calling this func.
+func (s *AdminEntity) Delete(ctx context.Context, db *sql.DB) error {
+	_, err := db.ExecContext(ctx, `DELETE FROM admins WHERE id = $1`, s.ID)
+	return err
+}
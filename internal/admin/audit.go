@@ -0,0 +1,110 @@
+package admin
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	"github.com/cicconee/weather-app/internal/app"
+)
+
+const (
+	// AuditSuccess is the result of a audited action that
+	// completed successfully.
+	AuditSuccess = "success"
+
+	// AuditFailure is the result of a audited action that
+	// failed.
+	AuditFailure = "failure"
+)
+
+// AuditEntry is a record of a action an admin performed.
+type AuditEntry struct {
+	ID int `json:"id"`
+
+	// The id of the admin that performed the action.
+	AdminID int `json:"admin_id"`
+
+	// The action performed (e.g. "state.create").
+	Action string `json:"action"`
+
+	// What the action was performed on (e.g. a state
+	// id or admin id). May be empty.
+	Target string `json:"target"`
+
+	// The result of the action, either AuditSuccess
+	// or AuditFailure.
+	Result string `json:"result"`
+
+	// The HTTP status code the action responded with.
+	Status int `json:"status"`
+
+	// The time the action was performed.
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// MarshalJSON formats the CreatedAt time as app.Time.
+func (a AuditEntry) MarshalJSON() ([]byte, error) {
+	type auditEntry AuditEntry
+	return json.Marshal(struct {
+		auditEntry
+		CreatedAt app.Time `json:"created_at"`
+	}{
+		auditEntry: auditEntry(a),
+		CreatedAt:  app.Time(a.CreatedAt),
+	})
+}
+
+func (a *AuditEntry) Scan(scanner func(...any) error) error {
+	return scanner(
+		&a.ID,
+		&a.AdminID,
+		&a.Action,
+		&a.Target,
+		&a.Result,
+		&a.Status,
+		&a.CreatedAt,
+	)
+}
+
+// Insert writes this audit entry into the database and
+// sets the ID field.
+func (a *AuditEntry) Insert(ctx context.Context, db *sql.DB) error {
+	query := `INSERT INTO admin_audit_log(admin_id, action, target, result, status, created_at)
+			  VALUES($1, $2, $3, $4, $5, $6) RETURNING id`
+
+	return db.QueryRowContext(ctx, query,
+		a.AdminID,
+		a.Action,
+		a.Target,
+		a.Result,
+		a.Status,
+		a.CreatedAt).Scan(&a.ID)
+}
+
+// AuditLog is a collection of audit entries.
+type AuditLog []AuditEntry
+
+// Select reads the most recent audit entries into this
+// audit log, skipping the first offset entries.
+func (a *AuditLog) Select(ctx context.Context, db *sql.DB, limit int, offset int) error {
+	query := `SELECT id, admin_id, action, target, result, status, created_at
+			  FROM admin_audit_log ORDER BY created_at DESC, id DESC LIMIT $1 OFFSET $2`
+
+	rows, err := db.QueryContext(ctx, query, limit, offset)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var entry AuditEntry
+		if err := entry.Scan(rows.Scan); err != nil {
+			return err
+		}
+		*a = append(*a, entry)
+	}
+
+	return rows.Err()
+}
@@ -5,6 +5,7 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
+	"log"
 	"net/http"
 	"strconv"
 	"time"
@@ -13,9 +14,29 @@ import (
 	"github.com/golang-jwt/jwt/v4"
 )
 
+// TokenTTL is how long a admin access token is valid for after
+// it is issued by Login.
+const TokenTTL = time.Hour
+
+// TokenLeeway is the allowed clock skew when validating the
+// time based claims (exp, nbf, iat) of a admin access token.
+const TokenLeeway = 30 * time.Second
+
 type Service struct {
 	Secret []byte
 	DB     *sql.DB
+
+	// Clock is the time the service reads as now, such as to issue
+	// and expire tokens. Nil uses the system time.
+	Clock app.Clock
+
+	// ReadOnly skips the writes Login would make, so a password
+	// hash created with an outdated cost is not upgraded.
+	ReadOnly bool
+}
+
+func (s *Service) now() time.Time {
+	return app.Now(s.Clock)
 }
 
 func New(secret []byte, db *sql.DB) *Service {
@@ -28,8 +49,24 @@ func New(secret []byte, db *sql.DB) *Service {
 // Signup will create a admin and store it into the database. A admin will only
 // signup successfully if the username is not in use.
 func (s *Service) Signup(ctx context.Context, username string, password string) error {
-	admin := AdminEntity{Username: username}
+	return s.create(ctx, AdminEntity{Username: username}, password)
+}
+
+// Create will create a approved admin and store it into the database.
+// It is used to bootstrap a deployment, since admins created through
+// Signup are not approved. superadmin sets whether the admin can
+// manage other admins.
+func (s *Service) Create(ctx context.Context, username string, password string, superadmin bool) error {
+	return s.create(ctx, AdminEntity{
+		Username:   username,
+		Approved:   true,
+		Superadmin: superadmin,
+	}, password)
+}
 
+// create validates and inserts admin, hashing password. The
+// username of admin must not be in use.
+func (s *Service) create(ctx context.Context, admin AdminEntity, password string) error {
 	// Check if username is in use.
 	err := admin.SelectWhereUsername(ctx, s.DB)
 	if err == nil {
@@ -52,8 +89,7 @@ func (s *Service) Signup(ctx context.Context, username string, password string)
 		return fmt.Errorf("Validating username: %w", err)
 	}
 
-	admin.Approved = false
-	admin.CreatedAt = time.Now().UTC()
+	admin.CreatedAt = s.now().UTC()
 
 	// Insert admin.
 	if err := admin.Insert(ctx, s.DB); err != nil {
@@ -95,6 +131,23 @@ func (s *Service) Login(ctx context.Context, username string, password string) (
 		}
 	}
 
+	if admin.IsDeactivated() {
+		return "", &app.ServerResponseError{
+			Err:        fmt.Errorf("admin deactivated (id=%d)", admin.ID),
+			Msg:        "Your account has been deactivated",
+			StatusCode: http.StatusUnauthorized,
+		}
+	}
+
+	// The credentials are valid, so the raw password can be used to
+	// upgrade a hash created with an outdated cost. A failed upgrade
+	// should not prevent the admin from logging in.
+	if !s.ReadOnly && admin.NeedsRehash() {
+		if err := s.rehash(ctx, &admin, password); err != nil {
+			log.Printf("failed to upgrade password hash (id=%d): %v\n", admin.ID, err)
+		}
+	}
+
 	if !admin.IsApproved() {
 		return "", &app.ServerResponseError{
 			Err:        errors.New("admin not approved"),
@@ -105,8 +158,11 @@ func (s *Service) Login(ctx context.Context, username string, password string) (
 
 	token := jwt.New(jwt.SigningMethodHS256)
 	claims := token.Claims.(jwt.MapClaims)
+	now := s.now()
 	claims["sub"] = fmt.Sprintf("%d", admin.ID)
-	claims["exp"] = time.Now().Add(time.Hour).Unix()
+	claims["iat"] = now.Unix()
+	claims["nbf"] = now.Unix()
+	claims["exp"] = now.Add(TokenTTL).Unix()
 
 	tokenStr, err := token.SignedString(s.Secret)
 	if err != nil {
@@ -116,6 +172,20 @@ func (s *Service) Login(ctx context.Context, username string, password string) (
 	return tokenStr, nil
 }
 
+// rehash hashes password with the current PasswordCost and
+// writes it to the database as the admins PasswordHash.
+func (s *Service) rehash(ctx context.Context, admin *AdminEntity, password string) error {
+	if err := admin.SetPasswordHash(password); err != nil {
+		return fmt.Errorf("setting password hash: %w", err)
+	}
+
+	if err := admin.UpdatePasswordHash(ctx, s.DB); err != nil {
+		return fmt.Errorf("updating password hash: %w", err)
+	}
+
+	return nil
+}
+
 // Validate will parse and validate a token. If the token belongs to an
 // admin, the admin account will be returned. Any errors that occur while
 // parsing or validating the token will be returned.
@@ -134,7 +204,9 @@ func (s *Service) Validate(ctx context.Context, tokenStr string) (Account, error
 			}
 			return s.Secret, nil
 		},
-		jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Name}))
+		jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Name}),
+		// The time based claims are validated below with TokenLeeway.
+		jwt.WithoutClaimsValidation())
 	if err != nil {
 		return Account{}, &app.ServerResponseError{
 			Err:        fmt.Errorf("parsing token: %w", err),
@@ -152,7 +224,8 @@ func (s *Service) Validate(ctx context.Context, tokenStr string) (Account, error
 		return Account{}, errors.New("Could not get token claims")
 	}
 
-	if !claims.VerifyExpiresAt(time.Now().Unix(), true) {
+	now := s.now()
+	if !claims.VerifyExpiresAt(now.Add(-TokenLeeway).Unix(), true) {
 		return Account{}, &app.ServerResponseError{
 			Err:        errors.New("Token is expired"),
 			Msg:        "Please login",
@@ -160,6 +233,25 @@ func (s *Service) Validate(ctx context.Context, tokenStr string) (Account, error
 		}
 	}
 
+	if !claims.VerifyNotBefore(now.Add(TokenLeeway).Unix(), true) {
+		return Account{}, &app.ServerResponseError{
+			Err:        errors.New("Token used before nbf"),
+			Msg:        "Please login",
+			StatusCode: http.StatusUnauthorized,
+		}
+	}
+
+	// A token issued in the future, or longer ago than a token can
+	// live, was not issued by Login.
+	if !claims.VerifyIssuedAt(now.Add(TokenLeeway).Unix(), true) ||
+		claims.VerifyIssuedAt(now.Add(-TokenTTL-TokenLeeway).Unix(), true) {
+		return Account{}, &app.ServerResponseError{
+			Err:        errors.New("Token has implausible iat"),
+			Msg:        "Please login",
+			StatusCode: http.StatusUnauthorized,
+		}
+	}
+
 	subClaim, ok := claims["sub"]
 	if !ok {
 		// This should never return since only tokens that will parse successfully
@@ -200,5 +292,145 @@ func (s *Service) Validate(ctx context.Context, tokenStr string) (Account, error
 		return Account{}, fmt.Errorf("selecting admin: %w", err)
 	}
 
+	if admin.IsDeactivated() {
+		return Account{}, &app.ServerResponseError{
+			Err:        fmt.Errorf("admin deactivated (id=%d)", admin.ID),
+			Msg:        "Your account has been deactivated",
+			StatusCode: http.StatusUnauthorized,
+		}
+	}
+
 	return admin.Account(), nil
 }
+
+// Delete will permanently delete the admin with the id targetID. The
+// admin performing the delete (actorID) must be a superadmin and cannot
+// delete themselves.
+//
+// Any outstanding tokens of the deleted admin are rejected by Validate,
+// since Validate verifies the admin still exists.
+func (s *Service) Delete(ctx context.Context, actorID int, targetID int) error {
+	target, err := s.target(ctx, actorID, targetID)
+	if err != nil {
+		return err
+	}
+
+	if err := target.Delete(ctx, s.DB); err != nil {
+		return fmt.Errorf("deleting admin (id=%d): %w", target.ID, err)
+	}
+
+	return nil
+}
+
+// Deactivate will soft delete the admin with the id targetID. The admin
+// performing the deactivation (actorID) must be a superadmin and cannot
+// deactivate themselves.
+//
+// A deactivated admin remains in the database but cannot login, and any
+// outstanding tokens are rejected by Validate.
+func (s *Service) Deactivate(ctx context.Context, actorID int, targetID int) error {
+	target, err := s.target(ctx, actorID, targetID)
+	if err != nil {
+		return err
+	}
+
+	target.Deactivated = true
+	if err := target.UpdateDeactivated(ctx, s.DB); err != nil {
+		return fmt.Errorf("deactivating admin (id=%d): %w", target.ID, err)
+	}
+
+	return nil
+}
+
+// Audit writes entry to the audit log. The entry CreatedAt field
+// will be set.
+func (s *Service) Audit(ctx context.Context, entry AuditEntry) error {
+	entry.CreatedAt = s.now().UTC()
+	if err := entry.Insert(ctx, s.DB); err != nil {
+		return fmt.Errorf("inserting audit entry (adminID=%d, action=%s): %w", entry.AdminID, entry.Action, err)
+	}
+
+	return nil
+}
+
+// MaxAuditLimit is the most audit entries that AuditLog will
+// return at once.
+const MaxAuditLimit = 500
+
+// AuditLog returns the most recent audit entries, skipping the first
+// offset entries. The admin reading the log (actorID) must be a
+// superadmin. A limit of zero or more than MaxAuditLimit will be set
+// to MaxAuditLimit.
+func (s *Service) AuditLog(ctx context.Context, actorID int, limit int, offset int) (AuditLog, error) {
+	if err := s.superadmin(ctx, actorID); err != nil {
+		return AuditLog{}, err
+	}
+
+	if limit <= 0 || limit > MaxAuditLimit {
+		limit = MaxAuditLimit
+	}
+
+	entries := AuditLog{}
+	if err := entries.Select(ctx, s.DB, limit, offset); err != nil {
+		return AuditLog{}, fmt.Errorf("selecting audit log: %w", err)
+	}
+
+	return entries, nil
+}
+
+// RequireSuperadmin returns an error if the admin with the id actorID
+// is not a superadmin. It guards actions outside of this Service that
+// only superadmins may perform.
+func (s *Service) RequireSuperadmin(ctx context.Context, actorID int) error {
+	return s.superadmin(ctx, actorID)
+}
+
+// superadmin verifies the admin with the id actorID is a superadmin.
+func (s *Service) superadmin(ctx context.Context, actorID int) error {
+	actor := AdminEntity{ID: actorID}
+	if err := actor.Select(ctx, s.DB); err != nil {
+		return fmt.Errorf("selecting actor (id=%d): %w", actorID, err)
+	}
+
+	if !actor.IsSuperadmin() {
+		return &app.ServerResponseError{
+			Err:        fmt.Errorf("admin not superadmin (id=%d)", actor.ID),
+			Msg:        "Only superadmins can perform this action",
+			StatusCode: http.StatusForbidden,
+		}
+	}
+
+	return nil
+}
+
+// target verifies the admin with the id actorID is a superadmin that
+// can act on the admin with the id targetID. The target admin is
+// returned.
+func (s *Service) target(ctx context.Context, actorID int, targetID int) (AdminEntity, error) {
+	if err := s.superadmin(ctx, actorID); err != nil {
+		return AdminEntity{}, err
+	}
+
+	if actorID == targetID {
+		return AdminEntity{}, &app.ServerResponseError{
+			Err:        fmt.Errorf("admin acting on self (id=%d)", actorID),
+			Msg:        "You cannot perform this action on your own account",
+			StatusCode: http.StatusBadRequest,
+		}
+	}
+
+	target := AdminEntity{ID: targetID}
+	if err := target.Select(ctx, s.DB); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return AdminEntity{}, &app.ServerResponseError{
+				Err:        fmt.Errorf("admin not found (id=%d)", targetID),
+				Msg:        "Admin not found",
+				StatusCode: http.StatusNotFound,
+			}
+		}
+
+		return AdminEntity{}, fmt.Errorf("selecting target (id=%d): %w", targetID, err)
+	}
+
+	return target, nil
+}
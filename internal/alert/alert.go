@@ -3,8 +3,11 @@ package alert
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
+	"fmt"
 	"time"
 
+	"github.com/cicconee/weather-app/internal/app"
 	"github.com/cicconee/weather-app/internal/geometry"
 )
 
@@ -25,6 +28,100 @@ type Response struct {
 	Description string     `json:"description"`
 	Instruction string     `json:"instruction"`
 	Response    string     `json:"response"`
+	SenderName  string     `json:"sender_name"`
+	Parameters  Parameters `json:"parameters"`
+
+	// Expires is only used to project when the
+	// alert expires if Ends is nil.
+	Expires time.Time `json:"-"`
+
+	// MessageType and Sent are only used to write
+	// the alert in the CAP format.
+	MessageType string    `json:"-"`
+	Sent        time.Time `json:"-"`
+
+	// now is the time the seconds until the alert
+	// expires are projected from.
+	now time.Time
+}
+
+// MarshalJSON formats the OnSet, Ends and event ending
+// times as app.Time. A nil time is omitted. The seconds until
+// the alert expires are projected from the time the
+// response was made at.
+func (r Response) MarshalJSON() ([]byte, error) {
+	return json.Marshal(r.jsonResponse())
+}
+
+type response Response
+
+// jsonResponse is the JSON form of a Response.
+type jsonResponse struct {
+	response
+	OnSet      *app.Time      `json:"starts,omitempty"`
+	Ends       *app.Time      `json:"ends,omitempty"`
+	ExpiresIn  *int64         `json:"expiresInSeconds,omitempty"`
+	Parameters jsonParameters `json:"parameters"`
+}
+
+type parameters Parameters
+
+// jsonParameters is the JSON form of the Parameters of
+// a Response.
+type jsonParameters struct {
+	parameters
+	EventEndingTime *app.Time `json:"event_ending_time,omitempty"`
+}
+
+func (r Response) jsonResponse() jsonResponse {
+	return jsonResponse{
+		response:  response(r),
+		OnSet:     app.TimePtr(r.OnSet),
+		Ends:      app.TimePtr(r.Ends),
+		ExpiresIn: r.expiresIn(r.now),
+		Parameters: jsonParameters{
+			parameters:      parameters(r.Parameters),
+			EventEndingTime: app.TimePtr(r.Parameters.EventEndingTime),
+		},
+	}
+}
+
+// expiresIn returns the whole seconds from now until
+// the alert ends, or expires if it has no end time.
+// It returns nil if that time is not after now.
+func (r Response) expiresIn(now time.Time) *int64 {
+	end := r.Expires
+	if r.Ends != nil && !r.Ends.IsZero() {
+		end = *r.Ends
+	}
+
+	if !end.After(now) {
+		return nil
+	}
+
+	seconds := int64(end.Sub(now) / time.Second)
+	return &seconds
+}
+
+// In returns this response with the OnSet, Ends and
+// event ending times in loc.
+func (r Response) In(loc *time.Location) Response {
+	if r.OnSet != nil {
+		onset := r.OnSet.In(loc)
+		r.OnSet = &onset
+	}
+
+	if r.Ends != nil {
+		ends := r.Ends.In(loc)
+		r.Ends = &ends
+	}
+
+	if r.Parameters.EventEndingTime != nil {
+		ending := r.Parameters.EventEndingTime.In(loc)
+		r.Parameters.EventEndingTime = &ending
+	}
+
+	return r
 }
 
 // Resource is a alert and all its relationships.
@@ -49,6 +146,11 @@ type Resource struct {
 	Zones []Zone
 }
 
+// StatusActual is the status of alerts that are not
+// exercises, tests, system messages or drafts. Alerts
+// of any other status are stored but never served.
+const StatusActual = "Actual"
+
 // Alert is a alert for a geographical location.
 type Alert struct {
 	// The alert identifier.
@@ -77,6 +179,11 @@ type Alert struct {
 	// Cancel).
 	MessageType string
 
+	// The alert status (Actual, Exercise, System,
+	// Test, Draft). Only alerts with a status of
+	// StatusActual are served.
+	Status string
+
 	// The code denoting the category of the
 	// subject event of the alert message
 	// (Met, Geo, Safety, Security, Rescue, Fire,
@@ -118,6 +225,16 @@ type Alert struct {
 	// Avoid, Monitor, Assess, AllClear, None).
 	Response string
 
+	// The name of the NWS office that issued the
+	// alert (e.g. "NWS Boulder CO"). It is empty
+	// for alerts stored before it was recorded.
+	SenderName string
+
+	// The selected NWS parameters of the alert. It
+	// is empty for alerts stored before they were
+	// recorded.
+	Parameters Parameters
+
 	// The geometric bounds of the alert. This field
 	// may be empty.
 	Points geometry.Polygon
@@ -127,8 +244,9 @@ type Alert struct {
 	CreatedAt time.Time
 }
 
-// AsResponse returns this alert as a Response.
-func (a *Alert) AsResponse() Response {
+// AsResponse returns this alert as a Response made
+// at now.
+func (a *Alert) AsResponse(now time.Time) Response {
 	return Response{
 		ID:          a.ID,
 		AreaDesc:    a.AreaDesc,
@@ -143,6 +261,12 @@ func (a *Alert) AsResponse() Response {
 		Description: a.Description,
 		Instruction: a.Instruction,
 		Response:    a.Response,
+		SenderName:  a.SenderName,
+		Parameters:  a.Parameters,
+		Expires:     a.Expires,
+		MessageType: a.MessageType,
+		Sent:        a.CreatedAt,
+		now:         now,
 	}
 }
 
@@ -163,6 +287,8 @@ func (a *Alert) Scan(scanner Scanner) error {
 		&a.Description,
 		&a.Instruction,
 		&a.Response,
+		&a.SenderName,
+		&a.Parameters,
 		&a.CreatedAt,
 	)
 }
@@ -174,7 +300,7 @@ func (a *Alert) Scan(scanner Scanner) error {
 func (a *Alert) Select(ctx context.Context, db *sql.DB) error {
 	query := `SELECT id, area_desc, onset, expires, ends, message_type, category, 
 			  severity, certainty, urgency, event, headline, description, instruction, 
-			  response, created_at FROM alerts WHERE id = $1`
+			  response, sender_name, parameters, created_at FROM alerts WHERE id = $1`
 
 	return a.Scan(db.QueryRowContext(ctx, query, a.ID))
 }
@@ -183,8 +309,8 @@ func (a *Alert) Select(ctx context.Context, db *sql.DB) error {
 func (a *Alert) Insert(ctx context.Context, db *sql.Tx) error {
 	query := `INSERT INTO alerts(id, area_desc, onset, expires, ends, message_type, category,
 			  severity, certainty, urgency, event, headline, description, instruction, response,
-			  boundary, created_at) VALUES($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, 
-			  $13, $14, $15, $16, $17)`
+			  boundary, created_at, status, sender_name, parameters) VALUES($1, $2, $3, $4, $5, $6, $7, 
+			  $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20)`
 
 	_, err := db.ExecContext(ctx, query,
 		a.ID,
@@ -203,7 +329,10 @@ func (a *Alert) Insert(ctx context.Context, db *sql.Tx) error {
 		a.Instruction,
 		a.Response,
 		a.sqlPoints(),
-		a.CreatedAt)
+		a.CreatedAt,
+		a.Status,
+		a.SenderName,
+		a.Parameters)
 
 	return err
 }
@@ -217,9 +346,13 @@ func (a *Alert) sqlEnds() sql.NullTime {
 }
 
 func (a *Alert) nullTime(t *time.Time) sql.NullTime {
+	if t == nil {
+		return sql.NullTime{}
+	}
+
 	return sql.NullTime{
 		Time:  *t,
-		Valid: !t.IsZero() && t != nil,
+		Valid: !t.IsZero(),
 	}
 }
 
@@ -237,76 +370,220 @@ func (a *Alert) sqlPoints() sql.NullString {
 type AlertCollection []Alert
 
 // ResponseCollection returns this alert
-// collection as a collection of responses.
-func (a *AlertCollection) ResponseCollection() []Response {
+// collection as a collection of responses made
+// at now.
+func (a *AlertCollection) ResponseCollection(now time.Time) []Response {
 	response := []Response{}
 	for _, alert := range *a {
-		response = append(response, alert.AsResponse())
+		response = append(response, alert.AsResponse(now))
 	}
 	return response
 }
 
-// SelectPointless reads a collection of alerts
-// that do not have a defined geometric bounds and
-// stores the alerts into this alert collection.
-// Each alert associated with a zone that contains
-// point will be read.
+// containsWhere matches the alerts where the point $2
+// resides inside the geometric bounds of the alert, or
+// the boundary of a zone the alert is mapped to. Alerts
+// with a MessageType of $1, or a Status other than
+// StatusActual, are not matched.
+const containsWhere = `message_type != $1 AND status = 'Actual' AND (boundary @> $2 OR id IN (
+		SELECT alert_zones.alert_id FROM alert_zones, state_zone_perimeters 
+		WHERE state_zone_perimeters.sz_id = alert_zones.sz_id 
+		AND state_zone_perimeters.boundary @> $2))`
+
+// SelectContainsPage reads a page of alerts where
+// the point resides inside the geometric bounds of
+// the alert, or the boundary of a zone the alert is
+// mapped to. The alerts are stored into this alert
+// collection and the total number of alerts is
+// returned.
 //
-// Alerts with a MessageType of "Cancel" will not
-// be read.
-func (a *AlertCollection) SelectPointless(ctx context.Context, db *sql.DB, point geometry.Point) error {
-	query := `SELECT a.id, a.area_desc, a.onset, a.expires, a.ends, a.message_type, a.category, 
-			  a.severity, a.certainty, a.urgency, a.event, a.headline, a.description, a.instruction, 
-			  a.response, a.created_at FROM alerts AS a, alert_zones, state_zone_perimeters 
-			  WHERE state_zone_perimeters.sz_id = alert_zones.sz_id AND alert_zones.alert_id = a.id
-			  AND a.message_type != $1 AND state_zone_perimeters.boundary @> $2`
-
-	rows, err := db.QueryContext(ctx, query, "Cancel", point.String())
-	if err != nil {
-		return err
-	}
-	defer rows.Close()
+// Alerts with a MessageType of "Cancel", or a
+// Status other than StatusActual, will not be read.
+func (a *AlertCollection) SelectContainsPage(ctx context.Context, db *sql.DB, point geometry.Point, page Page) (int, error) {
+	return a.selectPage(ctx, db, pageQuery{
+		from:    "alerts",
+		where:   containsWhere,
+		orderBy: "created_at DESC, id",
+		args:    []any{"Cancel", point.String()},
+		page:    page,
+	})
+}
 
-	for rows.Next() {
-		var alert Alert
-		if err := alert.Scan(rows); err != nil {
-			return err
-		}
-		*a = append(*a, alert)
-	}
+// SelectWhereState reads a page of alerts that
+// reside in a state and stores them into this
+// alert collection. A alert resides in a state if
+// it is mapped to a zone of the state, or its
+// geometric bounds overlaps the boundary of a zone
+// in the state. The total number of alerts is
+// returned.
+//
+// Alerts with a MessageType of "Cancel", or a
+// Status other than StatusActual, will not be read.
+func (a *AlertCollection) SelectWhereState(ctx context.Context, db *sql.DB, stateID string, page Page) (int, error) {
+	return a.selectPage(ctx, db, pageQuery{
+		from:    "alerts",
+		where:   "message_type != $1 AND status = 'Actual' AND " + stateWhere(2),
+		orderBy: "created_at DESC, id",
+		args:    []any{"Cancel", stateID},
+		page:    page,
+	})
+}
 
-	return nil
+// SelectOverlapsPage reads a page of alerts whose
+// geometric bounds, or the boundary of a zone they
+// are mapped to, overlap bbox and stores them into
+// this alert collection. The total number of alerts
+// is returned.
+//
+// Alerts with a MessageType of "Cancel", or a
+// Status other than StatusActual, will not be read.
+func (a *AlertCollection) SelectOverlapsPage(ctx context.Context, db *sql.DB, bbox geometry.BBox, page Page) (int, error) {
+	return a.selectPage(ctx, db, pageQuery{
+		from:    "alerts",
+		where:   "message_type != $1 AND status = 'Actual' AND " + overlapsWhere(2),
+		orderBy: "created_at DESC, id",
+		args:    []any{"Cancel", bbox.Polygon().Permiter().String()},
+		page:    page,
+	})
+}
+
+// stateWhere matches the alerts that reside in the
+// state with the id $n. A alert resides in a state
+// if it is mapped to a zone of the state, or its
+// geometric bounds overlaps the boundary of a zone
+// in the state.
+func stateWhere(n int) string {
+	return fmt.Sprintf(`(id IN (SELECT alert_zones.alert_id FROM alert_zones, state_zones 
+		WHERE alert_zones.sz_id = state_zones.id AND state_zones.state = $%[1]d) 
+		OR (boundary IS NOT NULL AND EXISTS (SELECT 1 FROM state_zone_perimeters, state_zones 
+		WHERE state_zone_perimeters.sz_id = state_zones.id AND state_zones.state = $%[1]d 
+		AND state_zone_perimeters.boundary && alerts.boundary)))`, n)
 }
 
-// Select reads a collection of alerts that
-// have a defined geometric bounds. Each alert
-// geometric bounds that contains point will be
-// read.
+// Search reads a page of alerts where the event,
+// headline, area description, or description
+// matches the text query q and stores them into
+// this alert collection. Alerts that are currently
+// active are read first, followed by the most
+// relevant. The total number of alerts is returned.
 //
-// Alerts with a MessageType of "Cancel" will not
-// be read.
-func (a *AlertCollection) Select(ctx context.Context, db *sql.DB, point geometry.Point) error {
+// Alerts with a MessageType of "Cancel", or a
+// Status other than StatusActual, will not be read.
+func (a *AlertCollection) Search(ctx context.Context, db *sql.DB, q string, page Page) (int, error) {
+	return a.selectPage(ctx, db, pageQuery{
+		from:  "alerts, plainto_tsquery('english', $2) AS q",
+		where: "message_type != $1 AND status = 'Actual' AND search @@ q",
+		orderBy: `(coalesce(onset, created_at) <= now() AND coalesce(ends, expires) > now()) DESC, 
+				  ts_rank(search, q) DESC, id`,
+		args: []any{"Cancel", q},
+		page: page,
+	})
+}
+
+// metersPerDegree is the approximate number of
+// meters in one degree of latitude. The boundary
+// columns are stored in degrees, so distances are
+// converted with this ratio.
+const metersPerDegree = 111320.0
+
+// NearbyAlert is a alert and its distance from
+// a point.
+type NearbyAlert struct {
+	Alert
+
+	// The distance in meters from the point to
+	// the closest edge of the alert boundary. A
+	// distance of zero means the point is inside
+	// the boundary.
+	Distance float64
+}
+
+// NearbyAlertCollection is a collection of alerts
+// ordered by distance from a point.
+type NearbyAlertCollection []NearbyAlert
+
+// Select reads a collection of alerts where the
+// geometric bounds of the alert, or the boundary
+// of a zone the alert is mapped to, is within
+// radius meters of point. The alerts are stored
+// closest first.
+//
+// Distances are computed in degrees by the database
+// and converted to meters using metersPerDegree. This
+// is an approximation that overstates east-west
+// distances away from the equator.
+//
+// Alerts with a MessageType of "Cancel", or a
+// Status other than StatusActual, will not be read.
+func (n *NearbyAlertCollection) Select(ctx context.Context, db *sql.DB, point geometry.Point, radius float64) error {
 	query := `SELECT id, area_desc, onset, expires, ends, message_type, category, 
 			  severity, certainty, urgency, event, headline, description, instruction, 
-			  response, created_at FROM alerts WHERE message_type != $1 AND boundary @> $2`
-
-	rows, err := db.QueryContext(ctx, query, "Cancel", point.String())
+			  response, sender_name, parameters, created_at, distance FROM (
+			  SELECT alerts.*, LEAST($2::point <-> boundary, (
+			  SELECT MIN($2::point <-> state_zone_perimeters.boundary) 
+			  FROM alert_zones, state_zone_perimeters 
+			  WHERE alert_zones.alert_id = alerts.id 
+			  AND state_zone_perimeters.sz_id = alert_zones.sz_id)) AS distance 
+			  FROM alerts WHERE message_type != $1 AND status = 'Actual') AS nearby 
+			  WHERE distance <= $3 ORDER BY distance, id LIMIT $4`
+
+	rows, err := db.QueryContext(ctx, query, "Cancel", point.String(), radius/metersPerDegree, MaxLimit)
 	if err != nil {
 		return err
 	}
 	defer rows.Close()
 
 	for rows.Next() {
-		var alert Alert
-		if err := alert.Scan(rows); err != nil {
+		var nearby NearbyAlert
+		scanner := ScanFunc(func(dest ...any) error {
+			return rows.Scan(append(dest, &nearby.Distance)...)
+		})
+		if err := nearby.Scan(scanner); err != nil {
 			return err
 		}
-		*a = append(*a, alert)
+		nearby.Distance *= metersPerDegree
+		*n = append(*n, nearby)
 	}
 
 	return nil
 }
 
+// NearbyResponse is a alert response with the
+// distance from the requested point. NearbyResponse
+// can safely be consumed by a external package.
+type NearbyResponse struct {
+	Response
+	Distance float64 `json:"distance_meters"`
+}
+
+// MarshalJSON formats the response the same as
+// Response.MarshalJSON with the distance added.
+// It must be defined, otherwise the promoted
+// Response.MarshalJSON would drop the distance.
+func (n NearbyResponse) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		jsonResponse
+		Distance float64 `json:"distance_meters"`
+	}{
+		jsonResponse: n.Response.jsonResponse(),
+		Distance:     n.Distance,
+	})
+}
+
+// ResponseCollection returns this nearby alert
+// collection as a collection of nearby responses
+// made at now.
+func (n *NearbyAlertCollection) ResponseCollection(now time.Time) []NearbyResponse {
+	response := []NearbyResponse{}
+	for _, nearby := range *n {
+		response = append(response, NearbyResponse{
+			Response: nearby.AsResponse(now),
+			Distance: nearby.Distance,
+		})
+	}
+	return response
+}
+
 // DeleteEnded will delete all alerts from the
 // database that has ended before t.
 func (e *AlertCollection) DeleteEnded(ctx context.Context, db *sql.DB, t time.Time) (sql.Result, error) {
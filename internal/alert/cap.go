@@ -0,0 +1,162 @@
+package alert
+
+import (
+	"encoding/xml"
+	"time"
+)
+
+// CAPNamespace is the XML namespace of a CAP 1.2 alert.
+const CAPNamespace = "urn:oasis:names:tc:emergency:cap:1.2"
+
+// CAPSender is the sender of every CAP alert. All alerts
+// originate from the NWS.
+const CAPSender = "w-nws.webmaster@noaa.gov"
+
+// capTimeFormat is the layout of CAP times. CAP requires
+// a numeric time zone, so UTC is written as +00:00 and
+// never as Z.
+const capTimeFormat = "2006-01-02T15:04:05-07:00"
+
+// CAPCollection is a collection of alerts in the Common
+// Alerting Protocol (CAP) 1.2 format.
+type CAPCollection struct {
+	XMLName xml.Name   `xml:"alerts"`
+	Alerts  []CAPAlert `xml:"alert"`
+}
+
+// CAPAlert is a alert in the CAP 1.2 format. Only the
+// elements stored for an alert are written.
+type CAPAlert struct {
+	XMLName    xml.Name `xml:"alert"`
+	XMLNS      string   `xml:"xmlns,attr"`
+	Identifier string   `xml:"identifier"`
+	Sender     string   `xml:"sender"`
+	Sent       string   `xml:"sent"`
+	Status     string   `xml:"status"`
+	MsgType    string   `xml:"msgType"`
+	Scope      string   `xml:"scope"`
+	Info       CAPInfo  `xml:"info"`
+}
+
+// CAPInfo is the info element of a CAPAlert.
+type CAPInfo struct {
+	Category     string         `xml:"category"`
+	Event        string         `xml:"event"`
+	ResponseType string         `xml:"responseType,omitempty"`
+	Urgency      string         `xml:"urgency"`
+	Severity     string         `xml:"severity"`
+	Certainty    string         `xml:"certainty"`
+	Onset        string         `xml:"onset,omitempty"`
+	Expires      string         `xml:"expires,omitempty"`
+	SenderName   string         `xml:"senderName,omitempty"`
+	Headline     string         `xml:"headline,omitempty"`
+	Description  string         `xml:"description,omitempty"`
+	Instruction  string         `xml:"instruction,omitempty"`
+	Parameters   []CAPParameter `xml:"parameter,omitempty"`
+	Area         CAPArea        `xml:"area"`
+}
+
+// CAPParameter is a parameter element of a CAPInfo.
+type CAPParameter struct {
+	ValueName string `xml:"valueName"`
+	Value     string `xml:"value"`
+}
+
+// CAPArea is the area element of a CAPInfo.
+type CAPArea struct {
+	AreaDesc string `xml:"areaDesc"`
+}
+
+// CAP returns this response in the CAP 1.2 format. Only
+// actual alerts are served, so the status is always
+// StatusActual. The sent time is when the alert was
+// stored, since the NWS sent time is not kept.
+//
+// The alert expires when it ends, or at its expire time
+// if it has no end time.
+func (r Response) CAP() CAPAlert {
+	msgType := r.MessageType
+	if msgType == "" {
+		msgType = "Alert"
+	}
+
+	expires := r.Expires
+	if r.Ends != nil && !r.Ends.IsZero() {
+		expires = *r.Ends
+	}
+
+	return CAPAlert{
+		XMLNS:      CAPNamespace,
+		Identifier: r.ID,
+		Sender:     CAPSender,
+		Sent:       capTime(r.Sent),
+		Status:     StatusActual,
+		MsgType:    msgType,
+		Scope:      "Public",
+		Info: CAPInfo{
+			Category:     r.Category,
+			Event:        r.Event,
+			ResponseType: r.Response,
+			Urgency:      r.Urgency,
+			Severity:     r.Severity,
+			Certainty:    r.Certainty,
+			Onset:        capTimePtr(r.OnSet),
+			Expires:      capTime(expires),
+			SenderName:   r.SenderName,
+			Headline:     r.Headline,
+			Description:  r.Description,
+			Instruction:  r.Instruction,
+			Parameters:   r.Parameters.cap(),
+			Area: CAPArea{
+				AreaDesc: r.AreaDesc,
+			},
+		},
+	}
+}
+
+// CAPCollectionFrom returns responses as a CAPCollection.
+func CAPCollectionFrom(responses []Response) CAPCollection {
+	collection := CAPCollection{Alerts: []CAPAlert{}}
+	for _, r := range responses {
+		collection.Alerts = append(collection.Alerts, r.CAP())
+	}
+
+	return collection
+}
+
+// capTime formats t as a CAP time. A zero time is
+// formatted as a empty string.
+func capTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+
+	return t.Format(capTimeFormat)
+}
+
+func capTimePtr(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+
+	return capTime(*t)
+}
+
+// cap returns these parameters as CAP parameters, using the
+// NWS parameter names.
+func (p Parameters) cap() []CAPParameter {
+	params := []CAPParameter{}
+	if p.NWSHeadline != "" {
+		params = append(params, CAPParameter{ValueName: "NWSheadline", Value: p.NWSHeadline})
+	}
+
+	for _, vtec := range p.VTEC {
+		params = append(params, CAPParameter{ValueName: "VTEC", Value: vtec})
+	}
+
+	if p.EventEndingTime != nil {
+		params = append(params, CAPParameter{ValueName: "eventEndingTime", Value: capTime(*p.EventEndingTime)})
+	}
+
+	return params
+}
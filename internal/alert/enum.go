@@ -0,0 +1,100 @@
+package alert
+
+import "strings"
+
+// Unknown is the value of a alert severity, certainty or
+// urgency that the NWS API did not send, or sent with a
+// value outside its CAP set.
+const Unknown = "Unknown"
+
+// The CAP values of a alert category.
+const (
+	CategoryGeo       = "Geo"
+	CategoryMet       = "Met"
+	CategorySafety    = "Safety"
+	CategorySecurity  = "Security"
+	CategoryRescue    = "Rescue"
+	CategoryFire      = "Fire"
+	CategoryHealth    = "Health"
+	CategoryEnv       = "Env"
+	CategoryTransport = "Transport"
+	CategoryInfra     = "Infra"
+	CategoryCBRNE     = "CBRNE"
+	CategoryOther     = "Other"
+)
+
+// The CAP values of a alert severity.
+const (
+	SeverityMinor    = "Minor"
+	SeverityModerate = "Moderate"
+	SeveritySevere   = "Severe"
+	SeverityExtreme  = "Extreme"
+)
+
+// The CAP values of a alert certainty.
+const (
+	CertaintyObserved = "Observed"
+	CertaintyLikely   = "Likely"
+	CertaintyPossible = "Possible"
+	CertaintyUnlikely = "Unlikely"
+)
+
+// The CAP values of a alert urgency.
+const (
+	UrgencyImmediate = "Immediate"
+	UrgencyExpected  = "Expected"
+	UrgencyFuture    = "Future"
+	UrgencyPast      = "Past"
+)
+
+// The CAP values of a alert response.
+const (
+	ResponseShelter  = "Shelter"
+	ResponseEvacuate = "Evacuate"
+	ResponsePrepare  = "Prepare"
+	ResponseExecute  = "Execute"
+	ResponseAvoid    = "Avoid"
+	ResponseMonitor  = "Monitor"
+	ResponseAssess   = "Assess"
+	ResponseAllClear = "AllClear"
+	ResponseNone     = "None"
+)
+
+var (
+	categories = []string{CategoryGeo, CategoryMet, CategorySafety, CategorySecurity,
+		CategoryRescue, CategoryFire, CategoryHealth, CategoryEnv, CategoryTransport,
+		CategoryInfra, CategoryCBRNE, CategoryOther}
+	certainties = []string{CertaintyObserved, CertaintyLikely, CertaintyPossible,
+		CertaintyUnlikely, Unknown}
+	urgencies = []string{UrgencyImmediate, UrgencyExpected, UrgencyFuture,
+		UrgencyPast, Unknown}
+	responses = []string{ResponseShelter, ResponseEvacuate, ResponsePrepare,
+		ResponseExecute, ResponseAvoid, ResponseMonitor, ResponseAssess,
+		ResponseAllClear, ResponseNone}
+)
+
+// normalizeEnum returns the value in valid that equals v,
+// ignoring case, so "severe" is stored as "Severe". An
+// empty v is returned as fallback. Any other v outside
+// valid is logged with the field name and alert id and
+// returned as fallback, or as it was sent if fallback is
+// empty.
+func (s *Service) normalizeEnum(id, field, v string, valid []string, fallback string) string {
+	v = strings.TrimSpace(v)
+	if v == "" {
+		return fallback
+	}
+
+	for _, value := range valid {
+		if strings.EqualFold(v, value) {
+			return value
+		}
+	}
+
+	s.logger().Printf("alert has unexpected %s %q (id=%s)\n", field, v, id)
+	if fallback == "" {
+		return v
+	}
+
+	return fallback
+}
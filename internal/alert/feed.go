@@ -0,0 +1,95 @@
+package alert
+
+import (
+	"encoding/xml"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/cicconee/weather-app/internal/app"
+	"github.com/cicconee/weather-app/internal/geometry"
+)
+
+// AtomNamespace is the XML namespace of a Atom feed.
+const AtomNamespace = "http://www.w3.org/2005/Atom"
+
+// Feed is a Atom feed of the active alerts at a point.
+type Feed struct {
+	XMLName xml.Name    `xml:"feed"`
+	XMLNS   string      `xml:"xmlns,attr"`
+	ID      string      `xml:"id"`
+	Title   string      `xml:"title"`
+	Updated string      `xml:"updated"`
+	Author  FeedAuthor  `xml:"author"`
+	Entries []FeedEntry `xml:"entry"`
+}
+
+// FeedAuthor is the author of a Feed.
+type FeedAuthor struct {
+	Name string `xml:"name"`
+}
+
+// FeedEntry is a alert in a Feed.
+type FeedEntry struct {
+	ID      string      `xml:"id"`
+	Title   string      `xml:"title"`
+	Updated string      `xml:"updated"`
+	Content FeedContent `xml:"content"`
+}
+
+// FeedContent is the content of a FeedEntry.
+type FeedContent struct {
+	Type string `xml:"type,attr"`
+	Text string `xml:",chardata"`
+}
+
+// FeedFrom returns responses as a Feed of the alerts at
+// point. Each entry is titled by the event and headline
+// of the alert and updated when the alert was stored.
+// The feed is updated when its newest entry was, or at
+// now if there are no entries.
+func FeedFrom(point geometry.Point, responses []Response, now time.Time) Feed {
+	feed := Feed{
+		XMLNS: AtomNamespace,
+		ID:    fmt.Sprintf("urn:weather-app:alerts:%s", point.Round(4).Key()),
+		Title: fmt.Sprintf("Active alerts at %s", point.Round(4).Key()),
+		Author: FeedAuthor{
+			Name: "National Weather Service",
+		},
+		Entries: []FeedEntry{},
+	}
+
+	updated := time.Time{}
+	for _, r := range responses {
+		if r.Sent.After(updated) {
+			updated = r.Sent
+		}
+
+		feed.Entries = append(feed.Entries, r.entry())
+	}
+
+	if updated.IsZero() {
+		updated = now
+	}
+	feed.Updated = updated.UTC().Format(app.TimeFormat)
+
+	return feed
+}
+
+// entry returns this response as a FeedEntry.
+func (r Response) entry() FeedEntry {
+	title := r.Event
+	if r.Headline != "" {
+		title = strings.Join([]string{r.Event, r.Headline}, ": ")
+	}
+
+	return FeedEntry{
+		ID:      r.ID,
+		Title:   title,
+		Updated: r.Sent.UTC().Format(app.TimeFormat),
+		Content: FeedContent{
+			Type: "text",
+			Text: r.Description,
+		},
+	}
+}
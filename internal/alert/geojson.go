@@ -0,0 +1,200 @@
+package alert
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/cicconee/weather-app/internal/geometry"
+)
+
+// Feature is a alert and its geometric bounds. It is written as a
+// GeoJSON Feature with the alert Response as its properties.
+type Feature struct {
+	Alert
+
+	// The geometric bounds of the alert. Alerts without geometric
+	// bounds of their own use the boundaries of the zones they are
+	// mapped to. It is empty if the alert has neither.
+	Geometry geometry.MultiPolygon
+
+	// now is the time the Feature was read at. The
+	// seconds until the alert expires are projected
+	// from it.
+	now time.Time
+}
+
+// AsResponse returns the alert of this Feature as a
+// Response made at the time the Feature was read.
+func (f Feature) AsResponse() Response {
+	return f.Alert.AsResponse(f.now)
+}
+
+// MarshalJSON formats this Feature as a GeoJSON Feature with the
+// geometry of GeoJSONGeometry.
+func (f Feature) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Type       string            `json:"type"`
+		ID         string            `json:"id"`
+		Geometry   *geometry.GeoJSON `json:"geometry"`
+		Properties Response          `json:"properties"`
+	}{
+		Type:       "Feature",
+		ID:         f.ID,
+		Geometry:   f.GeoJSONGeometry(),
+		Properties: f.AsResponse(),
+	})
+}
+
+// GeoJSONGeometry returns the geometry of this Feature as GeoJSON. A
+// single polygon is returned as a Polygon, and no polygons as nil.
+func (f Feature) GeoJSONGeometry() *geometry.GeoJSON {
+	var geo geometry.GeoJSON
+	switch len(f.Geometry) {
+	case 0:
+		return nil
+	case 1:
+		geo = f.Geometry[0].GeoJSON()
+	default:
+		geo = f.Geometry.GeoJSON()
+	}
+
+	return &geo
+}
+
+// FeatureScope limits the alerts read by EachFeature. The zero
+// FeatureScope reads every active alert.
+type FeatureScope struct {
+	// StateID is optional. If set, only the alerts that reside in
+	// the state are read, the same as SelectWhereState.
+	StateID string
+
+	// BBox is optional. If set, only the alerts whose geometric
+	// bounds, or the boundary of a zone they are mapped to, overlap
+	// the box are read.
+	BBox *geometry.BBox
+}
+
+// EachFeature reads the alerts in scope as features and calls fn for
+// each, newest first. The alerts are read one at a time, so a large
+// set of alerts is never held in memory. If fn returns an error no more
+// alerts are read and the error is returned.
+//
+// Alerts with a MessageType of "Cancel", or a Status other than
+// StatusActual, will not be read.
+func EachFeature(ctx context.Context, db *sql.DB, scope FeatureScope, fn func(Feature) error) error {
+	where := []string{"message_type != $1", "status = 'Actual'"}
+	args := []any{"Cancel"}
+	if scope.StateID != "" {
+		args = append(args, scope.StateID)
+		where = append(where, stateWhere(len(args)))
+	}
+	if scope.BBox != nil {
+		args = append(args, scope.BBox.Polygon().Permiter().String())
+		where = append(where, overlapsWhere(len(args)))
+	}
+
+	query := fmt.Sprintf(`%s WHERE %s ORDER BY created_at DESC, id`,
+		selectFeatures,
+		strings.Join(where, " AND "))
+
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var feature Feature
+		if err := feature.Scan(rows); err != nil {
+			return err
+		}
+
+		if err := fn(feature); err != nil {
+			return err
+		}
+	}
+
+	return rows.Err()
+}
+
+// Select reads a active alert by id from the database, with its
+// geometric bounds, and stores it into this Feature. The same as
+// EachFeature, a alert with a MessageType of "Cancel", or a Status
+// other than StatusActual, is not read and sql.ErrNoRows is returned.
+//
+// ID must be set before calling this func.
+func (f *Feature) Select(ctx context.Context, db *sql.DB) error {
+	query := selectFeatures + ` WHERE id = $1 AND message_type != $2 AND status = 'Actual'`
+
+	return f.Scan(db.QueryRowContext(ctx, query, f.ID, "Cancel"))
+}
+
+// selectFeatures selects the columns read by Feature.Scan from the
+// alerts table. The zone boundaries are only read for alerts without
+// geometric bounds. A polygon is never written with a ';', so the
+// boundaries are joined with it.
+const selectFeatures = `SELECT id, area_desc, onset, expires, ends, message_type, category, 
+			  severity, certainty, urgency, event, headline, description, instruction, 
+			  response, sender_name, parameters, created_at, boundary, CASE WHEN boundary IS NULL THEN (
+			  SELECT string_agg(state_zone_perimeters.boundary::text, ';') 
+			  FROM alert_zones, state_zone_perimeters 
+			  WHERE state_zone_perimeters.sz_id = alert_zones.sz_id 
+			  AND alert_zones.alert_id = alerts.id) END 
+			  FROM alerts`
+
+// Scan reads the columns of selectFeatures into this Feature.
+func (f *Feature) Scan(scanner Scanner) error {
+	var (
+		boundary sql.NullString
+		zones    sql.NullString
+	)
+	err := f.Alert.Scan(ScanFunc(func(dest ...any) error {
+		return scanner.Scan(append(dest, &boundary, &zones)...)
+	}))
+	if err != nil {
+		return err
+	}
+
+	f.Geometry, err = parseBoundaries(boundary, zones)
+	if err != nil {
+		return fmt.Errorf("parsing geometry (alert.ID=%s): %w", f.ID, err)
+	}
+
+	return nil
+}
+
+// parseBoundaries parses the geometric bounds of a alert, or if it has
+// none the ';' separated boundaries of its zones, as a MultiPolygon.
+func parseBoundaries(boundary sql.NullString, zones sql.NullString) (geometry.MultiPolygon, error) {
+	var boundaries []string
+	switch {
+	case boundary.Valid && boundary.String != "":
+		boundaries = []string{boundary.String}
+	case zones.Valid && zones.String != "":
+		boundaries = strings.Split(zones.String, ";")
+	}
+
+	multiPolygon := geometry.MultiPolygon{}
+	for _, b := range boundaries {
+		perimeter, err := geometry.ParsePointCollection(b)
+		if err != nil {
+			return nil, err
+		}
+		multiPolygon = append(multiPolygon, geometry.Polygon{perimeter})
+	}
+
+	return multiPolygon, nil
+}
+
+// overlapsWhere matches the alerts whose geometric bounds, or the
+// boundary of a zone they are mapped to, overlap the polygon $n.
+func overlapsWhere(n int) string {
+	return fmt.Sprintf(`(boundary && $%[1]d::polygon OR id IN (
+		SELECT alert_zones.alert_id FROM alert_zones, state_zone_perimeters 
+		WHERE state_zone_perimeters.sz_id = alert_zones.sz_id 
+		AND state_zone_perimeters.boundary && $%[1]d::polygon))`, n)
+}
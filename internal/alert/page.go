@@ -0,0 +1,107 @@
+package alert
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+const (
+	// DefaultLimit is the number of alerts read
+	// when a Page does not set a Limit.
+	DefaultLimit = 50
+
+	// MaxLimit is the most alerts that can be
+	// read in a single Page.
+	MaxLimit = 200
+)
+
+// Page is a window into a collection of alerts.
+// Limit is the max number of alerts to read and
+// Offset is the number of alerts to skip.
+type Page struct {
+	Limit  int
+	Offset int
+}
+
+// clamp returns this page with the Limit capped
+// to MaxLimit and defaulted to DefaultLimit. A
+// negative Offset is set to zero.
+func (p Page) clamp() Page {
+	if p.Limit <= 0 {
+		p.Limit = DefaultLimit
+	}
+
+	if p.Limit > MaxLimit {
+		p.Limit = MaxLimit
+	}
+
+	if p.Offset < 0 {
+		p.Offset = 0
+	}
+
+	return p
+}
+
+// List is a page of alert responses. List can
+// safely be consumed by a external package.
+type List struct {
+	// The alerts in the page.
+	Alerts []Response
+
+	// The total number of alerts across all
+	// pages.
+	Total int
+
+	// The page that was read.
+	Page Page
+}
+
+// pageQuery is a paged alert query. The from
+// and where clauses are shared by the count and
+// select queries. The args are referenced by the
+// clauses as $1, $2, etc.
+type pageQuery struct {
+	from    string
+	where   string
+	orderBy string
+	args    []any
+	page    Page
+}
+
+// selectPage reads the alerts in the page of the
+// query into this alert collection. It returns the
+// total number of alerts matching the query.
+func (a *AlertCollection) selectPage(ctx context.Context, db *sql.DB, q pageQuery) (int, error) {
+	var total int
+	countQuery := fmt.Sprintf("SELECT COUNT(*) FROM %s WHERE %s", q.from, q.where)
+	if err := db.QueryRowContext(ctx, countQuery, q.args...).Scan(&total); err != nil {
+		return 0, err
+	}
+
+	query := fmt.Sprintf(`SELECT id, area_desc, onset, expires, ends, message_type, category, 
+			  severity, certainty, urgency, event, headline, description, instruction, 
+			  response, sender_name, parameters, created_at FROM %s WHERE %s ORDER BY %s LIMIT $%d OFFSET $%d`,
+		q.from,
+		q.where,
+		q.orderBy,
+		len(q.args)+1,
+		len(q.args)+2)
+
+	args := append(q.args, q.page.Limit, q.page.Offset)
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var alert Alert
+		if err := alert.Scan(rows); err != nil {
+			return 0, err
+		}
+		*a = append(*a, alert)
+	}
+
+	return total, nil
+}
@@ -0,0 +1,82 @@
+package alert
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/cicconee/weather-app/internal/nws"
+)
+
+// Parameters are the NWS parameters of a alert that are
+// stored. The NWS API sends many more, only the ones
+// useful to consumers are kept. Each field may be empty.
+type Parameters struct {
+	// The headline written by the NWS office, which is
+	// usually more specific than Headline.
+	NWSHeadline string `json:"nws_headline,omitempty"`
+
+	// The Valid Time Event Codes of the alert (e.g.
+	// "/O.NEW.KBOU.WS.W.0001.240101T0000Z-240102T0000Z/").
+	// They identify the event across updates of the alert.
+	VTEC []string `json:"vtec,omitempty"`
+
+	// The time the event is expected to end, which can be
+	// later than the alert ends.
+	EventEndingTime *time.Time `json:"event_ending_time,omitempty"`
+}
+
+// Value writes these parameters as JSON. It is written
+// as a string, since a []byte is sent as bytea.
+func (p Parameters) Value() (driver.Value, error) {
+	b, err := json.Marshal(p)
+	if err != nil {
+		return nil, err
+	}
+
+	return string(b), nil
+}
+
+// Scan reads these parameters from JSON. A NULL value is
+// read as no parameters.
+func (p *Parameters) Scan(src any) error {
+	var data []byte
+	switch v := src.(type) {
+	case nil:
+		*p = Parameters{}
+		return nil
+	case []byte:
+		data = v
+	case string:
+		data = []byte(v)
+	default:
+		return fmt.Errorf("cannot scan %T into Parameters", src)
+	}
+
+	*p = Parameters{}
+	return json.Unmarshal(data, p)
+}
+
+// parametersFromNWS returns the stored parameters of the
+// NWS parameters of a alert. An eventEndingTime that is
+// not a RFC 3339 time is skipped.
+func parametersFromNWS(params nws.AlertParameters) Parameters {
+	p := Parameters{}
+
+	if headline := params["NWSheadline"]; len(headline) > 0 {
+		p.NWSHeadline = headline[0]
+	}
+
+	if vtec := params["VTEC"]; len(vtec) > 0 {
+		p.VTEC = append([]string{}, vtec...)
+	}
+
+	if ending := params["eventEndingTime"]; len(ending) > 0 {
+		if t, err := time.Parse(time.RFC3339, ending[0]); err == nil {
+			p.EventEndingTime = utcPtr(t)
+		}
+	}
+
+	return p
+}
@@ -0,0 +1,102 @@
+package alert
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	"github.com/cicconee/weather-app/internal/app"
+	"github.com/lib/pq"
+)
+
+// SyncRun is a record of a completed Sync. SyncRun can
+// safely be consumed by a external package.
+type SyncRun struct {
+	ID int `json:"id"`
+
+	// The states that were synced.
+	States []string `json:"states"`
+
+	// The total alerts written.
+	TotalWrites int `json:"total_writes"`
+
+	// The number of alerts that failed to sync.
+	Fails int `json:"fails"`
+
+	// The time the sync completed.
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// syncRunFromResult creates a SyncRun from the result
+// of a sync that completed at t.
+func syncRunFromResult(result SyncResult, t time.Time) SyncRun {
+	states := StateCollection(result.States)
+	return SyncRun{
+		States:      states.AsStrings(),
+		TotalWrites: result.TotalWrites,
+		Fails:       len(result.Fails),
+		CreatedAt:   t,
+	}
+}
+
+// MarshalJSON formats the CreatedAt time as app.Time.
+func (s SyncRun) MarshalJSON() ([]byte, error) {
+	type syncRun SyncRun
+	return json.Marshal(struct {
+		syncRun
+		CreatedAt app.Time `json:"created_at"`
+	}{
+		syncRun:   syncRun(s),
+		CreatedAt: app.Time(s.CreatedAt),
+	})
+}
+
+func (s *SyncRun) Scan(scanner Scanner) error {
+	return scanner.Scan(
+		&s.ID,
+		pq.Array(&s.States),
+		&s.TotalWrites,
+		&s.Fails,
+		&s.CreatedAt,
+	)
+}
+
+// Insert writes this sync run into the database and
+// sets the ID field.
+func (s *SyncRun) Insert(ctx context.Context, db *sql.DB) error {
+	query := `INSERT INTO alert_sync_runs(states, total_writes, fails, created_at)
+			  VALUES($1, $2, $3, $4) RETURNING id`
+
+	return db.QueryRowContext(ctx, query,
+		pq.Array(s.States),
+		s.TotalWrites,
+		s.Fails,
+		s.CreatedAt).Scan(&s.ID)
+}
+
+// SyncRunCollection is a collection of sync runs.
+type SyncRunCollection []SyncRun
+
+// Select reads the most recent sync runs into this
+// collection.
+func (s *SyncRunCollection) Select(ctx context.Context, db *sql.DB, limit int) error {
+	query := `SELECT id, states, total_writes, fails, created_at
+			  FROM alert_sync_runs ORDER BY created_at DESC, id DESC LIMIT $1`
+
+	rows, err := db.QueryContext(ctx, query, limit)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var run SyncRun
+		if err := run.Scan(rows); err != nil {
+			return err
+		}
+		*s = append(*s, run)
+	}
+
+	return rows.Err()
+}
@@ -3,3 +3,12 @@ package alert
 type Scanner interface {
 	Scan(...any) error
 }
+
+// ScanFunc is a func that can be used as a
+// Scanner. It is used to scan additional columns
+// alongside a alert.
+type ScanFunc func(...any) error
+
+func (f ScanFunc) Scan(dest ...any) error {
+	return f(dest...)
+}
@@ -5,7 +5,9 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
+	"log"
 	"net/http"
+	"strings"
 	"time"
 
 	"github.com/cicconee/weather-app/internal/app"
@@ -13,12 +15,63 @@ import (
 	"github.com/cicconee/weather-app/internal/nws"
 )
 
+// AlertAPI is the interface that wraps the GetAlerts method.
+//
+// GetAlerts executes a HTTP GET request to the following url:
+// https://api.weather.gov/alerts/active?status={status}&{scope}={codes}
+// It returns the active alerts matching the query and any errors
+// encountered.
+//
+// *nws.Client implements AlertAPI.
+type AlertAPI interface {
+	GetAlerts(context.Context, nws.AlertQuery) ([]nws.Alert, error)
+}
+
 type Service struct {
-	Client *nws.Client
+	// The interface that will make network calls to the NWS API to
+	// get the active alerts.
+	Client AlertAPI
 	Store  *Store
+
+	// Status is the status of the alerts Sync fetches (e.g.
+	// "exercise", "test"). Empty fetches actual alerts. Alerts
+	// that are not actual are stored but never served, so
+	// operators can run drills without affecting users.
+	Status string
+
+	// Scope is how Sync scopes the active alerts. Empty, or
+	// nws.AlertScopeArea, fetches the alerts of the synced
+	// states. A zone or region scope fetches the alerts of the
+	// zones or regions in Areas, for when only part of a state
+	// is covered.
+	Scope nws.AlertScope
+
+	// Areas are the zone or region codes fetched when Scope is
+	// not nws.AlertScopeArea.
+	Areas []string
+
+	// Clock is the time the service reads as now, such as to decide
+	// if a alert ended. Nil uses the system time.
+	Clock app.Clock
+
+	// Logger is optional. It logs alerts the NWS API sent with
+	// unexpected values. Nil uses log.Default().
+	Logger *log.Logger
+}
+
+func (s *Service) now() time.Time {
+	return app.Now(s.Clock)
+}
+
+func (s *Service) logger() *log.Logger {
+	if s.Logger == nil {
+		return log.Default()
+	}
+
+	return s.Logger
 }
 
-func New(client *nws.Client, db *sql.DB) *Service {
+func New(client AlertAPI, db *sql.DB) *Service {
 	return &Service{
 		Client: client,
 		Store:  NewStore(db),
@@ -26,20 +79,55 @@ func New(client *nws.Client, db *sql.DB) *Service {
 }
 
 // Sync fetches and stores all the active alerts for
-// each state stored in the database. Any referenced
-// alerts will be deleted from the database and the
-// most up to date alert will be stored.
+// each state stored in the database. If any states are
+// marked as priority, only those states are synced.
+// Any referenced alerts will be deleted from the
+// database and the most up to date alert will be
+// stored.
 //
 // A SyncResult is returned stating what states are
 // being synced, the total alerts written, and if
-// any failures happened while syncing.
+// any failures happened while syncing. The result
+// is recorded as a SyncRun.
 func (s *Service) Sync(ctx context.Context) (SyncResult, error) {
-	states, err := s.Store.SelectStates(ctx)
+	states, err := s.Store.SelectSyncStates(ctx)
 	if err != nil {
 		return SyncResult{}, fmt.Errorf("failed to select states: %w", err)
 	}
 
-	return s.sync(ctx, states)
+	result, err := s.sync(ctx, states)
+	if err != nil {
+		return SyncResult{}, err
+	}
+
+	// The alerts are already written. Failing to record
+	// the run should not fail the sync.
+	run := syncRunFromResult(result, s.now().UTC())
+	if err := s.Store.InsertSyncRun(ctx, &run); err != nil {
+		log.Printf("failed to record alert sync run: %v\n", err)
+	}
+
+	return result, nil
+}
+
+// MaxSyncRunLimit is the most sync runs that Runs will
+// return at once.
+const MaxSyncRunLimit = 100
+
+// Runs returns the most recent sync runs. A limit of
+// zero or more than MaxSyncRunLimit will be set to
+// MaxSyncRunLimit.
+func (s *Service) Runs(ctx context.Context, limit int) (SyncRunCollection, error) {
+	if limit <= 0 || limit > MaxSyncRunLimit {
+		limit = MaxSyncRunLimit
+	}
+
+	runs, err := s.Store.SelectSyncRuns(ctx, limit)
+	if err != nil {
+		return SyncRunCollection{}, fmt.Errorf("failed to select sync runs: %w", err)
+	}
+
+	return runs, nil
 }
 
 // SyncResult defines the result of syncing
@@ -99,6 +187,7 @@ func (s *Service) sync(ctx context.Context, states []State) (SyncResult, error)
 }
 
 func (s *Service) write(ctx context.Context, e Resource, sync *SyncResult) {
+	e.Alert.CreatedAt = s.now().UTC()
 	if err := s.Store.InsertAlertTx(ctx, e); err != nil {
 		sync.Fail(SyncResourceFail{ID: e.Alert.ID, Op: "insert", Err: err})
 	} else {
@@ -106,12 +195,29 @@ func (s *Service) write(ctx context.Context, e Resource, sync *SyncResult) {
 	}
 }
 
+// query returns the query for the active alerts of states. If
+// Scope is a zone or region scope, Areas are queried instead of
+// states.
+func (s *Service) query(states StateCollection) nws.AlertQuery {
+	q := nws.AlertQuery{
+		Status: s.Status,
+		Scope:  s.Scope,
+		Codes:  states.AsStrings(),
+	}
+
+	if s.Scope != "" && s.Scope != nws.AlertScopeArea {
+		q.Codes = s.Areas
+	}
+
+	return q
+}
+
 func (s *Service) alerts(ctx context.Context, states StateCollection) ([]Resource, error) {
-	alerts, err := s.Client.GetActiveAlerts(states.AsStrings()...)
+	alerts, err := s.Client.GetAlerts(ctx, s.query(states))
 	var statusError *app.NWSAPIStatusCodeError
 	switch {
 	case err == nil:
-		return resourcesFromNWS(alerts), nil
+		return s.resourcesFromNWS(alerts), nil
 	case errors.As(err, &statusError):
 		if statusError.StatusCode == 400 || statusError.StatusCode == 500 {
 			return nil, &Error{
@@ -127,15 +233,191 @@ func (s *Service) alerts(ctx context.Context, states StateCollection) ([]Resourc
 	}
 }
 
-// Get gets all the active alerts for point
-// and returns it as a GetResponse.
-func (s *Service) Get(ctx context.Context, point geometry.Point) ([]Response, error) {
-	collection, err := s.Store.SelectAlertsContains(ctx, point)
+// Get gets a page of the active alerts for point.
+// The page Limit is capped at MaxLimit.
+func (s *Service) Get(ctx context.Context, point geometry.Point, page Page) (List, error) {
+	page = page.clamp()
+	collection, total, err := s.Store.SelectAlertsContainsPage(ctx, point, page)
+	if err != nil {
+		return List{}, err
+	}
+
+	return List{Alerts: collection.ResponseCollection(s.now()), Total: total, Page: page}, nil
+}
+
+// GetByID gets the active alert with the id and its
+// geometric bounds. If no active alert has the id, a
+// 404 Error is returned.
+func (s *Service) GetByID(ctx context.Context, id string) (Feature, error) {
+	feature, err := s.Store.SelectFeature(ctx, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return Feature{}, &Error{
+			error:      fmt.Errorf("no active alert (id=%s): %w", id, err),
+			msg:        "Alert not found",
+			statusCode: http.StatusNotFound,
+		}
+	}
+	if err != nil {
+		return Feature{}, fmt.Errorf("selecting alert (id=%s): %w", id, err)
+	}
+
+	feature.now = s.now()
+	return feature, nil
+}
+
+// Count gets the number of alerts for the point
+// and their highest severity. It is cheaper than Get
+// since the alerts themselves are not read.
+func (s *Service) Count(ctx context.Context, point geometry.Point) (Summary, error) {
+	summary, err := s.Store.SelectSummaryContains(ctx, point)
+	if err != nil {
+		return Summary{}, fmt.Errorf("selecting alert summary (point=%v): %w", point, err)
+	}
+
+	return summary, nil
+}
+
+// GetByState gets a page of the active alerts for
+// the state with the id stateID. This includes alerts
+// mapped to the zones of the state and alerts with
+// geometric bounds overlapping the state. The page
+// Limit is capped at MaxLimit.
+func (s *Service) GetByState(ctx context.Context, stateID string, page Page) (List, error) {
+	page = page.clamp()
+	collection, total, err := s.Store.SelectAlertsWhereState(ctx, strings.ToUpper(stateID), page)
+	if err != nil {
+		return List{}, err
+	}
+
+	return List{Alerts: collection.ResponseCollection(s.now()), Total: total, Page: page}, nil
+}
+
+// MaxBBoxDegrees is the most degrees of longitude or
+// latitude a bounding box given to GetInBBox can span.
+const MaxBBoxDegrees = 30.0
+
+// GetInBBox gets a page of the active alerts whose
+// geometric bounds, or the boundary of a zone they
+// are mapped to, overlap bbox. The page Limit is
+// capped at MaxLimit.
+//
+// The bbox must be valid and span no more than
+// MaxBBoxDegrees in either direction.
+func (s *Service) GetInBBox(ctx context.Context, bbox geometry.BBox, page Page) (List, error) {
+	if !bbox.IsValid() {
+		return List{}, &Error{
+			error:      fmt.Errorf("invalid bbox %+v", bbox),
+			msg:        "Invalid bounding box, the minimums must be less than the maximums",
+			statusCode: http.StatusBadRequest,
+		}
+	}
+
+	if bbox.Width() > MaxBBoxDegrees || bbox.Height() > MaxBBoxDegrees {
+		return List{}, &Error{
+			error:      fmt.Errorf("bbox too large (width=%f, height=%f)", bbox.Width(), bbox.Height()),
+			msg:        fmt.Sprintf("Bounding box cannot span more than %.0f degrees", MaxBBoxDegrees),
+			statusCode: http.StatusBadRequest,
+		}
+	}
+
+	page = page.clamp()
+	collection, total, err := s.Store.SelectAlertsOverlapsPage(ctx, bbox, page)
+	if err != nil {
+		return List{}, err
+	}
+
+	return List{Alerts: collection.ResponseCollection(s.now()), Total: total, Page: page}, nil
+}
+
+// EachFeature calls fn with each active alert in
+// scope as a Feature, newest first. The alerts are
+// not paged, fn is called for every alert in scope.
+// If fn returns an error it is returned and no more
+// alerts are read.
+//
+// The scope BBox, if set, must be valid. The geometry
+// of each alert is clipped to it.
+func (s *Service) EachFeature(ctx context.Context, scope FeatureScope, fn func(Feature) error) error {
+	if scope.BBox != nil && !scope.BBox.IsValid() {
+		return &Error{
+			error:      fmt.Errorf("invalid bbox %+v", *scope.BBox),
+			msg:        "Invalid bounding box",
+			statusCode: http.StatusBadRequest,
+		}
+	}
+
+	if bbox := scope.BBox; bbox != nil {
+		write := fn
+		fn = func(feature Feature) error {
+			feature.Geometry = feature.Geometry.Clip(*bbox)
+			return write(feature)
+		}
+	}
+
+	now := s.now()
+	write := fn
+	fn = func(feature Feature) error {
+		feature.now = now
+		return write(feature)
+	}
+
+	scope.StateID = strings.ToUpper(scope.StateID)
+	if err := s.Store.EachFeature(ctx, scope, fn); err != nil {
+		return fmt.Errorf("reading alert features: %w", err)
+	}
+
+	return nil
+}
+
+// Search gets a page of the alerts matching the
+// text query q. A empty query will return an Error.
+// The page Limit is capped at MaxLimit.
+func (s *Service) Search(ctx context.Context, q string, page Page) (List, error) {
+	q = strings.TrimSpace(q)
+	if q == "" {
+		return List{}, &Error{
+			error:      errors.New("empty search query"),
+			msg:        "Must provide a search query",
+			statusCode: http.StatusBadRequest,
+		}
+	}
+
+	page = page.clamp()
+	collection, total, err := s.Store.SearchAlerts(ctx, q, page)
+	if err != nil {
+		return List{}, err
+	}
+
+	return List{Alerts: collection.ResponseCollection(s.now()), Total: total, Page: page}, nil
+}
+
+// MaxNearbyRadius is the largest radius in meters
+// that Nearest will search.
+const MaxNearbyRadius = 100000.0
+
+// Nearest gets the active alerts with a boundary
+// within radius meters of point, even if the
+// boundary does not contain point. The alerts are
+// ordered closest first. At most MaxLimit alerts
+// are returned.
+//
+// The radius must be greater than zero and no
+// larger than MaxNearbyRadius.
+func (s *Service) Nearest(ctx context.Context, point geometry.Point, radius float64) ([]NearbyResponse, error) {
+	if radius <= 0 || radius > MaxNearbyRadius {
+		return []NearbyResponse{}, &Error{
+			error:      fmt.Errorf("radius out of range (radius=%f)", radius),
+			msg:        fmt.Sprintf("Radius must be between 0 and %.0f meters", MaxNearbyRadius),
+			statusCode: http.StatusBadRequest,
+		}
+	}
+
+	collection, err := s.Store.SelectAlertsNearby(ctx, point, radius)
 	if err != nil {
-		return []Response{}, err
+		return []NearbyResponse{}, err
 	}
 
-	return collection.ResponseCollection(), nil
+	return collection.ResponseCollection(s.now()), nil
 }
 
 // CleanUp will delete any alerts from the database
@@ -145,7 +427,7 @@ func (s *Service) Get(ctx context.Context, point geometry.Point) ([]Response, er
 // If an error is returned it is still possible that
 // some rows were deleted.
 func (s *Service) CleanUp(ctx context.Context) (int64, error) {
-	t := time.Now().UTC()
+	t := s.now().UTC()
 
 	n1, err := s.Store.DeleteEndedAlerts(ctx, t)
 	if err != nil {
@@ -160,35 +442,93 @@ func (s *Service) CleanUp(ctx context.Context) (int64, error) {
 	return n1 + n2, nil
 }
 
-func resourcesFromNWS(alerts []nws.Alert) []Resource {
+// RebuildZoneMappings rewrites the alert zones of the
+// state with the id stateID. It should be called after
+// the zones of a state are re-ingested, since the
+// zone-derived containment queries rely on the alert
+// zones.
+//
+// Each active alert is mapped to the stored zones of
+// the state by the uris of the zones it affects, the
+// same as when it was written, so the mappings do not
+// depend on the zone IDs before the re-ingest. Lonely
+// alerts of zones now stored in the state are deleted.
+//
+// If the state is not stored in the database an Error
+// is returned.
+func (s *Service) RebuildZoneMappings(ctx context.Context, stateID string) (ZoneMapping, error) {
+	stateID = strings.ToUpper(stateID)
+
+	_, err := s.Store.SelectState(ctx, stateID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ZoneMapping{}, &Error{
+			error:      fmt.Errorf("state %q not saved to database", stateID),
+			msg:        fmt.Sprintf("%s does not exist", stateID),
+			statusCode: http.StatusNotFound,
+		}
+	}
+	if err != nil {
+		return ZoneMapping{}, fmt.Errorf("failed to select state %q: %w", stateID, err)
+	}
+
+	mapping, err := s.Store.RebuildZoneMappingsTx(ctx, stateID, s.now().UTC())
+	if err != nil {
+		return ZoneMapping{}, fmt.Errorf("failed to rebuild zone mappings of state %q: %w", stateID, err)
+	}
+
+	return mapping, nil
+}
+
+func (s *Service) resourcesFromNWS(alerts []nws.Alert) []Resource {
 	e := []Resource{}
 	for _, a := range alerts {
-		e = append(e, resourceFromNWS(a))
+		e = append(e, s.resourceFromNWS(a))
 	}
 	return e
 }
 
-func resourceFromNWS(a nws.Alert) Resource {
-	onset := a.OnSet.UTC()
-	ends := a.Ends.UTC()
+// utcPtr returns a pointer to t in UTC. The NWS API leaves times it does
+// not have empty, which decode as the zero time, so a nil pointer is
+// returned for the zero time. An alert without an end is stored with a
+// NULL end and is deleted once it expires.
+func utcPtr(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+
+	utc := t.UTC()
+	return &utc
+}
 
+// resourceFromNWS maps a NWS alert to a Resource. The
+// category, severity, certainty, urgency and response are
+// normalized to their CAP values, so filtering on them,
+// such as ranking by severity, only sees known values.
+// A missing severity, certainty or urgency, or one outside
+// its CAP set, is stored as Unknown, and such a category as
+// CategoryOther. A response outside its CAP set is stored
+// as it was sent, and a missing response stays empty.
+func (s *Service) resourceFromNWS(a nws.Alert) Resource {
 	return Resource{
 		Alert: &Alert{
 			ID:          a.ID,
 			AreaDesc:    a.AreaDesc,
-			OnSet:       &onset,
-			Ends:        &ends,
-			Category:    a.Category,
-			Severity:    a.Severity,
-			Certainty:   a.Certainty,
-			Urgency:     a.Urgency,
+			OnSet:       utcPtr(a.OnSet),
+			Ends:        utcPtr(a.Ends),
+			Category:    s.normalizeEnum(a.ID, "category", a.Category, categories, CategoryOther),
+			Severity:    s.normalizeEnum(a.ID, "severity", a.Severity, severities, Unknown),
+			Certainty:   s.normalizeEnum(a.ID, "certainty", a.Certainty, certainties, Unknown),
+			Urgency:     s.normalizeEnum(a.ID, "urgency", a.Urgency, urgencies, Unknown),
 			Event:       a.Event,
 			Headline:    a.Headline,
-			Description: a.Description,
-			Instruction: a.Instruction,
-			Response:    a.Response,
+			Description: sanitizeText(a.Description),
+			Instruction: sanitizeText(a.Instruction),
+			Response:    s.normalizeEnum(a.ID, "response", a.Response, responses, ""),
+			SenderName:  a.SenderName,
+			Parameters:  parametersFromNWS(a.Parameters),
 			Expires:     a.Expires,
 			MessageType: a.MessageType,
+			Status:      a.Status,
 			Points:      a.Geometry,
 		},
 		References: referenceCollectionFromNWS(a.References),
@@ -204,9 +544,25 @@ func referenceCollectionFromNWS(nwsRefs []nws.AlertReference) ReferenceCollectio
 	return refs
 }
 
+// zonesFromNWS returns the affected zone URIs as zones.
+// Each URI is normalized so it matches the URI of the
+// stored zone exactly. The NWS API sometimes lists a
+// zone more than once, so duplicate URIs are skipped.
+// Otherwise the alert would be mapped to the same zone
+// twice.
 func zonesFromNWS(affected []string) []Zone {
 	zones := []Zone{}
+	seen := map[string]bool{}
 	for _, uri := range affected {
+		if zoneURI, ok := nws.ParseZoneURI(uri); ok {
+			uri = zoneURI.String()
+		}
+
+		if seen[uri] {
+			continue
+		}
+		seen[uri] = true
+
 		zones = append(zones, Zone{
 			URI: uri,
 		})
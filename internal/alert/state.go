@@ -3,6 +3,8 @@ package alert
 import (
 	"context"
 	"database/sql"
+
+	"github.com/cicconee/weather-app/internal/database"
 )
 
 type State string
@@ -11,6 +13,13 @@ func (s *State) Scan(scanner Scanner) error {
 	return scanner.Scan(s)
 }
 
+// Select reads the state with the id of this state
+// from the database. If the state is not stored,
+// sql.ErrNoRows is returned.
+func (s *State) Select(ctx context.Context, db *sql.DB) error {
+	return db.QueryRowContext(ctx, "SELECT id FROM states WHERE id = $1", string(*s)).Scan(s)
+}
+
 // State is a collection of states.
 type StateCollection []State
 
@@ -29,21 +38,31 @@ func (s *StateCollection) AsStrings() []string {
 // Select reads all the states from the database
 // and stores it in this state collection.
 func (s *StateCollection) Select(ctx context.Context, db *sql.DB) error {
-	rows, err := db.QueryContext(ctx, "SELECT id FROM states")
+	return s.selectQuery(ctx, db, "SELECT id FROM states")
+}
+
+// SelectPriority reads the states marked as priority
+// from the database and stores it in this state
+// collection.
+func (s *StateCollection) SelectPriority(ctx context.Context, db *sql.DB) error {
+	return s.selectQuery(ctx, db, "SELECT state FROM states_priority")
+}
+
+func (s *StateCollection) selectQuery(ctx context.Context, db *sql.DB, query string) error {
+	rows, err := db.QueryContext(ctx, query)
 	if err != nil {
 		return err
 	}
-	defer rows.Close()
 
-	for rows.Next() {
+	states, err := database.ScanRows(rows, func(rows *sql.Rows) (State, error) {
 		var state State
-
-		if err := state.Scan(rows); err != nil {
-			return err
-		}
-
-		*s = append(*s, state)
+		err := state.Scan(rows)
+		return state, err
+	})
+	if err != nil {
+		return err
 	}
+	*s = append(*s, states...)
 
 	return nil
 }
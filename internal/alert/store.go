@@ -41,26 +41,97 @@ func (s *Store) SelectAlert(ctx context.Context, id string) (Alert, error) {
 	return alert, alert.Select(ctx, s.DB)
 }
 
-// SelectAlertsContains reads a collection of alerts
-// where the point resides inside the boundary of the
-// alerts.
-//
-// The boundary of an alert is determined by either
-// the alert having an explicit boundary, or the
-// boundary of the zones related to the alert.
-func (s *Store) SelectAlertsContains(ctx context.Context, point geometry.Point) (AlertCollection, error) {
+// SelectFeature reads an active alert by id, with
+// its geometric bounds, from the database.
+func (s *Store) SelectFeature(ctx context.Context, id string) (Feature, error) {
+	feature := Feature{Alert: Alert{ID: id}}
+	if err := feature.Select(ctx, s.DB); err != nil {
+		return Feature{}, err
+	}
+
+	return feature, nil
+}
+
+// SelectSummaryContains reads the summary of the
+// alerts where the point resides inside the boundary
+// of the alerts.
+func (s *Store) SelectSummaryContains(ctx context.Context, point geometry.Point) (Summary, error) {
+	summary := Summary{}
+	if err := summary.SelectContains(ctx, s.DB, point); err != nil {
+		return Summary{}, err
+	}
+
+	return summary, nil
+}
+
+// SelectAlertsContainsPage reads a page of alerts
+// where the point resides inside the boundary of
+// the alerts. The total number of alerts containing
+// the point is also returned.
+func (s *Store) SelectAlertsContainsPage(ctx context.Context, point geometry.Point, page Page) (AlertCollection, int, error) {
 	collection := AlertCollection{}
+	total, err := collection.SelectContainsPage(ctx, s.DB, point, page)
+	if err != nil {
+		return AlertCollection{}, 0, err
+	}
+
+	return collection, total, nil
+}
 
-	// Get all the alerts that have a specified
-	// geometric bounds.
-	if err := collection.Select(ctx, s.DB, point); err != nil {
-		return AlertCollection{}, err
+// SelectAlertsWhereState reads a page of alerts
+// that reside in the state with the id stateID.
+// The total number of alerts in the state is also
+// returned.
+func (s *Store) SelectAlertsWhereState(ctx context.Context, stateID string, page Page) (AlertCollection, int, error) {
+	collection := AlertCollection{}
+	total, err := collection.SelectWhereState(ctx, s.DB, stateID, page)
+	if err != nil {
+		return AlertCollection{}, 0, err
 	}
 
-	// Get all the alerts where the geometric bounds
-	// are determined through the mapping to zones.
-	if err := collection.SelectPointless(ctx, s.DB, point); err != nil {
-		return AlertCollection{}, err
+	return collection, total, nil
+}
+
+// EachFeature reads the alerts in scope as features
+// and calls fn for each, newest first.
+func (s *Store) EachFeature(ctx context.Context, scope FeatureScope, fn func(Feature) error) error {
+	return EachFeature(ctx, s.DB, scope, fn)
+}
+
+// SelectAlertsOverlapsPage reads a page of alerts
+// that overlap bbox. The total number of alerts that
+// overlap bbox is also returned.
+func (s *Store) SelectAlertsOverlapsPage(ctx context.Context, bbox geometry.BBox, page Page) (AlertCollection, int, error) {
+	collection := AlertCollection{}
+	total, err := collection.SelectOverlapsPage(ctx, s.DB, bbox, page)
+	if err != nil {
+		return AlertCollection{}, 0, err
+	}
+
+	return collection, total, nil
+}
+
+// SearchAlerts reads a page of alerts matching
+// the text query q, ranked by active status and
+// relevance. The total number of matching alerts
+// is also returned.
+func (s *Store) SearchAlerts(ctx context.Context, q string, page Page) (AlertCollection, int, error) {
+	collection := AlertCollection{}
+	total, err := collection.Search(ctx, s.DB, q, page)
+	if err != nil {
+		return AlertCollection{}, 0, err
+	}
+
+	return collection, total, nil
+}
+
+// SelectAlertsNearby reads a collection of alerts
+// with a boundary within radius meters of point,
+// ordered closest first.
+func (s *Store) SelectAlertsNearby(ctx context.Context, point geometry.Point, radius float64) (NearbyAlertCollection, error) {
+	collection := NearbyAlertCollection{}
+	if err := collection.Select(ctx, s.DB, point, radius); err != nil {
+		return NearbyAlertCollection{}, err
 	}
 
 	return collection, nil
@@ -74,19 +145,66 @@ func (s *Store) SelectStates(ctx context.Context) (StateCollection, error) {
 	return collection, collection.Select(ctx, s.DB)
 }
 
+// SelectState reads the state with the id stateID
+// from the database.
+func (s *Store) SelectState(ctx context.Context, stateID string) (State, error) {
+	state := State(stateID)
+	return state, state.Select(ctx, s.DB)
+}
+
+// SelectSyncStates reads the states alerts should
+// be synced for. If any states are marked as priority
+// only those are returned, otherwise all states in
+// the database are returned.
+func (s *Store) SelectSyncStates(ctx context.Context) (StateCollection, error) {
+	priority := StateCollection{}
+	if err := priority.SelectPriority(ctx, s.DB); err != nil {
+		return nil, err
+	}
+
+	if len(priority) > 0 {
+		return priority, nil
+	}
+
+	all := StateCollection{}
+	if err := all.Select(ctx, s.DB); err != nil {
+		return nil, err
+	}
+
+	return all, nil
+}
+
+// InsertSyncRun writes the sync run to the database
+// and sets the ID field.
+func (s *Store) InsertSyncRun(ctx context.Context, run *SyncRun) error {
+	return run.Insert(ctx, s.DB)
+}
+
+// SelectSyncRuns reads the limit most recent sync
+// runs from the database.
+func (s *Store) SelectSyncRuns(ctx context.Context, limit int) (SyncRunCollection, error) {
+	collection := SyncRunCollection{}
+	if err := collection.Select(ctx, s.DB, limit); err != nil {
+		return SyncRunCollection{}, err
+	}
+
+	return collection, nil
+}
+
 // InsertAlertTx writes an alert resource to the
 // database. All alerts persisted to the database
 // that are referenced by the resource will be
 // deleted. All relationships between the alert
-// and zones will be written to the database.
+// and zones will be written to the database, and
+// the uri of each zone is kept so the relationships
+// can be rebuilt.
 //
-// The alert CreatedAt field will be set.
+// The alert CreatedAt field must be set.
 //
 // InsertAlertTx is wrapped in a database transaction.
 // If any operations fail the database will roll back.
 func (s *Store) InsertAlertTx(ctx context.Context, r Resource) error {
 	return s.tx(ctx, func(tx *sql.Tx) error {
-		r.Alert.CreatedAt = time.Now().UTC()
 		if err := r.Alert.Insert(ctx, tx); err != nil {
 			return err
 		}
@@ -96,6 +214,11 @@ func (s *Store) InsertAlertTx(ctx context.Context, r Resource) error {
 		}
 
 		for _, z := range r.Zones {
+			zoneURI := AlertZoneURI{AlertID: r.Alert.ID, ZoneURI: z.URI}
+			if _, err := zoneURI.Insert(ctx, tx); err != nil {
+				return err
+			}
+
 			if err := z.Select(ctx, tx); err != nil && !errors.Is(err, sql.ErrNoRows) {
 				return err
 			}
@@ -118,6 +241,39 @@ func (s *Store) InsertAlertTx(ctx context.Context, r Resource) error {
 	})
 }
 
+// RebuildZoneMappingsTx rewrites the alert zones
+// of the state with the id stateID for the alerts
+// active at t. Each alert is remapped to the stored
+// zones of the state with the uris it affects, and
+// lonely alerts of stored zones are deleted.
+//
+// RebuildZoneMappingsTx is wrapped in a database
+// transaction. If any operations fail the database
+// will roll back.
+func (s *Store) RebuildZoneMappingsTx(ctx context.Context, stateID string, t time.Time) (ZoneMapping, error) {
+	mapping := ZoneMapping{State: stateID}
+	err := s.tx(ctx, func(tx *sql.Tx) error {
+		if err := mapping.Delete(ctx, tx, t); err != nil {
+			return fmt.Errorf("failed to delete alert zones: %w", err)
+		}
+
+		if err := mapping.Insert(ctx, tx, t); err != nil {
+			return fmt.Errorf("failed to insert alert zones: %w", err)
+		}
+
+		if err := mapping.Resolve(ctx, tx); err != nil {
+			return fmt.Errorf("failed to delete resolved lonely alerts: %w", err)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return ZoneMapping{}, err
+	}
+
+	return mapping, nil
+}
+
 // DeleteEndedAlerts will delete all alerts where
 // the end time is before t.
 func (s *Store) DeleteEndedAlerts(ctx context.Context, t time.Time) (int64, error) {
@@ -0,0 +1,54 @@
+package alert
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/cicconee/weather-app/internal/geometry"
+)
+
+// severities are the NWS alert severities ordered by
+// rank. The index of a severity is its rank.
+var severities = []string{Unknown, SeverityMinor, SeverityModerate, SeveritySevere, SeverityExtreme}
+
+// severityRank is the SQL expression that ranks the
+// severity column by its index in severities.
+const severityRank = `CASE severity WHEN 'Minor' THEN 1 WHEN 'Moderate' THEN 2 
+		WHEN 'Severe' THEN 3 WHEN 'Extreme' THEN 4 ELSE 0 END`
+
+// Summary is the number of alerts at a point and the
+// highest severity among them. Summary can safely be
+// consumed by a external package.
+type Summary struct {
+	Count int `json:"count"`
+
+	// The highest severity of the alerts. It is empty
+	// if Count is zero.
+	MaxSeverity string `json:"max_severity,omitempty"`
+}
+
+// SelectContains reads the summary of the alerts where
+// the point resides inside the geometric bounds of the
+// alert, or the boundary of a zone the alert is mapped
+// to. Only the count and severity are read, not the
+// alerts.
+//
+// Alerts with a MessageType of "Cancel", or a Status other
+// than StatusActual, are not counted.
+func (s *Summary) SelectContains(ctx context.Context, db *sql.DB, point geometry.Point) error {
+	query := fmt.Sprintf(`SELECT COUNT(*), COALESCE(MAX(%s), 0) FROM alerts WHERE %s`,
+		severityRank,
+		containsWhere)
+
+	var rank int
+	if err := db.QueryRowContext(ctx, query, "Cancel", point.String()).Scan(&s.Count, &rank); err != nil {
+		return err
+	}
+
+	if s.Count > 0 {
+		s.MaxSeverity = severities[rank]
+	}
+
+	return nil
+}
@@ -0,0 +1,51 @@
+package alert
+
+import (
+	"strings"
+	"unicode"
+)
+
+// sanitizeText cleans the free text of a NWS alert. NWS text is hard
+// wrapped with single newlines and can contain control characters.
+//
+// Paragraphs, separated by one or more blank lines, are kept and
+// joined with a single blank line. Within a paragraph all whitespace
+// is collapsed to a single space and control characters are removed.
+// Sanitizing text that is already sanitized does not change it.
+func sanitizeText(s string) string {
+	s = strings.ReplaceAll(s, "\r\n", "\n")
+	s = strings.ReplaceAll(s, "\r", "\n")
+
+	paragraphs := []string{}
+	paragraph := []string{}
+	flush := func() {
+		if len(paragraph) > 0 {
+			paragraphs = append(paragraphs, strings.Join(paragraph, " "))
+			paragraph = []string{}
+		}
+	}
+
+	for _, line := range strings.Split(s, "\n") {
+		words := strings.Fields(strings.Map(func(r rune) rune {
+			switch {
+			case unicode.IsSpace(r):
+				return ' '
+			case unicode.IsControl(r):
+				return -1
+			default:
+				return r
+			}
+		}, line))
+
+		// A blank line ends the paragraph.
+		if len(words) == 0 {
+			flush()
+			continue
+		}
+
+		paragraph = append(paragraph, words...)
+	}
+	flush()
+
+	return strings.Join(paragraphs, "\n\n")
+}
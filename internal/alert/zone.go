@@ -3,6 +3,7 @@ package alert
 import (
 	"context"
 	"database/sql"
+	"time"
 )
 
 // Zone is a geographical location that
@@ -17,8 +18,10 @@ type Zone struct {
 	URI string
 }
 
-// Select reads a zone that contains the uri
-// from the database and stores it in this zone.
+// Select reads the zone with the uri from the
+// database and stores it in this zone. The uri
+// is matched exactly, so a zone of a different
+// type with the same code is never read.
 //
 // The URI field must be set before calling this
 // func.
@@ -65,3 +68,100 @@ type LonelyAlert struct {
 func (a *LonelyAlert) Insert(ctx context.Context, db *sql.Tx) (sql.Result, error) {
 	return db.ExecContext(ctx, "INSERT INTO lonely_alerts(alert_id, sz_uri) VALUES($1, $2)", a.AlertID, a.ZoneURI)
 }
+
+// AlertZoneURI is the uri of a zone affected by
+// a alert, whether or not the zone is stored. It
+// is kept for the life of the alert so its alert
+// zones can be rebuilt after the zones are
+// re-ingested.
+type AlertZoneURI struct {
+	// The identifier of the alert.
+	AlertID string
+
+	// The uri of the zone.
+	ZoneURI string
+}
+
+// Insert writes this alert zone uri into the
+// database.
+//
+// AlertID and ZoneURI must be set before calling
+// this func.
+func (a *AlertZoneURI) Insert(ctx context.Context, db *sql.Tx) (sql.Result, error) {
+	return db.ExecContext(ctx, "INSERT INTO alert_zone_uris(alert_id, sz_uri) VALUES($1, $2)", a.AlertID, a.ZoneURI)
+}
+
+// ZoneMapping is the result of rebuilding the
+// alert zones of a state.
+type ZoneMapping struct {
+	// The identifier of the state.
+	State string `json:"state"`
+
+	// The number of alert zones deleted before
+	// being remapped.
+	Removed int64 `json:"removed"`
+
+	// The number of alert zones written from
+	// the affected zone uris of the alerts.
+	Mapped int64 `json:"mapped"`
+
+	// The number of lonely alerts of zones now
+	// stored in the state.
+	Resolved int64 `json:"resolved"`
+}
+
+// Delete deletes the alert zones between the zones
+// of the state and the alerts active at t. The
+// number of rows deleted is stored in Removed.
+//
+// State must be set before calling this func.
+func (m *ZoneMapping) Delete(ctx context.Context, db *sql.Tx, t time.Time) error {
+	query := `
+		DELETE FROM alert_zones USING alerts, state_zones 
+		WHERE alert_zones.alert_id = alerts.id AND alert_zones.sz_id = state_zones.id 
+		AND state_zones.state = $1 
+		AND alerts.expires > $2 AND (alerts.ends IS NULL OR alerts.ends > $2)`
+
+	return m.exec(ctx, db, &m.Removed, query, m.State, t)
+}
+
+// Insert writes a alert zone for each zone of the
+// state with a uri affected by an alert active at
+// t. The number of rows written is stored in
+// Mapped.
+//
+// State must be set before calling this func.
+func (m *ZoneMapping) Insert(ctx context.Context, db *sql.Tx, t time.Time) error {
+	query := `
+		INSERT INTO alert_zones(alert_id, sz_id) 
+		SELECT alerts.id, state_zones.id FROM alerts, alert_zone_uris, state_zones 
+		WHERE alert_zone_uris.alert_id = alerts.id AND alert_zone_uris.sz_uri = state_zones.uri 
+		AND state_zones.state = $1 
+		AND alerts.expires > $2 AND (alerts.ends IS NULL OR alerts.ends > $2) 
+		ON CONFLICT DO NOTHING`
+
+	return m.exec(ctx, db, &m.Mapped, query, m.State, t)
+}
+
+// Resolve deletes the lonely alerts of zones stored
+// in the state, since Insert mapped them. The number
+// of lonely alerts deleted is stored in Resolved.
+//
+// State must be set before calling this func.
+func (m *ZoneMapping) Resolve(ctx context.Context, db *sql.Tx) error {
+	query := `
+		DELETE FROM lonely_alerts USING state_zones 
+		WHERE lonely_alerts.sz_uri = state_zones.uri AND state_zones.state = $1`
+
+	return m.exec(ctx, db, &m.Resolved, query, m.State)
+}
+
+func (m *ZoneMapping) exec(ctx context.Context, db *sql.Tx, n *int64, query string, args ...any) error {
+	res, err := db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+
+	*n, err = res.RowsAffected()
+	return err
+}
@@ -0,0 +1,28 @@
+package app
+
+import "time"
+
+// Clock tells the current time. Services that depend on the
+// current time, such as to expire data, read it from a Clock
+// so tests can control it.
+type Clock interface {
+	Now() time.Time
+}
+
+// SystemClock is the Clock that reads the system time.
+type SystemClock struct{}
+
+// Now returns the current system time.
+func (SystemClock) Now() time.Time {
+	return time.Now()
+}
+
+// Now returns the current time of c. If c is nil the current
+// system time is returned, so a Clock field can be left unset.
+func Now(c Clock) time.Time {
+	if c == nil {
+		return time.Now()
+	}
+
+	return c.Now()
+}
@@ -1,6 +1,11 @@
 package app
 
-import "fmt"
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+)
 
 // NWSAPIStatusCodeError is an error that occurs when the NWS API returns
 // a unexpected status code for a request.
@@ -16,3 +21,43 @@ type NWSAPIStatusCodeError struct {
 func (s *NWSAPIStatusCodeError) Error() string {
 	return fmt.Sprintf("statusCode=%d, detail=%s", s.StatusCode, s.Detail)
 }
+
+var (
+	// ErrNWSNotFound matches a NWSAPIStatusCodeError with a 404 status
+	// code when used with errors.Is.
+	ErrNWSNotFound = errors.New("nws api: not found")
+
+	// ErrNWSBadRequest matches a NWSAPIStatusCodeError with a 400 status
+	// code when used with errors.Is.
+	ErrNWSBadRequest = errors.New("nws api: bad request")
+)
+
+// Is reports if target is the sentinel error for the status code of
+// this error.
+func (s *NWSAPIStatusCodeError) Is(target error) bool {
+	switch target {
+	case ErrNWSNotFound:
+		return s.StatusCode == http.StatusNotFound
+	case ErrNWSBadRequest:
+		return s.StatusCode == http.StatusBadRequest
+	default:
+		return false
+	}
+}
+
+// UpstreamUnavailableError is an error that occurs when requests to the
+// NWS API are not sent because it recently failed repeatedly. Requests
+// are not sent again until RetryAt.
+type UpstreamUnavailableError struct {
+	RetryAt time.Time
+}
+
+func (u *UpstreamUnavailableError) Error() string {
+	return fmt.Sprintf("nws api unavailable until %s", u.RetryAt.Format(time.RFC3339))
+}
+
+// ServerErrorResponse returns a 503 status code and a response body
+// that is safe to be displayed to external sources.
+func (u *UpstreamUnavailableError) ServerErrorResponse() (int, string) {
+	return http.StatusServiceUnavailable, "Weather service is temporarily unavailable"
+}
@@ -0,0 +1,31 @@
+package app
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// TimeFormat is the layout of all times in server responses.
+const TimeFormat = time.RFC3339
+
+// Time is a time that marshals to JSON in TimeFormat. Response
+// types use Time so every endpoint formats times the same way.
+//
+// Times that may not be set are held as a *Time tagged omitempty.
+// A nil time is omitted from the response rather than marshaled
+// as null.
+type Time time.Time
+
+func (t Time) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Time(t).Format(TimeFormat))
+}
+
+// TimePtr returns t as a *Time. If t is nil, nil is returned.
+func TimePtr(t *time.Time) *Time {
+	if t == nil {
+		return nil
+	}
+
+	at := Time(*t)
+	return &at
+}
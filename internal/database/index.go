@@ -0,0 +1,70 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+)
+
+// SpatialIndex is a GiST index on a boundary column.
+// The containment queries (boundary @> point) rely on
+// these indexes to avoid full table scans.
+type SpatialIndex struct {
+	Name   string
+	Table  string
+	Column string
+}
+
+// SpatialIndexes are the spatial indexes the app
+// expects to exist. They are also created by
+// migrations/0005_create_spatial_indexes.up.sql.
+var SpatialIndexes = []SpatialIndex{
+	{Name: "gridpoints_boundary_idx", Table: "gridpoints", Column: "boundary"},
+	{Name: "alerts_boundary_idx", Table: "alerts", Column: "boundary"},
+	{Name: "state_zone_perimeters_boundary_idx", Table: "state_zone_perimeters", Column: "boundary"},
+}
+
+// Exists reports if this index exists in the database.
+func (i *SpatialIndex) Exists(ctx context.Context, db *sql.DB) (bool, error) {
+	query := `SELECT EXISTS (SELECT 1 FROM pg_indexes WHERE tablename = $1 AND indexname = $2)`
+
+	var exists bool
+	if err := db.QueryRowContext(ctx, query, i.Table, i.Name).Scan(&exists); err != nil {
+		return false, err
+	}
+
+	return exists, nil
+}
+
+// Create creates this index in the database if it
+// does not already exist.
+func (i *SpatialIndex) Create(ctx context.Context, db *sql.DB) error {
+	query := fmt.Sprintf("CREATE INDEX IF NOT EXISTS %s ON %s USING GIST(%s)", i.Name, i.Table, i.Column)
+
+	_, err := db.ExecContext(ctx, query)
+	return err
+}
+
+// EnsureSpatialIndexes verifies each of the SpatialIndexes
+// exists in the database. Any missing index is logged as a
+// warning and then created.
+func EnsureSpatialIndexes(ctx context.Context, db *sql.DB, logger *log.Logger) error {
+	for _, index := range SpatialIndexes {
+		exists, err := index.Exists(ctx, db)
+		if err != nil {
+			return fmt.Errorf("checking index (name=%s): %w", index.Name, err)
+		}
+
+		if exists {
+			continue
+		}
+
+		logger.Printf("warning: spatial index %s on %s(%s) is missing, creating it\n", index.Name, index.Table, index.Column)
+		if err := index.Create(ctx, db); err != nil {
+			return fmt.Errorf("creating index (name=%s): %w", index.Name, err)
+		}
+	}
+
+	return nil
+}
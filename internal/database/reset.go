@@ -0,0 +1,75 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// ResetTables are the tables emptied by Reset, in dependency
+// order. Tables referencing another table come before it. The
+// admins and audit log are not included.
+var ResetTables = []string{
+	"periods",
+	"gridpoints",
+	"alert_zones",
+	"alert_zone_uris",
+	"lonely_alerts",
+	"alerts",
+	"alert_sync_runs",
+	"state_zone_holes",
+	"state_zone_perimeters",
+	"state_zones",
+	"states_priority",
+	"states",
+}
+
+// ResetResult is the number of rows deleted from each of the
+// ResetTables.
+type ResetResult map[string]int64
+
+// Resetter deletes all forecast, alert, and zone data. It is
+// meant for re-bootstrapping test environments and must never
+// be enabled in production.
+type Resetter struct {
+	DB *sql.DB
+}
+
+// NewResetter returns a Resetter for the database db.
+func NewResetter(db *sql.DB) *Resetter {
+	return &Resetter{DB: db}
+}
+
+// Reset deletes every row of the ResetTables in a single
+// transaction. If any delete fails the database will roll
+// back.
+func (r *Resetter) Reset(ctx context.Context) (ResetResult, error) {
+	tx, err := r.DB.BeginTx(ctx, &sql.TxOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	result := ResetResult{}
+	for _, table := range ResetTables {
+		res, err := tx.ExecContext(ctx, fmt.Sprintf("DELETE FROM %s", table))
+		if err != nil {
+			if rbErr := tx.Rollback(); rbErr != nil {
+				return nil, fmt.Errorf("deleting %s: %w, rbErr: %v", table, err, rbErr)
+			}
+			return nil, fmt.Errorf("deleting %s: %w", table, err)
+		}
+
+		n, err := res.RowsAffected()
+		if err != nil {
+			tx.Rollback()
+			return nil, fmt.Errorf("counting %s deletes: %w", table, err)
+		}
+		result[table] = n
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
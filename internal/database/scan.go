@@ -0,0 +1,25 @@
+package database
+
+import "database/sql"
+
+// ScanRows calls scan for each row in rows and returns the scanned
+// values in order. rows is closed before returning. If scan or
+// iterating rows fails the error is returned and no values are.
+func ScanRows[T any](rows *sql.Rows, scan func(*sql.Rows) (T, error)) ([]T, error) {
+	defer rows.Close()
+
+	values := []T{}
+	for rows.Next() {
+		v, err := scan(rows)
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, v)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return values, nil
+}
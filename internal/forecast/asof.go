@@ -0,0 +1,95 @@
+package forecast
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/cicconee/weather-app/internal/app"
+	"github.com/cicconee/weather-app/internal/geometry"
+)
+
+// The serve decisions of an AsOf.
+const (
+	// ServeCache is a stored forecast Get serves as it is.
+	ServeCache = "cache"
+
+	// ServeCacheRefresh is a stored forecast Get serves as it is while
+	// it is refreshed in the background, since it is close to expiring.
+	ServeCacheRefresh = "cache_refresh"
+
+	// ServeRefresh is an expired forecast Get updates from the NWS API
+	// before serving it.
+	ServeRefresh = "refresh"
+)
+
+// AsOf is the stored forecast of a point and how Get would serve it at
+// CheckedAt. It is used to debug why a client sees stale or fresh data.
+type AsOf struct {
+	GridpointID int              `json:"gridpoint_id"`
+	Grid        Grid             `json:"grid"`
+	GeneratedAt app.Time         `json:"generated_at"`
+	ExpiresAt   app.Time         `json:"expires_at"`
+	CheckedAt   app.Time         `json:"checked_at"`
+	Expired     bool             `json:"expired"`
+	Decision    string           `json:"decision"`
+	Periods     PeriodCollection `json:"periods"`
+}
+
+// AsOf gets the stored periods of the gridpoint that point maps to, and
+// reports whether Get would serve them from the database or update them
+// from the NWS API first. The point is rounded the same as in Get. All the
+// stored periods are returned, including periods that already ended.
+//
+// Nothing is refreshed and the NWS API is never called. If no stored
+// gridpoint covers the point, a 404 is returned.
+func (s *Service) AsOf(ctx context.Context, point geometry.Point) (AsOf, error) {
+	point = point.Round(s.precision())
+
+	gridpoint, err := s.Store.SelectGridpoint(ctx, point)
+	if errors.Is(err, sql.ErrNoRows) {
+		return AsOf{}, app.NewServerResponseError(
+			fmt.Errorf("no stored gridpoint for point (lon=%f, lat=%f)", point.Lon(), point.Lat()),
+			fmt.Sprintf("No gridpoint stored for %f,%f", point.Lon(), point.Lat()),
+			http.StatusNotFound)
+	}
+	if err != nil {
+		return AsOf{}, fmt.Errorf("selecting gridpoint (point=%v): %w", point, err)
+	}
+
+	fc, err := s.stored(ctx, gridpoint)
+	if err != nil {
+		return AsOf{}, err
+	}
+
+	now := s.now()
+	expired := isExpired(gridpoint, now)
+
+	decision := ServeCache
+	switch {
+	case !s.ReadOnly && expired:
+		decision = ServeRefresh
+	case s.refreshSoon(gridpoint):
+		decision = ServeCacheRefresh
+	}
+
+	return AsOf{
+		GridpointID: gridpoint.ID,
+		Grid:        Grid{ID: gridpoint.GridID, X: gridpoint.GridX, Y: gridpoint.GridY},
+		GeneratedAt: app.Time(gridpoint.Timeline.GeneratedAt),
+		ExpiresAt:   app.Time(gridpoint.Timeline.ExpiresAt),
+		CheckedAt:   app.Time(now),
+		Expired:     expired,
+		Decision:    decision,
+		Periods:     fc.Periods,
+	}, nil
+}
+
+// isExpired reports if the stored forecast of gridpoint expired at now.
+// An expired forecast is updated from the NWS API before it is served.
+func isExpired(gridpoint GridpointEntity, now time.Time) bool {
+	return now.After(gridpoint.Timeline.ExpiresAt)
+}
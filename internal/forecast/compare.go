@@ -0,0 +1,122 @@
+package forecast
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/cicconee/weather-app/internal/app"
+	"github.com/cicconee/weather-app/internal/geometry"
+	"golang.org/x/sync/errgroup"
+)
+
+// PeriodDiff is the difference between the forecasts of two points for the
+// same period of time. The differences are B minus A.
+type PeriodDiff struct {
+	StartTime time.Time
+	EndTime   time.Time
+
+	A Period
+	B Period
+
+	// TemperatureDiff is the temperature of B minus the temperature of A.
+	// It is nil if the temperatures are in different units.
+	TemperatureDiff *int
+
+	// ConditionChanged is true if the short forecast of A and B differ.
+	ConditionChanged bool
+}
+
+// MarshalJSON formats the StartTime and EndTime as app.Time and flattens
+// the temperatures and conditions of A and B.
+func (d PeriodDiff) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		StartTime        app.Time `json:"start_time"`
+		EndTime          app.Time `json:"end_time"`
+		TemperatureUnit  string   `json:"temperature_unit"`
+		TemperatureA     int      `json:"temperature_a"`
+		TemperatureB     int      `json:"temperature_b"`
+		TemperatureDiff  *int     `json:"temperature_diff,omitempty"`
+		ShortForecastA   string   `json:"short_forecast_a"`
+		ShortForecastB   string   `json:"short_forecast_b"`
+		ConditionChanged bool     `json:"condition_changed"`
+	}{
+		StartTime:        app.Time(d.StartTime),
+		EndTime:          app.Time(d.EndTime),
+		TemperatureUnit:  d.A.TemperatureUnit,
+		TemperatureA:     d.A.Temperature,
+		TemperatureB:     d.B.Temperature,
+		TemperatureDiff:  d.TemperatureDiff,
+		ShortForecastA:   d.A.ShortForecast,
+		ShortForecastB:   d.B.ShortForecast,
+		ConditionChanged: d.ConditionChanged,
+	})
+}
+
+// Diff returns the difference of each period in a and b that start at the
+// same time. Periods are aligned on absolute time, so a and b can be in
+// different time zones. The StartTime and EndTime of each PeriodDiff are
+// in the time zone of a. Periods without a match in the other collection
+// are skipped.
+func Diff(a PeriodCollection, b PeriodCollection) []PeriodDiff {
+	byStart := map[int64]Period{}
+	for _, period := range b {
+		byStart[period.StartTime.Unix()] = period
+	}
+
+	diffs := []PeriodDiff{}
+	for _, pa := range a {
+		pb, ok := byStart[pa.StartTime.Unix()]
+		if !ok {
+			continue
+		}
+
+		diff := PeriodDiff{
+			StartTime:        pa.StartTime,
+			EndTime:          pa.EndTime,
+			A:                pa,
+			B:                pb,
+			ConditionChanged: pa.ShortForecast != pb.ShortForecast,
+		}
+		if pa.TemperatureUnit == pb.TemperatureUnit {
+			t := pb.Temperature - pa.Temperature
+			diff.TemperatureDiff = &t
+		}
+
+		diffs = append(diffs, diff)
+	}
+
+	return diffs
+}
+
+// Comparison is the forecasts of two points and the difference of each
+// period they share.
+type Comparison struct {
+	A     Forecast
+	B     Forecast
+	Diffs []PeriodDiff
+}
+
+// Compare gets the forecasts of points a and b concurrently, the same as
+// Get, and the difference of each period they share. If either forecast
+// cannot be got, the error of the first to fail is returned.
+func (s *Service) Compare(ctx context.Context, a geometry.Point, b geometry.Point) (Comparison, error) {
+	var c Comparison
+	g, gctx := errgroup.WithContext(ctx)
+	g.Go(func() error {
+		fc, err := s.Get(gctx, a, 0)
+		c.A = fc
+		return err
+	})
+	g.Go(func() error {
+		fc, err := s.Get(gctx, b, 0)
+		c.B = fc
+		return err
+	})
+	if err := g.Wait(); err != nil {
+		return Comparison{}, err
+	}
+
+	c.Diffs = Diff(c.A.Periods, c.B.Periods)
+	return c, nil
+}
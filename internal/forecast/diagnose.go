@@ -0,0 +1,175 @@
+package forecast
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/cicconee/weather-app/internal/app"
+	"github.com/cicconee/weather-app/internal/geometry"
+)
+
+// The outcomes of a Diagnosis.
+const (
+	// DiagnosisHourly is a point with an hourly forecast.
+	DiagnosisHourly = "hourly"
+
+	// DiagnosisTwelveHour is a point without an hourly forecast that
+	// falls back to the 12-hour forecast.
+	DiagnosisTwelveHour = "twelve_hour"
+
+	// DiagnosisUnsupported is a point the NWS API does not recognize.
+	DiagnosisUnsupported = "unsupported"
+
+	// DiagnosisNoGrid is a point the NWS API recognizes but that has no
+	// gridpoint.
+	DiagnosisNoGrid = "no_grid"
+
+	// DiagnosisOceanic is a gridpoint without an hourly or 12-hour
+	// forecast, most likely in the ocean.
+	DiagnosisOceanic = "oceanic"
+
+	// DiagnosisError is a lookup that failed for any other reason, such
+	// as an unexpected status code.
+	DiagnosisError = "error"
+)
+
+// DiagnosisStep is a single NWS API lookup made by Diagnose. StatusCode
+// is the status code returned by the NWS API, or 0 if no response was
+// received.
+type DiagnosisStep struct {
+	Lookup     string `json:"lookup"`
+	StatusCode int    `json:"status_code"`
+	Error      string `json:"error,omitempty"`
+}
+
+// Diagnosis is the reason a point does or does not have a forecast.
+// Steps are the NWS API lookups in the order they were made and Path
+// describes the decision made after each of them, e.g.
+// "gridpoint ok -> hourly 404 -> forecast 404 -> oceanic".
+type Diagnosis struct {
+	Lon     float64         `json:"lon"`
+	Lat     float64         `json:"lat"`
+	Grid    *Grid           `json:"grid,omitempty"`
+	Steps   []DiagnosisStep `json:"steps"`
+	Path    string          `json:"path"`
+	Outcome string          `json:"outcome"`
+}
+
+// step adds the result of a lookup to this Diagnosis.
+func (d *Diagnosis) step(lookup string, err error) {
+	step := DiagnosisStep{Lookup: lookup, StatusCode: statusCode(err)}
+	if err != nil {
+		step.Error = err.Error()
+	}
+	d.Steps = append(d.Steps, step)
+}
+
+// done sets the Outcome of this Diagnosis and builds the Path from the
+// steps.
+func (d *Diagnosis) done(outcome string) Diagnosis {
+	path := []string{}
+	for _, step := range d.Steps {
+		switch step.StatusCode {
+		case http.StatusOK:
+			path = append(path, step.Lookup+" ok")
+		case 0:
+			path = append(path, step.Lookup+" failed")
+		default:
+			path = append(path, fmt.Sprintf("%s %d", step.Lookup, step.StatusCode))
+		}
+	}
+	path = append(path, outcome)
+
+	d.Outcome = outcome
+	d.Path = strings.Join(path, " -> ")
+	return *d
+}
+
+// statusCode returns the status code of the NWS API response that
+// resulted in err. A nil err is a 200 status code and an err without a
+// response is 0.
+func statusCode(err error) int {
+	var apiErr *app.NWSAPIStatusCodeError
+	switch {
+	case err == nil:
+		return http.StatusOK
+	case errors.Is(err, errNoHourly):
+		return http.StatusNotFound
+	case errors.As(err, &apiErr):
+		return apiErr.StatusCode
+	default:
+		return 0
+	}
+}
+
+// Diagnose makes the same NWS API lookups as Get for a point that is not
+// stored, and reports the status codes returned and why the point does or
+// does not have a forecast. Nothing is written to the database.
+//
+// In read-only mode the NWS API is never called, so a 409 is returned.
+func (s *Service) Diagnose(ctx context.Context, point geometry.Point) (Diagnosis, error) {
+	if s.ReadOnly {
+		return Diagnosis{}, app.NewServerResponseError(
+			fmt.Errorf("diagnosing point (lon=%f, lat=%f) in read-only mode", point.Lon(), point.Lat()),
+			"Forecasts cannot be diagnosed in read-only mode",
+			http.StatusConflict)
+	}
+
+	d := Diagnosis{Lon: point.Lon(), Lat: point.Lat(), Steps: []DiagnosisStep{}}
+
+	gridpointResource, err := s.gridpoint(ctx, point)
+	d.step("gridpoint", err)
+	switch {
+	case errors.Is(err, app.ErrNWSBadRequest), errors.Is(err, app.ErrNWSNotFound):
+		return d.done(DiagnosisUnsupported), nil
+	case err != nil:
+		return d.done(DiagnosisError), nil
+	case gridpointResource.GridID == "":
+		return d.done(DiagnosisNoGrid), nil
+	}
+
+	d.Grid = &Grid{
+		ID: gridpointResource.GridID,
+		X:  gridpointResource.GridX,
+		Y:  gridpointResource.GridY,
+	}
+	if err := ctx.Err(); err != nil {
+		return Diagnosis{}, err
+	}
+
+	params := hourlyParams{
+		GridID: gridpointResource.GridID,
+		GridX:  gridpointResource.GridX,
+		GridY:  gridpointResource.GridY,
+	}
+	_, err = s.hourly(ctx, params)
+	d.step("hourly", err)
+	switch {
+	case err == nil:
+		return d.done(DiagnosisHourly), nil
+	case !errors.Is(err, errNoHourly):
+		return d.done(DiagnosisError), nil
+	}
+
+	if err := ctx.Err(); err != nil {
+		return Diagnosis{}, err
+	}
+
+	tz := gridpointResource.TimeZone
+	if tz == "" {
+		tz = DefaultTimeZone
+	}
+	_, err = s.twelveHour(ctx, params, tz)
+	d.step("forecast", err)
+	switch {
+	case err == nil:
+		return d.done(DiagnosisTwelveHour), nil
+	case errors.Is(err, app.ErrNWSNotFound):
+		return d.done(DiagnosisOceanic), nil
+	default:
+		return d.done(DiagnosisError), nil
+	}
+}
@@ -0,0 +1,95 @@
+package forecast
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+)
+
+// fetch is the fetches of a gridpoint not yet written to the database.
+type fetch struct {
+	count int64
+	last  time.Time
+}
+
+// fetchCounter holds the fetches of each gridpoint until they are
+// flushed. Writing each fetch as it happens makes the most requested
+// gridpoints hot rows.
+type fetchCounter struct {
+	mu      sync.Mutex
+	pending map[int]fetch
+}
+
+// add counts count fetches of the gridpoint with the id, the last at t.
+func (c *fetchCounter) add(id int, count int64, t time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.pending == nil {
+		c.pending = map[int]fetch{}
+	}
+
+	f := c.pending[id]
+	f.count += count
+	if t.After(f.last) {
+		f.last = t
+	}
+	c.pending[id] = f
+}
+
+// take returns the pending fetches and clears them.
+func (c *fetchCounter) take() map[int]fetch {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	pending := c.pending
+	c.pending = nil
+	return pending
+}
+
+// FlushFetches writes the fetches of each gridpoint counted since the last
+// flush to the database, adding them to its fetch count and setting its
+// last fetched time. Fetches that fail to write are kept for the next
+// flush.
+func (s *Service) FlushFetches(ctx context.Context) error {
+	var firstErr error
+	for id, f := range s.fetches.take() {
+		if err := s.Store.AddGridpointFetches(ctx, id, f.count, f.last); err != nil {
+			s.fetches.add(id, f.count, f.last)
+			if firstErr == nil {
+				firstErr = err
+			}
+		}
+	}
+
+	return firstErr
+}
+
+// RunFetchFlush flushes the counted fetches every d until killCh
+// receives, and once more before it returns. Failed flushes are
+// logged.
+//
+// Until they are flushed, fetches are not counted in the database and
+// the gridpoints may be evicted as if they were not fetched, so d
+// should be far less than the eviction age.
+func (s *Service) RunFetchFlush(d time.Duration, killCh <-chan struct{}) {
+	flush := func() {
+		if err := s.FlushFetches(context.Background()); err != nil {
+			log.Printf("failed to flush gridpoint fetches: %v\n", err)
+		}
+	}
+
+	ticker := time.NewTicker(d)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			flush()
+		case <-killCh:
+			flush()
+			return
+		}
+	}
+}
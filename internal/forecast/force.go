@@ -0,0 +1,75 @@
+package forecast
+
+import (
+	"sync"
+	"time"
+)
+
+// DefaultForceRefreshInterval is the least time between forced updates of
+// a gridpoint when Service.ForceRefreshInterval is not set.
+const DefaultForceRefreshInterval = 5 * time.Minute
+
+func (s *Service) forceRefreshInterval() time.Duration {
+	if s.ForceRefreshInterval == 0 {
+		return DefaultForceRefreshInterval
+	}
+
+	return s.ForceRefreshInterval
+}
+
+// allowForce reports if the gridpoint with the id may be force refreshed
+// now. It may not if a forced update of it succeeded less than
+// ForceRefreshInterval ago.
+func (s *Service) allowForce(id int) bool {
+	interval := s.forceRefreshInterval()
+	if interval < 0 {
+		return false
+	}
+
+	return s.forced.allow(id, s.now(), interval)
+}
+
+// recordForce records that a forced update of the gridpoint with the id
+// succeeded, so it cannot be force refreshed again until
+// ForceRefreshInterval has passed.
+func (s *Service) recordForce(id int) {
+	s.forced.record(id, s.now(), s.forceRefreshInterval())
+}
+
+// forceGate holds when each gridpoint was last force refreshed.
+type forceGate struct {
+	mu        sync.Mutex
+	last      map[int]time.Time
+	lastSweep time.Time
+}
+
+// allow reports if id was not recorded within interval of now.
+func (g *forceGate) allow(id int, now time.Time, interval time.Duration) bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	t, ok := g.last[id]
+	return !ok || now.Sub(t) >= interval
+}
+
+// record records now as the last time id was force refreshed. Times older
+// than interval are removed at most once every interval.
+func (g *forceGate) record(id int, now time.Time, interval time.Duration) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.last == nil {
+		g.last = map[int]time.Time{}
+	}
+
+	if now.Sub(g.lastSweep) >= interval {
+		g.lastSweep = now
+		for k, t := range g.last {
+			if now.Sub(t) >= interval {
+				delete(g.last, k)
+			}
+		}
+	}
+
+	g.last[id] = now
+}
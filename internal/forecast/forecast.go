@@ -22,6 +22,25 @@ type HourlyAPIResource struct {
 	// The time this data was generated at on the NWS API server.
 	GeneratedAt time.Time `json:"generatedAt"`
 
+	// The time the NWS API reports this data as stale, from the caching
+	// headers of the response. It is the zero time if the NWS API did
+	// not report it.
+	Expires time.Time `json:"-"`
+
+	// The time the NWS API reports this data was last modified, from
+	// the Last-Modified header of the response. It is the zero time if
+	// the NWS API did not report it.
+	LastModified time.Time `json:"-"`
+
+	// NotModified is true if the NWS API reported the data has not been
+	// modified since a conditional request was made. Only Expires is
+	// set, the data should be read from the database.
+	NotModified bool `json:"-"`
+
+	// The elevation of the gridpoint. In mountainous areas it tells
+	// which elevation band the forecast is for.
+	Elevation QuantitativeValue `json:"elevation"`
+
 	// The forecast periods. Each PeriodAPIResource holds weather information for a 1-hour
 	// period.
 	Periods []PeriodAPIResource `json:"periods"`
@@ -36,15 +55,67 @@ func (h *HourlyAPIResource) ToPeriodEntityCollection() PeriodEntityCollection {
 	return periods
 }
 
+// QuantitativeValue is a measurement returned by the NWS API. The
+// UnitCode is a WMO or NWS unit (e.g. "wmoUnit:m"). Value is nil if
+// the NWS API has no measurement.
+type QuantitativeValue struct {
+	UnitCode string   `json:"unitCode"`
+	Value    *float64 `json:"value"`
+}
+
+// feetPerMeter is the number of feet in a meter.
+const feetPerMeter = 3.28084
+
+// Meters returns this value in meters. It returns nil if there is no
+// value or the unit is not a length in meters or feet.
+func (q QuantitativeValue) Meters() *float64 {
+	if q.Value == nil {
+		return nil
+	}
+
+	var meters float64
+	switch q.UnitCode {
+	case "wmoUnit:m", "unit:m":
+		meters = *q.Value
+	case "wmoUnit:ft", "unit:ft":
+		meters = *q.Value / feetPerMeter
+	default:
+		return nil
+	}
+
+	return &meters
+}
+
 // Timeline returns this HourlyAPIResource GeneratedAt time and
 // when it will expire as a Timeline. Both times are in UTC format.
+//
+// The forecast expires when the NWS API reports it stale. If it
+// did not report it, or reported a time that is not after the
+// GeneratedAt time, the forecast expires an hour after it was
+// generated.
 func (h *HourlyAPIResource) Timeline() Timeline {
+	expiresAt := h.GeneratedAt.Add(time.Hour)
+	if h.Expires.After(h.GeneratedAt) {
+		expiresAt = h.Expires
+	}
+
 	return Timeline{
 		GeneratedAt: h.GeneratedAt.UTC(),
-		ExpiresAt:   h.GeneratedAt.Add(time.Hour).UTC(),
+		ExpiresAt:   expiresAt.UTC(),
 	}
 }
 
+// lastModified returns the LastModified time in UTC, or nil if the NWS
+// API did not report it.
+func (h *HourlyAPIResource) lastModified() *time.Time {
+	if h.LastModified.IsZero() {
+		return nil
+	}
+
+	t := h.LastModified.UTC()
+	return &t
+}
+
 // Timeline is the times forecast data was generated at and when it
 // will be expired.
 type Timeline struct {
@@ -54,3 +125,57 @@ type Timeline struct {
 	// The expiration time of the forecast data.
 	ExpiresAt time.Time
 }
+
+// The sources of a Forecast.
+const (
+	// SourceFresh is a forecast just fetched from the NWS API, or
+	// stored and confirmed unmodified by it.
+	SourceFresh = "fresh"
+
+	// SourceCached is a stored forecast that has not expired.
+	SourceCached = "cached"
+
+	// SourceStale is a stored forecast served after it expired.
+	// Only a read-only Service serves expired forecasts.
+	SourceStale = "stale"
+)
+
+// Forecast is the hourly forecast periods for a point and the time
+// they are valid until. Clients should refetch the forecast after
+// ValidUntil.
+type Forecast struct {
+	// The requested point, rounded to the precision of the Service.
+	Point      geometry.Point
+	Periods    PeriodCollection
+	ValidUntil time.Time
+
+	// The elevation of the gridpoint in meters. It is nil if the NWS
+	// API did not report one.
+	Elevation *float64
+
+	// The city and state nearest to the gridpoint. It is nil if the
+	// NWS API did not report one.
+	RelativeLocation *RelativeLocation
+
+	// TwelveHour is true if Periods are 12-hour periods. This is
+	// the case when the NWS API has no hourly forecast for the
+	// gridpoint but does have a 12-hour forecast.
+	TwelveHour bool
+
+	// Source is how the forecast was served, one of SourceFresh,
+	// SourceCached or SourceStale.
+	Source string
+
+	// The sunrise and sunset of each day the Periods cover. It is
+	// only set by Get.
+	Sun []SunDay
+
+	// The hourly sky cover in percent for the hours the Periods
+	// cover. It is only set by Get when the Service SkyCover is
+	// set, and is nil if the NWS API did not report it.
+	SkyCover []HourlyValue
+
+	// The grid of the gridpoint the forecast is for. It is used to
+	// get the sky cover.
+	grid Grid
+}
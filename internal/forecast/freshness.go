@@ -0,0 +1,128 @@
+package forecast
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/cicconee/weather-app/internal/app"
+)
+
+// Freshness is how fresh the stored forecasts were at a point in
+// time. Operators can alert on it to catch stale data.
+type Freshness struct {
+	// The age of the oldest stored forecast. It is zero if no
+	// forecasts are stored.
+	OldestAge time.Duration
+
+	// The number of gridpoints with an expired forecast. Expired
+	// forecasts are served as they are in read-only mode, and
+	// until the gridpoint is next requested otherwise.
+	Expired int
+
+	// The time the freshness was read.
+	CheckedAt time.Time
+}
+
+// MarshalJSON formats the OldestAge as whole seconds and the
+// CheckedAt time as app.Time.
+func (f Freshness) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		OldestAge int64    `json:"oldest_age_seconds"`
+		Expired   int      `json:"expired_gridpoints"`
+		CheckedAt app.Time `json:"checked_at"`
+	}{
+		OldestAge: int64(f.OldestAge / time.Second),
+		Expired:   f.Expired,
+		CheckedAt: app.Time(f.CheckedAt),
+	})
+}
+
+// Select reads the freshness of the stored gridpoints at now into
+// this Freshness.
+func (f *Freshness) Select(ctx context.Context, db QueryRower, now time.Time) error {
+	query := `SELECT MIN(generated_at), COUNT(*) FILTER (WHERE expires_at < $1)
+			  FROM gridpoints`
+
+	var oldest sql.NullTime
+	if err := db.QueryRowContext(ctx, query, now).Scan(&oldest, &f.Expired); err != nil {
+		return err
+	}
+
+	f.OldestAge = 0
+	if oldest.Valid && now.After(oldest.Time) {
+		f.OldestAge = now.Sub(oldest.Time)
+	}
+	f.CheckedAt = now
+
+	return nil
+}
+
+// FreshnessGauge holds the last Freshness read from Store. It is
+// safe for concurrent use.
+type FreshnessGauge struct {
+	Store *Store
+
+	// Clock is the time the gauge reads as now to age the stored
+	// forecasts. If nil the system time is used.
+	Clock app.Clock
+
+	mu    sync.Mutex
+	value Freshness
+}
+
+// NewFreshnessGauge returns a FreshnessGauge that reads from the
+// database db.
+func NewFreshnessGauge(db *sql.DB) *FreshnessGauge {
+	return &FreshnessGauge{Store: NewStore(db)}
+}
+
+// Update reads the current freshness and stores it as the value of
+// this gauge.
+func (g *FreshnessGauge) Update(ctx context.Context) error {
+	freshness, err := g.Store.SelectFreshness(ctx, app.Now(g.Clock).UTC())
+	if err != nil {
+		return err
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	g.value = freshness
+	return nil
+}
+
+// Value returns the last freshness read by Update.
+func (g *FreshnessGauge) Value() Freshness {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	return g.value
+}
+
+// Run updates this gauge immediately and then every d until
+// killCh receives. Failed updates are logged and the last value is
+// kept.
+func (g *FreshnessGauge) Run(d time.Duration, killCh <-chan struct{}) {
+	update := func() {
+		if err := g.Update(context.Background()); err != nil {
+			log.Printf("failed to update forecast freshness: %v\n", err)
+		}
+	}
+	update()
+
+	ticker := time.NewTicker(d)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			update()
+		case <-killCh:
+			return
+		}
+	}
+}
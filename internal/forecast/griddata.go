@@ -0,0 +1,215 @@
+package forecast
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cicconee/weather-app/internal/app"
+)
+
+// GridDataAPI is implemented by a ForecastAPI that can get the raw data
+// layers of a gridpoint.
+//
+// GetGridData executes a HTTP GET request to the following url:
+// https://api.weather.gov/gridpoints/{grid_id}/{grid_x},{grid_y}
+// It returns the server response in a GridDataAPIResource and any
+// errors encountered.
+type GridDataAPI interface {
+	GetGridData(context.Context, string, int, int) (GridDataAPIResource, error)
+}
+
+// GridDataAPIResource is the raw data layers of a gridpoint that is
+// returned by GridDataAPI. Only the layers used by Service are read.
+//
+// The NWS API does not report every layer for every gridpoint. A layer
+// that is not reported has no values.
+type GridDataAPIResource struct {
+	// SkyCover is the percent of the sky covered by clouds.
+	SkyCover Layer `json:"skyCover"`
+}
+
+// Layer is a time series of a single gridpoint data layer. Each value
+// holds for a ISO 8601 interval, such as "2024-01-01T06:00:00+00:00/PT3H".
+type Layer struct {
+	UOM    string       `json:"uom"`
+	Values []LayerValue `json:"values"`
+}
+
+// LayerValue is the value of a Layer for the interval ValidTime. Value is
+// nil if the NWS API has no value for the interval.
+type LayerValue struct {
+	ValidTime string   `json:"validTime"`
+	Value     *float64 `json:"value"`
+}
+
+// HourlyValue is the value of a gridpoint data layer for the hour
+// starting at Time.
+type HourlyValue struct {
+	Time  time.Time
+	Value float64
+}
+
+// MarshalJSON formats the Time as app.Time.
+func (h HourlyValue) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Time  app.Time `json:"time"`
+		Value float64  `json:"value"`
+	}{
+		Time:  app.Time(h.Time),
+		Value: h.Value,
+	})
+}
+
+// Hourly returns the values of this Layer as a value for each hour,
+// in order. A value that holds for several hours is repeated for each
+// hour. Intervals without a value are skipped.
+func (l Layer) Hourly() ([]HourlyValue, error) {
+	hourly := []HourlyValue{}
+	for _, v := range l.Values {
+		start, duration, err := parseValidTime(v.ValidTime)
+		if err != nil {
+			return nil, err
+		}
+
+		if v.Value == nil {
+			continue
+		}
+
+		for t := start; t.Before(start.Add(duration)); t = t.Add(time.Hour) {
+			hourly = append(hourly, HourlyValue{Time: t, Value: *v.Value})
+		}
+	}
+
+	return hourly, nil
+}
+
+// parseValidTime parses a ISO 8601 interval in the form start/duration,
+// such as "2024-01-01T06:00:00+00:00/PT3H".
+func parseValidTime(s string) (time.Time, time.Duration, error) {
+	startStr, durationStr, ok := strings.Cut(s, "/")
+	if !ok {
+		return time.Time{}, 0, fmt.Errorf("invalid valid time %q: missing duration", s)
+	}
+
+	start, err := time.Parse(time.RFC3339, startStr)
+	if err != nil {
+		return time.Time{}, 0, fmt.Errorf("invalid valid time %q: %w", s, err)
+	}
+
+	duration, err := parseISODuration(durationStr)
+	if err != nil {
+		return time.Time{}, 0, fmt.Errorf("invalid valid time %q: %w", s, err)
+	}
+
+	return start, duration, nil
+}
+
+// parseISODuration parses a ISO 8601 duration made of days, hours,
+// minutes and seconds, such as "P1DT6H". Years, months and weeks are
+// not used by the NWS API and are not supported.
+func parseISODuration(s string) (time.Duration, error) {
+	rest := strings.TrimPrefix(s, "P")
+	if rest == s || rest == "" || rest == "T" {
+		return 0, fmt.Errorf("invalid duration %q", s)
+	}
+
+	var d time.Duration
+	inTime := false
+	for rest != "" {
+		if rest[0] == 'T' {
+			inTime = true
+			rest = rest[1:]
+			continue
+		}
+
+		i := strings.IndexFunc(rest, func(r rune) bool { return r < '0' || r > '9' })
+		if i <= 0 {
+			return 0, fmt.Errorf("invalid duration %q", s)
+		}
+
+		n, err := strconv.Atoi(rest[:i])
+		if err != nil {
+			return 0, fmt.Errorf("invalid duration %q: %w", s, err)
+		}
+
+		switch unit := rest[i]; {
+		case unit == 'D' && !inTime:
+			d += time.Duration(n) * 24 * time.Hour
+		case unit == 'H' && inTime:
+			d += time.Duration(n) * time.Hour
+		case unit == 'M' && inTime:
+			d += time.Duration(n) * time.Minute
+		case unit == 'S' && inTime:
+			d += time.Duration(n) * time.Second
+		default:
+			return 0, fmt.Errorf("invalid duration %q: unsupported unit %q", s, unit)
+		}
+		rest = rest[i+1:]
+	}
+
+	return d, nil
+}
+
+// skyCoverSweepInterval is how often expired grids are removed from a
+// skyCoverCache.
+const skyCoverSweepInterval = time.Minute
+
+// skyCoverCache holds the hourly sky cover of each grid until the stored
+// forecast of its gridpoint expires, so the grid data is not fetched from
+// the NWS API on each request.
+type skyCoverCache struct {
+	mu        sync.Mutex
+	grids     map[Grid]skyCoverEntry
+	lastSweep time.Time
+}
+
+type skyCoverEntry struct {
+	hourly  []HourlyValue
+	expires time.Time
+}
+
+// get returns the sky cover stored for grid. If grid is not stored or has
+// expired, false is returned.
+func (c *skyCoverCache) get(grid Grid, now time.Time) ([]HourlyValue, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.grids[grid]
+	if !ok || !now.Before(entry.expires) {
+		return nil, false
+	}
+
+	return entry.hourly, true
+}
+
+// put stores hourly for grid until expires. Nothing is stored if expires
+// is not after now. Expired grids are removed at most once every
+// skyCoverSweepInterval.
+func (c *skyCoverCache) put(grid Grid, hourly []HourlyValue, expires time.Time, now time.Time) {
+	if !now.Before(expires) {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.grids == nil {
+		c.grids = map[Grid]skyCoverEntry{}
+	}
+
+	if now.Sub(c.lastSweep) >= skyCoverSweepInterval {
+		c.lastSweep = now
+		for k, entry := range c.grids {
+			if !now.Before(entry.expires) {
+				delete(c.grids, k)
+			}
+		}
+	}
+
+	c.grids[grid] = skyCoverEntry{hourly: hourly, expires: expires}
+}
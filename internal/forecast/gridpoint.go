@@ -2,7 +2,10 @@ package forecast
 
 import (
 	"context"
+	"database/sql"
+	"time"
 
+	"github.com/cicconee/weather-app/internal/app"
 	"github.com/cicconee/weather-app/internal/geometry"
 )
 
@@ -27,11 +30,24 @@ type GridpointAPIResource struct {
 
 	// The timezone used in the grid.
 	TimeZone string `json:"timeZone"`
+
+	// The nearest city to the gridpoint. It is empty if the NWS API
+	// did not return one.
+	RelativeLocation struct {
+		Properties RelativeLocation `json:"properties"`
+	} `json:"relativeLocation"`
+}
+
+// RelativeLocation is the nearest city to a gridpoint, so clients can
+// label a forecast (e.g. "Forecast for near Boulder, CO").
+type RelativeLocation struct {
+	City  string `json:"city"`
+	State string `json:"state"`
 }
 
 // ToGridpointEntity returns this GridpointAPIResource as a GridpointEntity.
-// Only the GridID, GridX, GridY, and TimeZone fields are populated in the
-// returned GridpointEntity.
+// Only the GridID, GridX, GridY, TimeZone, and relative location fields are
+// populated in the returned GridpointEntity.
 //
 // The GridpointEntity will need to have its Timeline and Geometry set.
 func (g *GridpointAPIResource) ToGridpointEntity() GridpointEntity {
@@ -40,6 +56,9 @@ func (g *GridpointAPIResource) ToGridpointEntity() GridpointEntity {
 		GridX:    g.GridX,
 		GridY:    g.GridY,
 		TimeZone: g.TimeZone,
+
+		RelativeLocationCity:  g.RelativeLocation.Properties.City,
+		RelativeLocationState: g.RelativeLocation.Properties.State,
 	}
 }
 
@@ -70,15 +89,82 @@ type GridpointEntity struct {
 	// The time zone used in the gridpoint.
 	TimeZone string
 
+	// The elevation of the gridpoint in meters. It is nil if the NWS API
+	// did not report one.
+	Elevation *float64
+
+	// The city and state nearest to the gridpoint. They are empty if the
+	// NWS API did not report them.
+	RelativeLocationCity  string
+	RelativeLocationState string
+
 	// The time of generation and expiration of the gridpoints forecast data.
 	Timeline Timeline
 
+	// The time the NWS API reported the forecast data was last modified. It
+	// is sent back to the NWS API when the forecast is updated, so unchanged
+	// data is not sent again. It is nil if the NWS API did not report it.
+	LastModified *time.Time
+
+	// The number of times the forecast of this gridpoint was served and
+	// the last time it was. They are only read with the geometry.
+	FetchCount    int64
+	LastFetchedAt time.Time
+
 	// The geographical boundary that this gridpoint covers. Any coordinate
 	// that resides within this polygon will get its forecast data from this
 	// gridpoint.
 	Geometry geometry.Polygon
 }
 
+// GridpointResponse is a stored gridpoint without its periods. It is used
+// to inspect which gridpoint a point maps to.
+type GridpointResponse struct {
+	ID            int               `json:"id"`
+	GridID        string            `json:"grid_id"`
+	GridX         int               `json:"grid_x"`
+	GridY         int               `json:"grid_y"`
+	TimeZone      string            `json:"time_zone"`
+	Elevation     *float64          `json:"elevation_meters,omitempty"`
+	Relative      *RelativeLocation `json:"relative_location,omitempty"`
+	GeneratedAt   app.Time          `json:"generated_at"`
+	ExpiresAt     app.Time          `json:"expires_at"`
+	FetchCount    int64             `json:"fetch_count"`
+	LastFetchedAt app.Time          `json:"last_fetched_at"`
+	Geometry      geometry.GeoJSON  `json:"geometry"`
+}
+
+// AsResponse returns this GridpointEntity as a GridpointResponse.
+func (g *GridpointEntity) AsResponse() GridpointResponse {
+	return GridpointResponse{
+		ID:            g.ID,
+		GridID:        g.GridID,
+		GridX:         g.GridX,
+		GridY:         g.GridY,
+		TimeZone:      g.TimeZone,
+		Elevation:     g.Elevation,
+		Relative:      g.RelativeLocation(),
+		GeneratedAt:   app.Time(g.Timeline.GeneratedAt),
+		ExpiresAt:     app.Time(g.Timeline.ExpiresAt),
+		FetchCount:    g.FetchCount,
+		LastFetchedAt: app.Time(g.LastFetchedAt),
+		Geometry:      g.Geometry.GeoJSON(),
+	}
+}
+
+// RelativeLocation returns the city and state nearest to this gridpoint.
+// It returns nil if the city is not known.
+func (g *GridpointEntity) RelativeLocation() *RelativeLocation {
+	if g.RelativeLocationCity == "" {
+		return nil
+	}
+
+	return &RelativeLocation{
+		City:  g.RelativeLocationCity,
+		State: g.RelativeLocationState,
+	}
+}
+
 // Scan will scan the query result in scanner into this GridpointEntity.
 func (g *GridpointEntity) Scan(scanner Scanner) error {
 	return scanner.Scan(
@@ -88,46 +174,217 @@ func (g *GridpointEntity) Scan(scanner Scanner) error {
 		&g.GridY,
 		&g.Timeline.GeneratedAt,
 		&g.Timeline.ExpiresAt,
-		&g.TimeZone)
+		&g.TimeZone,
+		&g.Elevation,
+		&g.RelativeLocationCity,
+		&g.RelativeLocationState,
+		&g.LastModified)
 }
 
 // Select reads a gridpoint into this GridpointEntity where point resides inside
-// its geometric bounds.
+// its geometric bounds. point is used as is, so it should already be rounded.
+//
+// If the bounds of more than one gridpoint hold point, such as on a shared edge
+// or where the NWS API returned overlapping bounds, the most recently generated
+// gridpoint is read. Ties are broken by the highest ID so the same gridpoint is
+// always read.
 func (g *GridpointEntity) Select(ctx context.Context, db QueryRower, point geometry.Point) error {
-	query := `SELECT id, grid_id, grid_x, grid_y, generated_at, expires_at, timezone
-			  FROM gridpoints WHERE boundary @> $1`
+	query := `SELECT id, grid_id, grid_x, grid_y, generated_at, expires_at, timezone, elevation,
+			  relative_location_city, relative_location_state, last_modified FROM gridpoints WHERE boundary @> $1
+			  ORDER BY generated_at DESC, id DESC LIMIT 1`
+
+	return g.Scan(db.QueryRowContext(ctx, query, point.String()))
+}
+
+// SelectByGrid reads the gridpoint with the grid identifier gridID and the
+// grid coordinates gridX and gridY into this GridpointEntity.
+func (g *GridpointEntity) SelectByGrid(ctx context.Context, db QueryRower, gridID string, gridX int, gridY int) error {
+	query := `SELECT id, grid_id, grid_x, grid_y, generated_at, expires_at, timezone, elevation,
+			  relative_location_city, relative_location_state, last_modified FROM gridpoints 
+			  WHERE grid_id = $1 AND grid_x = $2 AND grid_y = $3`
+
+	return g.Scan(db.QueryRowContext(ctx, query, gridID, gridX, gridY))
+}
+
+// SelectWithGeometry reads a gridpoint into this GridpointEntity like Select,
+// and also reads its fetch stats and its geometric bounds into the Geometry
+// field.
+func (g *GridpointEntity) SelectWithGeometry(ctx context.Context, db QueryRower, point geometry.Point) error {
+	query := `SELECT id, grid_id, grid_x, grid_y, generated_at, expires_at, timezone, elevation,
+			  relative_location_city, relative_location_state, last_modified, fetch_count, last_fetched_at, 
+			  boundary FROM gridpoints WHERE boundary @> $1
+			  ORDER BY generated_at DESC, id DESC LIMIT 1`
+
+	return g.scanWithGeometry(db.QueryRowContext(ctx, query, point.String()))
+}
+
+// scanWithGeometry scans the query result in scanner into this GridpointEntity
+// like Scan, followed by its fetch stats and geometric bounds.
+func (g *GridpointEntity) scanWithGeometry(scanner Scanner) error {
+	var boundary string
+	err := scanner.Scan(
+		&g.ID,
+		&g.GridID,
+		&g.GridX,
+		&g.GridY,
+		&g.Timeline.GeneratedAt,
+		&g.Timeline.ExpiresAt,
+		&g.TimeZone,
+		&g.Elevation,
+		&g.RelativeLocationCity,
+		&g.RelativeLocationState,
+		&g.LastModified,
+		&g.FetchCount,
+		&g.LastFetchedAt,
+		&boundary)
+	if err != nil {
+		return err
+	}
 
-	return g.Scan(db.QueryRowContext(ctx, query, point.RoundedString()))
+	perimeter, err := geometry.ParsePointCollection(boundary)
+	if err != nil {
+		return err
+	}
+	g.Geometry = geometry.Polygon{perimeter}
+
+	return nil
 }
 
 // Insert writes this GridpointEntity into the database and sets this
-// GridpointEntity ID field.
-func (g *GridpointEntity) Insert(ctx context.Context, db QueryRower) error {
+// GridpointEntity ID field. It reports if the gridpoint was inserted.
+//
+// If a gridpoint with the same GridID, GridX and GridY is already
+// stored, such as by a concurrent request, nothing is written. The ID
+// field is set to the stored gridpoint's ID and false is returned.
+func (g *GridpointEntity) Insert(ctx context.Context, db QueryRower) (bool, error) {
+	// The no-op update lets RETURNING read the existing row. A row
+	// that was just inserted has no xmax.
 	query := `INSERT INTO gridpoints(grid_id, grid_x, grid_y, generated_at, expires_at, timezone, 
-			  boundary) VALUES($1, $2, $3, $4, $5, $6, $7) RETURNING id`
+			  elevation, relative_location_city, relative_location_state, boundary, last_modified) 
+			  VALUES($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11) 
+			  ON CONFLICT (grid_id, grid_x, grid_y) DO UPDATE SET grid_id = EXCLUDED.grid_id 
+			  RETURNING id, xmax = 0`
 
-	return db.QueryRowContext(ctx, query,
+	var inserted bool
+	err := db.QueryRowContext(ctx, query,
 		g.GridID,
 		g.GridX,
 		g.GridY,
 		g.Timeline.GeneratedAt,
 		g.Timeline.ExpiresAt,
 		g.TimeZone,
-		g.Geometry.Permiter().String()).Scan(&g.ID)
+		g.Elevation,
+		g.RelativeLocationCity,
+		g.RelativeLocationState,
+		g.Geometry.Permiter().String(),
+		g.LastModified).Scan(&g.ID, &inserted)
+
+	return inserted, err
 }
 
-// Update writes this GridpointEntity to the database as an update. Only the Timeline
-// can be updated.
+// Update writes this GridpointEntity to the database as an update. Only the Timeline,
+// Elevation and LastModified can be updated.
 //
-// The only fields that need to be set are the ID and Timeline.
+// The only fields that need to be set are the ID, Timeline, Elevation and LastModified.
 func (g *GridpointEntity) Update(ctx context.Context, db Execer) error {
-	query := `UPDATE gridpoints SET generated_at = $1, expires_at = $2
-			  WHERE id = $3`
+	query := `UPDATE gridpoints SET generated_at = $1, expires_at = $2, elevation = $3,
+			  last_modified = $4 WHERE id = $5`
 
 	_, err := db.ExecContext(ctx, query,
 		g.Timeline.GeneratedAt,
 		g.Timeline.ExpiresAt,
+		g.Elevation,
+		g.LastModified,
 		g.ID)
 
 	return err
 }
+
+// AddFetches adds count to the fetch count of this gridpoint and sets the
+// time it was last fetched to t, unless it was already fetched later. The
+// only field that needs to be set is the ID.
+//
+// The count is added by the database rather than written from the
+// FetchCount field, so concurrent flushes of the same gridpoint are all
+// counted.
+func (g *GridpointEntity) AddFetches(ctx context.Context, db Execer, count int64, t time.Time) error {
+	query := `UPDATE gridpoints SET last_fetched_at = GREATEST(last_fetched_at, $1),
+			  fetch_count = fetch_count + $2 WHERE id = $3`
+
+	_, err := db.ExecContext(ctx, query, t, count, g.ID)
+	return err
+}
+
+// GridpointEntityCollection is a collection of GridpointEntity.
+type GridpointEntityCollection []GridpointEntity
+
+// SelectExpiring reads the gridpoints whose forecast expires before t into this
+// GridpointEntityCollection. The gridpoints are ordered by expiration, oldest
+// first, and at most limit gridpoints are read.
+func (g *GridpointEntityCollection) SelectExpiring(ctx context.Context, db Queryer, t time.Time, limit int) error {
+	query := `SELECT id, grid_id, grid_x, grid_y, generated_at, expires_at, timezone, elevation,
+			  relative_location_city, relative_location_state, last_modified FROM gridpoints WHERE expires_at < $1
+			  ORDER BY expires_at LIMIT $2`
+
+	rows, err := db.QueryContext(ctx, query, t, limit)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		gridpoint := GridpointEntity{}
+		if err := gridpoint.Scan(rows); err != nil {
+			return err
+		}
+		*g = append(*g, gridpoint)
+	}
+
+	return rows.Err()
+}
+
+// SelectOverlaps reads the gridpoints whose geometric bounds overlap bbox into
+// this GridpointEntityCollection, with their geometry. The gridpoints are ordered
+// by ID, skipping the first offset gridpoints, and at most limit gridpoints are
+// read.
+func (g *GridpointEntityCollection) SelectOverlaps(ctx context.Context, db Queryer, bbox geometry.BBox, limit int, offset int) error {
+	query := `SELECT id, grid_id, grid_x, grid_y, generated_at, expires_at, timezone, elevation,
+			  relative_location_city, relative_location_state, last_modified, fetch_count, last_fetched_at, 
+			  boundary FROM gridpoints 
+			  WHERE boundary && $1::polygon ORDER BY id LIMIT $2 OFFSET $3`
+
+	rows, err := db.QueryContext(ctx, query, bbox.Polygon().Permiter().String(), limit, offset)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		gridpoint := GridpointEntity{}
+		if err := gridpoint.scanWithGeometry(rows); err != nil {
+			return err
+		}
+		*g = append(*g, gridpoint)
+	}
+
+	return rows.Err()
+}
+
+// CountOverlaps returns the number of gridpoints whose geometric bounds overlap
+// bbox.
+func (g *GridpointEntityCollection) CountOverlaps(ctx context.Context, db QueryRower, bbox geometry.BBox) (int, error) {
+	query := `SELECT COUNT(*) FROM gridpoints WHERE boundary && $1::polygon`
+
+	var total int
+	err := db.QueryRowContext(ctx, query, bbox.Polygon().Permiter().String()).Scan(&total)
+
+	return total, err
+}
+
+// DeleteStale deletes all gridpoints that were generated and last fetched
+// before t. The periods of each gridpoint are deleted with it.
+func (g *GridpointEntityCollection) DeleteStale(ctx context.Context, db Execer, t time.Time) (sql.Result, error) {
+	query := `DELETE FROM gridpoints WHERE generated_at < $1 AND last_fetched_at < $1`
+
+	return db.ExecContext(ctx, query, t)
+}
@@ -0,0 +1,80 @@
+package forecast
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/cicconee/weather-app/internal/app"
+)
+
+// DefaultNoForecastTTL is how long a point without a forecast is
+// remembered when Service.NoForecastTTL is not set.
+const DefaultNoForecastTTL = 15 * time.Minute
+
+// noForecastSweepInterval is how often expired points are removed from
+// a noForecastCache.
+const noForecastSweepInterval = time.Minute
+
+// errNoGrid is returned by write when the NWS API recognizes a point but
+// it has no gridpoint.
+var errNoGrid = errors.New("no gridpoint")
+
+// isNoForecast reports if err means a point has no forecast, rather than
+// that the forecast could not be fetched. The point is not supported by
+// the NWS API, has no gridpoint, or its gridpoint is oceanic.
+func isNoForecast(err error) bool {
+	return errors.Is(err, errNoGrid) ||
+		errors.Is(err, app.ErrNWSBadRequest) ||
+		errors.Is(err, app.ErrNWSNotFound)
+}
+
+// noForecastCache holds the points without a forecast and the error
+// returned for them, so repeated requests fail without calling the NWS
+// API. Points expire since the NWS API coverage expands over time.
+type noForecastCache struct {
+	mu        sync.Mutex
+	points    map[string]noForecastEntry
+	lastSweep time.Time
+}
+
+type noForecastEntry struct {
+	err     error
+	expires time.Time
+}
+
+// get returns the error stored for key. If key is not stored or has
+// expired, nil is returned.
+func (c *noForecastCache) get(key string, now time.Time) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.points[key]
+	if !ok || !now.Before(entry.expires) {
+		return nil
+	}
+
+	return entry.err
+}
+
+// put stores err for key until expires. Expired points are removed at
+// most once every noForecastSweepInterval.
+func (c *noForecastCache) put(key string, err error, expires time.Time, now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.points == nil {
+		c.points = map[string]noForecastEntry{}
+	}
+
+	if now.Sub(c.lastSweep) >= noForecastSweepInterval {
+		c.lastSweep = now
+		for k, entry := range c.points {
+			if !now.Before(entry.expires) {
+				delete(c.points, k)
+			}
+		}
+	}
+
+	c.points[key] = noForecastEntry{err: err, expires: expires}
+}
@@ -0,0 +1,92 @@
+package forecast
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/cicconee/weather-app/internal/geometry"
+)
+
+// OverlapSide is one of the gridpoints of a GridpointOverlap.
+type OverlapSide struct {
+	GridpointID int `json:"gridpoint_id"`
+	Grid
+}
+
+// GridpointOverlap is two stored gridpoints whose geometric bounds overlap.
+// A point in the overlap is served the forecast of only one of them, the
+// one Select prefers.
+type GridpointOverlap struct {
+	A OverlapSide `json:"a"`
+	B OverlapSide `json:"b"`
+}
+
+// GridpointOverlapCollection is a collection of GridpointOverlap.
+type GridpointOverlapCollection []GridpointOverlap
+
+// Select reads the pairs of stored gridpoints whose geometric bounds overlap
+// into this GridpointOverlapCollection. Gridpoints that only share an edge or
+// vertex, as adjacent gridpoints do, are not read.
+//
+// The database finds the pairs whose bounds touch and the pairs that only
+// touch are filtered out by geometry.Polygon.Overlaps.
+func (g *GridpointOverlapCollection) Select(ctx context.Context, db Queryer) error {
+	query := `SELECT a.id, a.grid_id, a.grid_x, a.grid_y, a.boundary, 
+			  b.id, b.grid_id, b.grid_x, b.grid_y, b.boundary 
+			  FROM gridpoints AS a JOIN gridpoints AS b 
+			  ON a.id < b.id AND a.boundary && b.boundary 
+			  ORDER BY a.id, b.id`
+
+	rows, err := db.QueryContext(ctx, query)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var overlap GridpointOverlap
+		var boundaryA, boundaryB string
+		err := rows.Scan(
+			&overlap.A.GridpointID,
+			&overlap.A.ID,
+			&overlap.A.X,
+			&overlap.A.Y,
+			&boundaryA,
+			&overlap.B.GridpointID,
+			&overlap.B.ID,
+			&overlap.B.X,
+			&overlap.B.Y,
+			&boundaryB)
+		if err != nil {
+			return err
+		}
+
+		perimeterA, err := geometry.ParsePointCollection(boundaryA)
+		if err != nil {
+			return fmt.Errorf("parsing boundary (gridpoint.ID=%d): %w", overlap.A.GridpointID, err)
+		}
+
+		perimeterB, err := geometry.ParsePointCollection(boundaryB)
+		if err != nil {
+			return fmt.Errorf("parsing boundary (gridpoint.ID=%d): %w", overlap.B.GridpointID, err)
+		}
+
+		a, b := geometry.Polygon{perimeterA}, geometry.Polygon{perimeterB}
+		if a.Overlaps(b) {
+			*g = append(*g, overlap)
+		}
+	}
+
+	return rows.Err()
+}
+
+// Overlaps gets the pairs of stored gridpoints whose geometric bounds
+// overlap. The gridpoints are only reported, nothing is deleted.
+func (s *Service) Overlaps(ctx context.Context) (GridpointOverlapCollection, error) {
+	overlaps, err := s.Store.SelectOverlappingGridpoints(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("selecting overlapping gridpoints: %w", err)
+	}
+
+	return overlaps, nil
+}
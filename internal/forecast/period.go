@@ -2,8 +2,13 @@ package forecast
 
 import (
 	"context"
+	"database/sql"
+	"encoding/json"
 	"sort"
 	"time"
+
+	"github.com/cicconee/weather-app/internal/app"
+	"github.com/cicconee/weather-app/internal/database"
 )
 
 // Period is the weather data for a 1-hour period of time. The Number field
@@ -13,16 +18,38 @@ import (
 //
 // When periods are in a collection, organizing them in ascending order by
 // number corresponds to moving forward in time.
+//
+// Duration is the length of the period. It should be used rather than
+// assuming a period is exactly 1 hour, since 12-hour forecasts and future
+// NWS data may not be hourly.
 type Period struct {
-	Number          int       `json:"number"`
-	StartTime       time.Time `json:"start_time"`
-	EndTime         time.Time `json:"end_time"`
-	IsDaytime       bool      `json:"is_day_time"`
-	Temperature     int       `json:"temperature"`
-	TemperatureUnit string    `json:"temperature_unit"`
-	WindSpeed       string    `json:"wind_speed"`
-	WindDirection   string    `json:"wind_direction"`
-	ShortForecast   string    `json:"short_forecast"`
+	Number          int           `json:"number"`
+	StartTime       time.Time     `json:"start_time"`
+	EndTime         time.Time     `json:"end_time"`
+	Duration        time.Duration `json:"-"`
+	IsDaytime       bool          `json:"is_day_time"`
+	Temperature     int           `json:"temperature"`
+	TemperatureUnit string        `json:"temperature_unit"`
+	WindSpeed       string        `json:"wind_speed"`
+	WindDirection   string        `json:"wind_direction"`
+	ShortForecast   string        `json:"short_forecast"`
+}
+
+// MarshalJSON formats the StartTime and EndTime as app.Time and
+// the Duration in seconds.
+func (p Period) MarshalJSON() ([]byte, error) {
+	type period Period
+	return json.Marshal(struct {
+		period
+		StartTime app.Time `json:"start_time"`
+		EndTime   app.Time `json:"end_time"`
+		Duration  int64    `json:"duration_seconds"`
+	}{
+		period:    period(p),
+		StartTime: app.Time(p.StartTime),
+		EndTime:   app.Time(p.EndTime),
+		Duration:  int64(p.Duration / time.Second),
+	})
 }
 
 // loadTimeZone formats the StartTime and EndTime of this Period to loc.
@@ -65,6 +92,45 @@ func (p *PeriodCollection) Sort() {
 	})
 }
 
+// Current returns the periods of this PeriodCollection that have not
+// ended by now.
+func (p PeriodCollection) Current(now time.Time) PeriodCollection {
+	current := PeriodCollection{}
+	for _, period := range p {
+		if period.EndTime.After(now) {
+			current = append(current, period)
+		}
+	}
+
+	return current
+}
+
+// Upcoming returns the first n periods of this sorted PeriodCollection
+// that have not ended by now. Periods that already ended are dropped.
+func (p PeriodCollection) Upcoming(now time.Time, n int) PeriodCollection {
+	upcoming := p.Current(now)
+	if len(upcoming) > n {
+		upcoming = upcoming[:n]
+	}
+
+	return upcoming
+}
+
+// At returns the period of this sorted PeriodCollection that covers now,
+// the first period whose StartTime is not after now and whose EndTime is
+// after now. If no period covers now, such as in a gap between periods or
+// before the first period, the next period to start is returned. It
+// reports false if every period ended by now.
+func (p PeriodCollection) At(now time.Time) (Period, bool) {
+	for _, period := range p {
+		if period.EndTime.After(now) {
+			return period, true
+		}
+	}
+
+	return Period{}, false
+}
+
 // PeriodAPIResource is the 1-hour weather data of a forecast that is returned
 // by ForecastAPI. PeriodAPIResource should never be explicitly created and only
 // be used when returned from ForecastAPI.
@@ -92,6 +158,7 @@ func (p *PeriodAPIResource) ToPeriodEntity() PeriodEntity {
 		Number:          p.Number,
 		StartTime:       p.StartTime.UTC(),
 		EndTime:         p.EndTime.UTC(),
+		Duration:        p.EndTime.Sub(p.StartTime),
 		IsDaytime:       p.IsDaytime,
 		Temperature:     p.Temperature,
 		TemperatureUnit: p.TemperatureUnit,
@@ -108,10 +175,14 @@ func (p *PeriodAPIResource) ToPeriodEntity() PeriodEntity {
 // by the ToPeriodEntity method of a PeriodAPIResource.
 //
 // A period belongs to a gridpoint. It cannot exist without a gridpoint.
+//
+// Duration is not stored. It is computed from StartTime and EndTime by
+// ToPeriodEntity and Scan.
 type PeriodEntity struct {
 	Number          int
 	StartTime       time.Time
 	EndTime         time.Time
+	Duration        time.Duration
 	IsDaytime       bool
 	Temperature     int
 	TemperatureUnit string
@@ -127,6 +198,7 @@ func (p *PeriodEntity) ToPeriod() Period {
 		Number:          p.Number,
 		StartTime:       p.StartTime,
 		EndTime:         p.EndTime,
+		Duration:        p.Duration,
 		IsDaytime:       p.IsDaytime,
 		Temperature:     p.Temperature,
 		TemperatureUnit: p.TemperatureUnit,
@@ -137,8 +209,9 @@ func (p *PeriodEntity) ToPeriod() Period {
 }
 
 // Scan will scan the query result in scanner into this PeriodEntity.
+// The Duration is computed from the scanned StartTime and EndTime.
 func (p *PeriodEntity) Scan(scanner Scanner) error {
-	return scanner.Scan(
+	err := scanner.Scan(
 		&p.Number,
 		&p.StartTime,
 		&p.EndTime,
@@ -149,6 +222,12 @@ func (p *PeriodEntity) Scan(scanner Scanner) error {
 		&p.WindDirection,
 		&p.ShortForecast,
 		&p.GridpointID)
+	if err != nil {
+		return err
+	}
+
+	p.Duration = p.EndTime.Sub(p.StartTime)
+	return nil
 }
 
 // Insert writes this PeriodEntity into the database. All fields being written
@@ -226,15 +305,16 @@ func (p *PeriodEntityCollection) Select(ctx context.Context, db Queryer, gridpoi
 	if err != nil {
 		return err
 	}
-	defer rows.Close()
 
-	for rows.Next() {
+	periods, err := database.ScanRows(rows, func(rows *sql.Rows) (PeriodEntity, error) {
 		period := PeriodEntity{}
-		if err := period.Scan(rows); err != nil {
-			return err
-		}
-		*p = append(*p, period)
+		err := period.Scan(rows)
+		return period, err
+	})
+	if err != nil {
+		return err
 	}
+	*p = append(*p, periods...)
 
 	return nil
 }
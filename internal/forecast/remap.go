@@ -0,0 +1,156 @@
+package forecast
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/cicconee/weather-app/internal/app"
+	"github.com/cicconee/weather-app/internal/geometry"
+)
+
+// GridpointSample is a stored gridpoint and the center of its geometric
+// bounds. The center is used to ask the NWS API which gridpoint currently
+// serves the area.
+type GridpointSample struct {
+	GridpointEntity
+	Center geometry.Point
+}
+
+// GridpointSampleCollection is a collection of GridpointSample.
+type GridpointSampleCollection []GridpointSample
+
+// Select reads at most limit randomly chosen gridpoints, and the center of
+// their geometric bounds, into this GridpointSampleCollection.
+func (g *GridpointSampleCollection) Select(ctx context.Context, db Queryer, limit int) error {
+	query := `SELECT id, grid_id, grid_x, grid_y, generated_at, expires_at, timezone, elevation,
+			  relative_location_city, relative_location_state, point(boundary) FROM gridpoints
+			  ORDER BY random() LIMIT $1`
+
+	rows, err := db.QueryContext(ctx, query, limit)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		sample := GridpointSample{}
+		var center string
+		err := rows.Scan(
+			&sample.ID,
+			&sample.GridID,
+			&sample.GridX,
+			&sample.GridY,
+			&sample.Timeline.GeneratedAt,
+			&sample.Timeline.ExpiresAt,
+			&sample.TimeZone,
+			&sample.Elevation,
+			&sample.RelativeLocationCity,
+			&sample.RelativeLocationState,
+			&center)
+		if err != nil {
+			return err
+		}
+
+		// A point wrapped in parentheses is a point collection
+		// of one point.
+		points, err := geometry.ParsePointCollection("(" + center + ")")
+		if err != nil {
+			return err
+		}
+		sample.Center = points[0]
+
+		*g = append(*g, sample)
+	}
+
+	return rows.Err()
+}
+
+// Grid is the NWS office and grid coordinates that serve a gridpoint.
+type Grid struct {
+	ID string `json:"grid_id"`
+	X  int    `json:"grid_x"`
+	Y  int    `json:"grid_y"`
+}
+
+// Remapping is a stored gridpoint that the NWS API no longer serves from
+// the same grid. The stored forecast is for the wrong grid and the
+// gridpoint should be evicted. Lon and Lat is the point that was sent to
+// the NWS API.
+type Remapping struct {
+	GridpointID int     `json:"gridpoint_id"`
+	Lon         float64 `json:"lon"`
+	Lat         float64 `json:"lat"`
+	Stored      Grid    `json:"stored"`
+	Current     Grid    `json:"current"`
+}
+
+// RemapReport is the result of VerifyGridpoints. Failed is the number of
+// sampled gridpoints the NWS API could not be asked about.
+type RemapReport struct {
+	Checked  int         `json:"checked"`
+	Failed   int         `json:"failed"`
+	Remapped []Remapping `json:"remapped"`
+}
+
+// MaxVerifySample is the most gridpoints that can be verified by a single
+// call to VerifyGridpoints.
+const MaxVerifySample = 50
+
+// VerifyGridpoints checks if the NWS API still maps a random sample of
+// stored gridpoints to the same grid. The center of each sampled gridpoint
+// is sent to the NWS API and the grid it returns is compared to the stored
+// grid. Gridpoints that no longer match are reported, nothing is deleted.
+//
+// Between 1 and MaxVerifySample gridpoints can be sampled. In read-only
+// mode the NWS API is never called, so a 409 is returned.
+func (s *Service) VerifyGridpoints(ctx context.Context, sample int) (RemapReport, error) {
+	if sample < 1 || sample > MaxVerifySample {
+		return RemapReport{}, app.NewServerResponseError(
+			fmt.Errorf("verifying %d gridpoints, must be between 1 and %d", sample, MaxVerifySample),
+			fmt.Sprintf("Sample must be between 1 and %d", MaxVerifySample),
+			http.StatusBadRequest)
+	}
+
+	if s.ReadOnly {
+		return RemapReport{}, app.NewServerResponseError(
+			fmt.Errorf("verifying gridpoints in read-only mode"),
+			"Gridpoints cannot be verified in read-only mode",
+			http.StatusConflict)
+	}
+
+	samples, err := s.Store.SelectGridpointSample(ctx, sample)
+	if err != nil {
+		return RemapReport{}, fmt.Errorf("selecting gridpoint sample (sample=%d): %w", sample, err)
+	}
+
+	report := RemapReport{Remapped: []Remapping{}}
+	for _, sample := range samples {
+		if err := ctx.Err(); err != nil {
+			return RemapReport{}, err
+		}
+
+		resource, err := s.gridpoint(ctx, sample.Center)
+		if err != nil {
+			log.Printf("failed to verify gridpoint (gridpoint.ID=%d, point=%v): %v\n", sample.ID, sample.Center, err)
+			report.Failed++
+			continue
+		}
+		report.Checked++
+
+		stored := Grid{ID: sample.GridID, X: sample.GridX, Y: sample.GridY}
+		current := Grid{ID: resource.GridID, X: resource.GridX, Y: resource.GridY}
+		if stored != current {
+			report.Remapped = append(report.Remapped, Remapping{
+				GridpointID: sample.ID,
+				Lon:         sample.Center.Lon(),
+				Lat:         sample.Center.Lat(),
+				Stored:      stored,
+				Current:     current,
+			})
+		}
+	}
+
+	return report, nil
+}
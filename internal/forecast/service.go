@@ -5,28 +5,58 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
+	"log"
 	"net/http"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/cicconee/weather-app/internal/app"
 	"github.com/cicconee/weather-app/internal/geometry"
+	"github.com/cicconee/weather-app/internal/pool"
+	"golang.org/x/sync/singleflight"
 )
 
-// ForecastAPI is the interface that wraps the GetGridpoint
-// and GetHourlyForecast methods.
+// ForecastAPI is the interface that wraps the GetGridpoint,
+// GetHourlyForecast and GetForecast methods. Each request is
+// cancelled when the context is done.
 //
 // GetGridpoint executes a HTTP GET request to the following url:
-// https://api.weather.gov/points/{longitude},{latitude}
-// It returns the server response in a GridpointAPIResource and
-// any errors encountered.
+// https://api.weather.gov/points/{latitude},{longitude}
+// It is passed the longitude and latitude, in that order, and returns
+// the server response in a GridpointAPIResource and any errors
+// encountered.
 //
 // GetHourlyForecast executes a HTTP GET request to the following url:
 // https://api.weather.gov/{grid_id}/{grid_x},{grid_y}/forecast/hourly
 // It returns the server response in a HourlyAPIResource and any
 // errors encountered.
+//
+// GetForecast executes a HTTP GET request to the following url:
+// https://api.weather.gov/{grid_id}/{grid_x},{grid_y}/forecast
+// It returns the 12-hour periods in a HourlyAPIResource and any
+// errors encountered.
 type ForecastAPI interface {
-	GetGridpoint(float64, float64) (GridpointAPIResource, error)
-	GetHourlyForecast(string, int, int) (HourlyAPIResource, error)
+	GetGridpoint(context.Context, float64, float64) (GridpointAPIResource, error)
+	GetHourlyForecast(context.Context, string, int, int) (HourlyAPIResource, error)
+	GetForecast(context.Context, string, int, int) (HourlyAPIResource, error)
+}
+
+// ConditionalForecastAPI is implemented by a ForecastAPI that can make
+// conditional requests.
+//
+// GetHourlyForecastIfModified gets the hourly forecast like GetHourlyForecast
+// and sends the time as the If-Modified-Since header. If the forecast has not
+// been modified since then, the NWS API responds with a 304 status code and
+// the returned HourlyAPIResource only has NotModified and Expires set.
+type ConditionalForecastAPI interface {
+	GetHourlyForecastIfModified(context.Context, string, int, int, time.Time) (HourlyAPIResource, error)
+}
+
+// RetryBudgeter is implemented by a ForecastAPI that caps the retries
+// of failed requests. AllowRetry reports if a retry may be made.
+type RetryBudgeter interface {
+	AllowRetry() bool
 }
 
 // Service serves hourly forecasts. Hourly forecasts are retrieved from
@@ -43,6 +73,114 @@ type Service struct {
 
 	// The database storage.
 	Store *Store
+
+	// ReadOnly serves forecasts only from the database. The NWS API
+	// is never called, so points without a stored gridpoint are not
+	// available and expired forecasts are served as they are.
+	ReadOnly bool
+
+	// Precision is the number of decimal places a point is rounded to
+	// before it is matched to a stored gridpoint or sent to the NWS API.
+	// Zero uses geometry.DefaultPrecision.
+	//
+	// Rounding lets nearby requests share a cache entry. A lower
+	// precision matches more requests to one gridpoint but moves points
+	// further from where they were requested, so points near the edge
+	// of a gridpoint may be matched to its neighbor. 4 decimal places
+	// is within about 11 meters.
+	Precision uint
+
+	// IncludePast returns every stored period, including periods that
+	// already ended. By default periods that ended are dropped so a
+	// forecast starts at the current hour. It is meant for debugging.
+	IncludePast bool
+
+	// RefreshWithin is how close to expiring a stored forecast must be
+	// to be refreshed in the background. The stored forecast is served
+	// while Pool refreshes it. Zero, or a nil Pool, only refreshes
+	// forecasts once they expire, while the request waits.
+	RefreshWithin time.Duration
+
+	// Pool runs the background refreshes.
+	Pool *pool.Pool
+
+	// SkyCover is optional. If set, Get includes the hourly sky cover of
+	// the gridpoint when the API implements GridDataAPI. The sky cover is
+	// fetched from the NWS API and cached in memory until the forecast of
+	// the gridpoint expires. If it cannot be fetched it is omitted and the
+	// forecast is still returned.
+	SkyCover bool
+
+	// skyCovers holds the sky cover of each grid fetched for SkyCover.
+	skyCovers skyCoverCache
+
+	// NoForecastTTL is how long a point without a forecast is
+	// remembered. Requests for it fail with the same error until then
+	// without calling the NWS API. Zero uses DefaultNoForecastTTL and a
+	// negative value disables it.
+	NoForecastTTL time.Duration
+
+	// noForecast holds the points without a forecast.
+	noForecast noForecastCache
+
+	// ForceRefreshInterval is the least time between updates of a
+	// gridpoint forced by GetFresh, so clients cannot use it to
+	// hammer the NWS API. Zero uses DefaultForceRefreshInterval and a
+	// negative value disables forced updates.
+	ForceRefreshInterval time.Duration
+
+	// forced holds when each gridpoint was last force refreshed.
+	forced forceGate
+
+	// fetches holds the fetches of each gridpoint until FlushFetches
+	// writes them.
+	fetches fetchCounter
+
+	// WriteTimeout is the deadline of a write shared by concurrent
+	// requests for the same point. The write does not use the context
+	// of any one request, so it is not cancelled when the request that
+	// started it is. Zero or less uses DefaultWriteTimeout.
+	WriteTimeout time.Duration
+
+	// writes coalesces concurrent writes of the same point so they
+	// share one fetch from the NWS API and one insert.
+	writes singleflight.Group
+
+	// refreshing holds the IDs of the gridpoints being refreshed in
+	// the background.
+	refreshing sync.Map
+
+	// Clock is the time the service reads as now, such as to decide if
+	// a forecast expired. Nil uses the system time.
+	Clock app.Clock
+}
+
+func (s *Service) now() time.Time {
+	return app.Now(s.Clock)
+}
+
+func (s *Service) precision() uint {
+	if s.Precision == 0 {
+		return geometry.DefaultPrecision
+	}
+
+	return s.Precision
+}
+
+func (s *Service) noForecastTTL() time.Duration {
+	if s.NoForecastTTL == 0 {
+		return DefaultNoForecastTTL
+	}
+
+	return s.NoForecastTTL
+}
+
+func (s *Service) writeTimeout() time.Duration {
+	if s.WriteTimeout <= 0 {
+		return DefaultWriteTimeout
+	}
+
+	return s.WriteTimeout
 }
 
 // New will return a pointer to a Service.
@@ -53,40 +191,478 @@ func New(api ForecastAPI, db *sql.DB) *Service {
 	}
 }
 
-// Get will get the hourly forecast periods for the specified point.
-func (s *Service) Get(ctx context.Context, point geometry.Point) (PeriodCollection, error) {
+// Get will get the hourly forecast for the specified point. Periods that
+// already ended are dropped unless IncludePast is set. If maxPeriods is
+// greater than zero, only the first maxPeriods periods that have not ended
+// are returned. The sunrise and sunset of each day the returned periods
+// cover is included.
+func (s *Service) Get(ctx context.Context, point geometry.Point, maxPeriods int) (Forecast, error) {
+	return s.getPeriods(ctx, point, maxPeriods, false)
+}
+
+// GetFresh gets the hourly forecast for the specified point the same as Get,
+// but a stored forecast that has not expired is updated from the NWS API
+// first, such as when a user explicitly refreshes. A gridpoint is only
+// updated early once every ForceRefreshInterval, otherwise the stored
+// forecast is returned the same as Get.
+func (s *Service) GetFresh(ctx context.Context, point geometry.Point, maxPeriods int) (Forecast, error) {
+	return s.getPeriods(ctx, point, maxPeriods, true)
+}
+
+// getPeriods gets the forecast for point and trims its periods for Get
+// and GetFresh. If force is set, get updates a stored forecast that has
+// not expired.
+func (s *Service) getPeriods(ctx context.Context, point geometry.Point, maxPeriods int, force bool) (Forecast, error) {
+	fc, err := s.get(ctx, point, force)
+	if err != nil {
+		return fc, err
+	}
+
+	fc.Periods = s.trim(fc.Periods, maxPeriods)
+	fc.Sun = SunDays(fc.Point.Lat(), fc.Point.Lon(), fc.Periods)
+
+	if s.SkyCover {
+		fc.SkyCover = s.skyCover(ctx, fc)
+	}
+
+	return fc, nil
+}
+
+// GetCurrent gets the forecast for the specified point the same as Get, but
+// only with the period that covers the current time. If no period covers
+// it, the next period to start is returned. The sunrise, sunset and sky
+// cover are not included.
+//
+// If every stored period already ended, a 404 is returned.
+func (s *Service) GetCurrent(ctx context.Context, point geometry.Point) (Forecast, error) {
+	fc, err := s.get(ctx, point, false)
+	if err != nil {
+		return fc, err
+	}
+
+	period, ok := fc.Periods.At(s.now())
+	if !ok {
+		return Forecast{}, app.NewServerResponseError(
+			fmt.Errorf("no current period for point (lon=%f, lat=%f)", fc.Point.Lon(), fc.Point.Lat()),
+			fmt.Sprintf("No current forecast available for %f,%f", fc.Point.Lon(), fc.Point.Lat()),
+			http.StatusNotFound)
+	}
+
+	fc.Periods = PeriodCollection{period}
+	return fc, nil
+}
+
+// trim drops the periods that already ended unless IncludePast is set. If
+// maxPeriods is greater than zero, only the first maxPeriods periods that
+// have not ended are kept.
+func (s *Service) trim(periods PeriodCollection, maxPeriods int) PeriodCollection {
+	now := s.now()
+	if !s.IncludePast {
+		periods = periods.Current(now)
+	}
+
+	if maxPeriods > 0 {
+		periods = periods.Upcoming(now, maxPeriods)
+	}
+
+	return periods
+}
+
+// GetByGrid gets the hourly forecast of the stored gridpoint with the grid
+// identifier gridID and grid coordinates gridX and gridY, such as from a
+// prior response, without matching a point to it. The periods are trimmed
+// the same as Get, but the sunrise, sunset and sky cover are not included.
+// An expired forecast is updated from the NWS API.
+//
+// The NWS API only reports the time zone and nearest city of a gridpoint
+// for a point, so a gridpoint that is not stored is never fetched and a 404
+// is returned.
+func (s *Service) GetByGrid(ctx context.Context, gridID string, gridX int, gridY int, maxPeriods int) (Forecast, error) {
+	gridID = strings.ToUpper(gridID)
+
+	gridpoint, err := s.Store.SelectGridpointByGrid(ctx, gridID, gridX, gridY)
+	if errors.Is(err, sql.ErrNoRows) {
+		return Forecast{}, app.NewServerResponseError(
+			fmt.Errorf("no stored gridpoint (GridID=%s, GridX=%d, GridY=%d)", gridID, gridX, gridY),
+			fmt.Sprintf("No forecast stored for %s %d,%d, request it by lon and lat", gridID, gridX, gridY),
+			http.StatusNotFound)
+	}
+	if err != nil {
+		return Forecast{}, fmt.Errorf("selecting gridpoint (GridID=%s, GridX=%d, GridY=%d): %w", gridID, gridX, gridY, err)
+	}
+
+	fc, err := s.serve(ctx, gridpoint, false)
+	if err != nil {
+		return fc, err
+	}
+
+	fc.Periods = s.trim(fc.Periods, maxPeriods)
+	return fc, nil
+}
+
+// skyCover gets the hourly sky cover of the gridpoint of fc for the hours
+// its Periods cover. It returns nil if the API does not implement
+// GridDataAPI or the sky cover cannot be fetched. The sky cover of a
+// gridpoint is cached until its forecast expires.
+func (s *Service) skyCover(ctx context.Context, fc Forecast) []HourlyValue {
+	api, ok := s.API.(GridDataAPI)
+	if !ok || s.ReadOnly || len(fc.Periods) == 0 || fc.grid.ID == "" {
+		return nil
+	}
+
+	hourly, ok := s.skyCovers.get(fc.grid, s.now())
+	if !ok {
+		data, err := api.GetGridData(ctx, fc.grid.ID, fc.grid.X, fc.grid.Y)
+		if err != nil {
+			log.Printf("failed to get grid data (GridID=%s, GridX=%d, GridY=%d): %v\n", fc.grid.ID, fc.grid.X, fc.grid.Y, err)
+			return nil
+		}
+
+		hourly, err = data.SkyCover.Hourly()
+		if err != nil {
+			log.Printf("failed to parse sky cover (GridID=%s, GridX=%d, GridY=%d): %v\n", fc.grid.ID, fc.grid.X, fc.grid.Y, err)
+			return nil
+		}
+
+		s.skyCovers.put(fc.grid, hourly, fc.ValidUntil, s.now())
+	}
+
+	start := fc.Periods[0].StartTime
+	end := fc.Periods[len(fc.Periods)-1].EndTime
+	covered := []HourlyValue{}
+	for _, v := range hourly {
+		if !v.Time.Before(start) && v.Time.Before(end) {
+			v.Time = v.Time.In(start.Location())
+			covered = append(covered, v)
+		}
+	}
+	if len(covered) == 0 {
+		return nil
+	}
+
+	return covered
+}
+
+// get gets the forecast for point. A point without a stored gridpoint is
+// fetched from the NWS API and written. A stored forecast is updated from
+// the NWS API once it expires, or if force is set and the gridpoint may be
+// force refreshed.
+func (s *Service) get(ctx context.Context, point geometry.Point, force bool) (Forecast, error) {
+	point = point.Round(s.precision())
+
 	gridpoint, err := s.Store.SelectGridpoint(ctx, point)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
-			return s.write(ctx, point)
+			if s.ReadOnly {
+				return Forecast{}, app.NewServerResponseError(
+					fmt.Errorf("no stored forecast for point (lon=%f, lat=%f)", point.Lon(), point.Lat()),
+					fmt.Sprintf("No forecast available for %f,%f", point.Lon(), point.Lat()),
+					http.StatusNotFound)
+			}
+
+			fc, err := s.coalescedWrite(ctx, point)
+			fc.Source = SourceFresh
+			return fc, err
+		}
+
+		return Forecast{}, fmt.Errorf("selecting gridpoint (point=%v): %w", point, err)
+	}
+
+	fc, err := s.serve(ctx, gridpoint, force)
+	fc.Point = point
+	fc.grid = Grid{ID: gridpoint.GridID, X: gridpoint.GridX, Y: gridpoint.GridY}
+	return fc, err
+}
+
+// serve returns the forecast of a stored gridpoint. The forecast is updated
+// from the NWS API if it expired, or if force is set and the gridpoint may
+// be force refreshed. If a forced update fails the stored forecast is
+// served instead. A forecast close to expiring is refreshed in the
+// background.
+func (s *Service) serve(ctx context.Context, gridpoint GridpointEntity, force bool) (Forecast, error) {
+	// Count the fetch so the gridpoint is not evicted while it is in
+	// use. It is written to the database by FlushFetches.
+	if !s.ReadOnly {
+		s.fetches.add(gridpoint.ID, 1, s.now().UTC())
+	}
+
+	expired := isExpired(gridpoint, s.now())
+	if !s.ReadOnly && expired {
+		fc, err := s.update(ctx, gridpoint)
+		fc.Source = SourceFresh
+		return fc, err
+	}
+
+	// The stored forecast has not expired, so a failed forced update
+	// still serves it.
+	if !s.ReadOnly && force && s.allowForce(gridpoint.ID) {
+		fc, err := s.update(ctx, gridpoint)
+		if err == nil {
+			s.recordForce(gridpoint.ID)
+			fc.Source = SourceFresh
+			return fc, nil
 		}
 
-		return PeriodCollection{}, fmt.Errorf("selecting gridpoint (point=%v): %w", point, err)
+		log.Printf("failed forced update, serving stored forecast (gridpoint.ID=%d): %v\n", gridpoint.ID, err)
 	}
 
-	if time.Now().After(gridpoint.Timeline.ExpiresAt) {
-		return s.update(ctx, gridpoint)
+	if s.refreshSoon(gridpoint) {
+		s.refresh(gridpoint)
 	}
 
+	fc, err := s.stored(ctx, gridpoint)
+	fc.Source = SourceCached
+	if expired {
+		fc.Source = SourceStale
+	}
+	return fc, err
+}
+
+// stored returns the forecast of a gridpoint from the periods stored in
+// the database.
+func (s *Service) stored(ctx context.Context, gridpoint GridpointEntity) (Forecast, error) {
 	periodEntityCollection, err := s.Store.SelectPeriodCollection(ctx, gridpoint.ID)
 	if err != nil {
-		return PeriodCollection{}, fmt.Errorf("selecting periods (gridpoint.ID=%d): %w", gridpoint.ID, err)
+		return Forecast{}, fmt.Errorf("selecting periods (gridpoint.ID=%d): %w", gridpoint.ID, err)
 	}
 
-	location, err := time.LoadLocation(gridpoint.TimeZone)
+	location, err := loadLocation(gridpoint.TimeZone)
 	if err != nil {
-		return PeriodCollection{}, fmt.Errorf("loading location (name=%s): %w", gridpoint.TimeZone, err)
+		return Forecast{}, fmt.Errorf("loading location (name=%s): %w", gridpoint.TimeZone, err)
+	}
+
+	return Forecast{
+		Periods:          periodEntityCollection.ToPeriods(location),
+		ValidUntil:       gridpoint.Timeline.ExpiresAt,
+		Elevation:        gridpoint.Elevation,
+		RelativeLocation: gridpoint.RelativeLocation(),
+	}, nil
+}
+
+// refreshSoon reports if the gridpoint should be refreshed in the
+// background.
+func (s *Service) refreshSoon(gridpoint GridpointEntity) bool {
+	if s.ReadOnly || s.Pool == nil || s.RefreshWithin <= 0 {
+		return false
 	}
 
-	return periodEntityCollection.ToPeriods(location), nil
+	return s.now().Add(s.RefreshWithin).After(gridpoint.Timeline.ExpiresAt)
+}
+
+// RefreshExpiring refreshes in the background the stored gridpoints whose
+// forecast expires within RefreshWithin, so they are fresh before they are
+// next requested. At most RefreshBatchSize gridpoints are refreshed, oldest
+// first. It does nothing under the same conditions as the refresh of a
+// requested gridpoint: in read-only mode, without a Pool, or when
+// RefreshWithin is not positive.
+func (s *Service) RefreshExpiring(ctx context.Context) error {
+	if s.ReadOnly || s.Pool == nil || s.RefreshWithin <= 0 {
+		return nil
+	}
+
+	gridpoints, err := s.Store.SelectExpiringGridpoints(ctx, s.now().Add(s.RefreshWithin).UTC())
+	if err != nil {
+		return fmt.Errorf("selecting expiring gridpoints: %w", err)
+	}
+
+	for _, gridpoint := range gridpoints {
+		s.refresh(gridpoint)
+	}
+
+	return nil
+}
+
+// RunRefresh calls RefreshExpiring every d until killCh receives. Failed
+// refreshes are logged.
+func (s *Service) RunRefresh(d time.Duration, killCh <-chan struct{}) {
+	ticker := time.NewTicker(d)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if err := s.RefreshExpiring(context.Background()); err != nil {
+				log.Printf("failed to refresh expiring gridpoints: %v\n", err)
+			}
+		case <-killCh:
+			return
+		}
+	}
+}
+
+// refresh updates the gridpoint in the background. A gridpoint already
+// being refreshed is skipped. If the pool is full the refresh is
+// skipped, the gridpoint will be updated once it expires.
+func (s *Service) refresh(gridpoint GridpointEntity) {
+	if _, loaded := s.refreshing.LoadOrStore(gridpoint.ID, struct{}{}); loaded {
+		return
+	}
+
+	added := s.Pool.TryAdd(func() {
+		defer s.refreshing.Delete(gridpoint.ID)
+
+		if _, err := s.update(context.Background(), gridpoint); err != nil {
+			log.Printf("failed to refresh gridpoint (gridpoint.ID=%d): %v\n", gridpoint.ID, err)
+		}
+	})
+	if !added {
+		s.refreshing.Delete(gridpoint.ID)
+	}
+}
+
+// Gridpoint gets the stored gridpoint that point maps to, with its
+// geometry but without its periods. The point is rounded the same as
+// in Get. If no stored gridpoint covers the point, a 404 is returned.
+// The NWS API is never called.
+func (s *Service) Gridpoint(ctx context.Context, point geometry.Point) (GridpointResponse, error) {
+	point = point.Round(s.precision())
+
+	gridpoint, err := s.Store.SelectGridpointWithGeometry(ctx, point)
+	if errors.Is(err, sql.ErrNoRows) {
+		return GridpointResponse{}, app.NewServerResponseError(
+			fmt.Errorf("no stored gridpoint for point (lon=%f, lat=%f)", point.Lon(), point.Lat()),
+			fmt.Sprintf("No gridpoint stored for %f,%f", point.Lon(), point.Lat()),
+			http.StatusNotFound)
+	}
+	if err != nil {
+		return GridpointResponse{}, fmt.Errorf("selecting gridpoint (point=%v): %w", point, err)
+	}
+
+	return gridpoint.AsResponse(), nil
+}
+
+// DefaultBBoxGridpoints is the number of gridpoints GridpointsInBBox returns
+// when no limit is given.
+const DefaultBBoxGridpoints = 100
+
+// MaxBBoxGridpoints is the most gridpoints GridpointsInBBox returns at once.
+const MaxBBoxGridpoints = 500
+
+// GridpointPage is a page of stored gridpoints and the total number of stored
+// gridpoints that match.
+type GridpointPage struct {
+	Gridpoints []GridpointResponse
+	Total      int
+}
+
+// GridpointsInBBox gets a page of the stored gridpoints whose geometric bounds
+// overlap bbox, with their geometry but without their periods, skipping the
+// first offset gridpoints. A limit of zero uses DefaultBBoxGridpoints, and a
+// limit over MaxBBoxGridpoints uses MaxBBoxGridpoints. The NWS API is never
+// called.
+func (s *Service) GridpointsInBBox(ctx context.Context, bbox geometry.BBox, limit int, offset int) (GridpointPage, error) {
+	if !bbox.IsValid() {
+		return GridpointPage{}, app.NewServerResponseError(
+			fmt.Errorf("invalid bbox %+v", bbox),
+			"Invalid bounding box, the minimums must be less than the maximums",
+			http.StatusBadRequest)
+	}
+
+	if limit <= 0 {
+		limit = DefaultBBoxGridpoints
+	}
+	if limit > MaxBBoxGridpoints {
+		limit = MaxBBoxGridpoints
+	}
+
+	gridpoints, total, err := s.Store.SelectGridpointsOverlaps(ctx, bbox, limit, offset)
+	if err != nil {
+		return GridpointPage{}, fmt.Errorf("selecting gridpoints (bbox=%+v): %w", bbox, err)
+	}
+
+	page := GridpointPage{Gridpoints: []GridpointResponse{}, Total: total}
+	for _, gridpoint := range gridpoints {
+		page.Gridpoints = append(page.Gridpoints, gridpoint.AsResponse())
+	}
+
+	return page, nil
+}
+
+// MinEvictAge is the smallest age EvictStale accepts. Gridpoints younger
+// than this are still likely to be requested again.
+const MinEvictAge = 24 * time.Hour
+
+// EvictStale deletes the gridpoints, and their periods, that were generated
+// and last fetched more than olderThan ago. It returns the number of
+// gridpoints deleted. Evicted gridpoints are fetched from the NWS API again
+// the next time they are requested.
+func (s *Service) EvictStale(ctx context.Context, olderThan time.Duration) (int64, error) {
+	if olderThan < MinEvictAge {
+		return 0, app.NewServerResponseError(
+			fmt.Errorf("evict age %v is less than %v", olderThan, MinEvictAge),
+			fmt.Sprintf("Age must be at least %v", MinEvictAge),
+			http.StatusBadRequest)
+	}
+
+	n, err := s.Store.DeleteStaleGridpoints(ctx, s.now().UTC().Add(-olderThan))
+	if err != nil {
+		return 0, fmt.Errorf("deleting stale gridpoints (olderThan=%v): %w", olderThan, err)
+	}
+
+	return n, nil
+}
+
+// DefaultTimeZone is the time zone stored for gridpoints
+// the NWS API returns without one.
+const DefaultTimeZone = "UTC"
+
+// loadLocation returns the location for the time zone name.
+// Gridpoints stored without a time zone use DefaultTimeZone.
+func loadLocation(name string) (*time.Location, error) {
+	if name == "" {
+		name = DefaultTimeZone
+	}
+
+	return time.LoadLocation(name)
+}
+
+// DefaultWriteTimeout is the deadline of a shared write when
+// Service.WriteTimeout is not set.
+const DefaultWriteTimeout = 30 * time.Second
+
+// coalescedWrite calls write for point. Concurrent calls for the same
+// point wait for the first call and share its result, including the
+// error. The write runs with its own deadline of WriteTimeout, so one
+// caller giving up does not fail the others. A caller whose ctx is done
+// returns its error without waiting for the write.
+//
+// If write reports the point has no forecast, the error is remembered
+// for NoForecastTTL and returned without calling write again.
+func (s *Service) coalescedWrite(ctx context.Context, point geometry.Point) (Forecast, error) {
+	key := point.Key()
+	ttl := s.noForecastTTL()
+	if ttl > 0 {
+		if err := s.noForecast.get(key, s.now()); err != nil {
+			return Forecast{}, err
+		}
+	}
+
+	ch := s.writes.DoChan(key, func() (any, error) {
+		writeCtx, cancel := context.WithTimeout(context.Background(), s.writeTimeout())
+		defer cancel()
+
+		fc, err := s.write(writeCtx, point)
+		if ttl > 0 && isNoForecast(err) {
+			now := s.now()
+			s.noForecast.put(key, err, now.Add(ttl), now)
+		}
+		return fc, err
+	})
+
+	select {
+	case res := <-ch:
+		return res.Val.(Forecast), res.Err
+	case <-ctx.Done():
+		return Forecast{}, ctx.Err()
+	}
 }
 
 // write will get the gridpoint and hourly forecast data from the NWS API. Once
 // fetched, it will write the data to the database.
-func (s *Service) write(ctx context.Context, point geometry.Point) (PeriodCollection, error) {
-	gridpointResource, err := s.gridpoint(point)
+func (s *Service) write(ctx context.Context, point geometry.Point) (Forecast, error) {
+	gridpointResource, err := s.gridpoint(ctx, point)
 	if err != nil {
-		return PeriodCollection{}, fmt.Errorf("write: fetching gridpoint (lon=%f, lat=%f): %w", point.Lon(), point.Lat(), err)
+		return Forecast{}, fmt.Errorf("write: fetching gridpoint (lon=%f, lat=%f): %w", point.Lon(), point.Lat(), err)
 	}
 
 	// Some points are recognized by the NWS API as valid but do not have
@@ -95,19 +671,39 @@ func (s *Service) write(ctx context.Context, point geometry.Point) (PeriodCollec
 	// be a 200 status code with GridID not set. These are points without
 	// forecasts.
 	if gridpointResource.GridID == "" {
-		return PeriodCollection{}, app.NewServerResponseError(
-			fmt.Errorf("write: no forecast for point (lon=%f, lat=%f)", point.Lon(), point.Lat()),
+		return Forecast{}, app.NewServerResponseError(
+			fmt.Errorf("write: %w for point (lon=%f, lat=%f)", errNoGrid, point.Lon(), point.Lat()),
 			fmt.Sprintf("%f,%f is not a supported area", point.Lon(), point.Lat()),
 			http.StatusBadRequest)
 	}
 
-	hourlyResource, err := s.hourly(hourlyParams{
+	// Some gridpoints have no time zone. Store DefaultTimeZone
+	// so the periods are localized the same on every path.
+	if gridpointResource.TimeZone == "" {
+		log.Printf("write: gridpoint has no time zone, using %s (GridID=%s, GridX=%d, GridY=%d)\n",
+			DefaultTimeZone,
+			gridpointResource.GridID,
+			gridpointResource.GridX,
+			gridpointResource.GridY)
+		gridpointResource.TimeZone = DefaultTimeZone
+	}
+
+	params := hourlyParams{
 		GridID: gridpointResource.GridID,
 		GridX:  gridpointResource.GridX,
 		GridY:  gridpointResource.GridY,
-	})
+	}
+	hourlyResource, err := s.hourly(ctx, params)
+	if errors.Is(err, errNoHourly) {
+		fc, err := s.twelveHour(ctx, params, gridpointResource.TimeZone)
+		fc.Point = point
+		fc.grid = Grid{ID: gridpointResource.GridID, X: gridpointResource.GridX, Y: gridpointResource.GridY}
+		gridpointEntity := gridpointResource.ToGridpointEntity()
+		fc.RelativeLocation = gridpointEntity.RelativeLocation()
+		return fc, err
+	}
 	if err != nil {
-		return PeriodCollection{},
+		return Forecast{},
 			fmt.Errorf("write: fetching hourly (GridID=%s, GridX=%d, GridY=%d): %w",
 				gridpointResource.GridID,
 				gridpointResource.GridX,
@@ -118,33 +714,53 @@ func (s *Service) write(ctx context.Context, point geometry.Point) (PeriodCollec
 	gridpointEntity := gridpointResource.ToGridpointEntity()
 	gridpointEntity.Geometry = hourlyResource.Geometry
 	gridpointEntity.Timeline = hourlyResource.Timeline()
+	gridpointEntity.Elevation = hourlyResource.Elevation.Meters()
+	gridpointEntity.LastModified = hourlyResource.lastModified()
 	periodEntityCollection := hourlyResource.ToPeriodEntityCollection()
 	err = s.Store.InsertGridpointPeriodsTx(ctx, GridpointPeriodsTxParams{
 		Gridpoint: &gridpointEntity,
 		Periods:   periodEntityCollection,
 	})
 	if err != nil {
-		return PeriodCollection{}, err
+		return Forecast{}, err
 	}
 
-	location, err := time.LoadLocation(gridpointEntity.TimeZone)
+	location, err := loadLocation(gridpointEntity.TimeZone)
 	if err != nil {
-		return PeriodCollection{}, fmt.Errorf("write: loading location (name=%s): %w", gridpointEntity.TimeZone, err)
+		return Forecast{}, fmt.Errorf("write: loading location (name=%s): %w", gridpointEntity.TimeZone, err)
 	}
 
-	return periodEntityCollection.ToPeriods(location), nil
+	return Forecast{
+		Point:            point,
+		Periods:          periodEntityCollection.ToPeriods(location),
+		ValidUntil:       gridpointEntity.Timeline.ExpiresAt,
+		Elevation:        gridpointEntity.Elevation,
+		RelativeLocation: gridpointEntity.RelativeLocation(),
+		grid:             Grid{ID: gridpointEntity.GridID, X: gridpointEntity.GridX, Y: gridpointEntity.GridY},
+	}, nil
 }
 
 // update will get the hourly forecast data for a gridpoint from the NWS API. Once
 // fetched, the gridpoint and hourly forecast will be updated in the database.
-func (s *Service) update(ctx context.Context, gridpoint GridpointEntity) (PeriodCollection, error) {
-	hourlyResource, err := s.hourly(hourlyParams{
-		GridID: gridpoint.GridID,
-		GridX:  gridpoint.GridX,
-		GridY:  gridpoint.GridY,
-	})
+//
+// If the NWS API reports the forecast has not been modified since it was stored,
+// only the expiration of the gridpoint is extended and the stored periods are
+// returned.
+func (s *Service) update(ctx context.Context, gridpoint GridpointEntity) (Forecast, error) {
+	params := hourlyParams{
+		GridID:          gridpoint.GridID,
+		GridX:           gridpoint.GridX,
+		GridY:           gridpoint.GridY,
+		IfModifiedSince: gridpoint.LastModified,
+	}
+	hourlyResource, err := s.hourly(ctx, params)
+	if errors.Is(err, errNoHourly) {
+		fc, err := s.twelveHour(ctx, params, gridpoint.TimeZone)
+		fc.RelativeLocation = gridpoint.RelativeLocation()
+		return fc, err
+	}
 	if err != nil {
-		return PeriodCollection{},
+		return Forecast{},
 			fmt.Errorf("update: fetching hourly (GridID=%s, GridX=%d, GridY=%d): %w",
 				gridpoint.GridID,
 				gridpoint.GridX,
@@ -152,43 +768,52 @@ func (s *Service) update(ctx context.Context, gridpoint GridpointEntity) (Period
 				err)
 	}
 
+	if hourlyResource.NotModified {
+		return s.extend(ctx, gridpoint, hourlyResource.Expires)
+	}
+
 	gridpoint.Timeline = hourlyResource.Timeline()
+	gridpoint.Elevation = hourlyResource.Elevation.Meters()
+	gridpoint.LastModified = hourlyResource.lastModified()
 	periodEntityCollection := hourlyResource.ToPeriodEntityCollection()
 	err = s.Store.UpdateGridpointPeriodTx(ctx, GridpointPeriodsTxParams{
 		Gridpoint: &gridpoint,
 		Periods:   periodEntityCollection,
 	})
 	if err != nil {
-		return PeriodCollection{}, fmt.Errorf("update: updating gridpoint and periods (gridpoint.ID=%d): %w",
+		return Forecast{}, fmt.Errorf("update: updating gridpoint and periods (gridpoint.ID=%d): %w",
 			gridpoint.ID,
 			err)
 	}
 
-	location, err := time.LoadLocation(gridpoint.TimeZone)
+	location, err := loadLocation(gridpoint.TimeZone)
 	if err != nil {
-		return PeriodCollection{}, fmt.Errorf("update: loading location (name=%s): %w", gridpoint.TimeZone, err)
+		return Forecast{}, fmt.Errorf("update: loading location (name=%s): %w", gridpoint.TimeZone, err)
 	}
 
-	return periodEntityCollection.ToPeriods(location), nil
+	return Forecast{
+		Periods:          periodEntityCollection.ToPeriods(location),
+		ValidUntil:       gridpoint.Timeline.ExpiresAt,
+		Elevation:        gridpoint.Elevation,
+		RelativeLocation: gridpoint.RelativeLocation(),
+	}, nil
 }
 
 // gridpoint calls the GetGridpoint method of ForecastAPI for a point.
 // If a 400 or 404 status code is returned it will return an Error with
 // a safe message.
-func (s *Service) gridpoint(point geometry.Point) (GridpointAPIResource, error) {
-	gridpoint, err := s.API.GetGridpoint(point.Lon(), point.Lat())
+func (s *Service) gridpoint(ctx context.Context, point geometry.Point) (GridpointAPIResource, error) {
+	gridpoint, err := s.API.GetGridpoint(ctx, point.Lon(), point.Lat())
 	var apiErr *app.NWSAPIStatusCodeError
 	switch {
 	case err == nil:
 		return gridpoint, nil
+	case errors.Is(err, app.ErrNWSBadRequest), errors.Is(err, app.ErrNWSNotFound):
+		return GridpointAPIResource{}, app.NewServerResponseError(
+			fmt.Errorf("not supported by api: %w", err),
+			fmt.Sprintf("%f,%f is not a supported area", point.Lon(), point.Lat()),
+			http.StatusBadRequest)
 	case errors.As(err, &apiErr):
-		if apiErr.StatusCode == 400 || apiErr.StatusCode == 404 {
-			return GridpointAPIResource{}, app.NewServerResponseError(
-				fmt.Errorf("not supported by api: %w", apiErr),
-				fmt.Sprintf("%f,%f is not a supported area", point.Lon(), point.Lat()),
-				http.StatusBadRequest)
-		}
-
 		return GridpointAPIResource{}, fmt.Errorf("unexpected status code: %w", apiErr)
 	default:
 		return GridpointAPIResource{}, err
@@ -196,50 +821,59 @@ func (s *Service) gridpoint(point geometry.Point) (GridpointAPIResource, error)
 }
 
 // hourlyParams is the parameters for the hourly method.
-// When passing hourlyParams to hourly, all fields should be set.
+// When passing hourlyParams to hourly, all fields except
+// IfModifiedSince should be set.
 type hourlyParams struct {
 	GridID string
 	GridX  int
 	GridY  int
+
+	// IfModifiedSince is optional. If set and the API
+	// implements ConditionalForecastAPI, a conditional
+	// request is made.
+	IfModifiedSince *time.Time
 }
 
+// errNoHourly is returned by hourly when the NWS API has no hourly
+// forecast for a valid gridpoint.
+var errNoHourly = errors.New("no hourly forecast")
+
 // hourly calls the GetHourlyForecast method of ForecastAPI for a gridpoint.
-// If a 404 status code is returned it will return an Error with a safe message.
+// If a 404 status code is returned it will return errNoHourly.
 //
 // It is a known issue that sometimes a 500 status code is returned from the NWS API
 // hourly forecast endpoint for a valid gridpoint. The NWS API recommends retrying the
-// request a few times. This will sometimes fix it.
-func (s *Service) hourly(p hourlyParams) (HourlyAPIResource, error) {
+// request a few times. This will sometimes fix it. The retry is skipped if the API
+// retry budget is spent. If the last attempt still returns a 500 status code, a 502
+// is returned, since the gridpoint is valid but the NWS API failed.
+func (s *Service) hourly(ctx context.Context, p hourlyParams) (HourlyAPIResource, error) {
 	var (
 		rErr     error
 		attempts = 0
 	)
 
 	for attempts < 2 {
-		hourly, err := s.API.GetHourlyForecast(p.GridID, p.GridX, p.GridY)
+		hourly, err := s.getHourly(ctx, p)
 		var apiErr *app.NWSAPIStatusCodeError
 		switch {
 		case err == nil:
 			return hourly, nil
+		case errors.Is(err, app.ErrNWSNotFound):
+			// A valid gridpoint can result in a 404 status code. The
+			// 12-hour forecast may still be available.
+			return HourlyAPIResource{}, fmt.Errorf("%w: %v", errNoHourly, err)
 		case errors.As(err, &apiErr):
-			// If a valid gridpoint results in a 404 status code it is due to the
-			// gridpoint being located in the ocean. The NWS API does not yet
-			// support hourly forecasts for oceanic points.
-			if apiErr.StatusCode == 404 {
-				return HourlyAPIResource{}, app.NewServerResponseError(
-					fmt.Errorf("not supported by api: %w", apiErr),
-					"Oceanic points are not yet supported",
-					http.StatusBadRequest)
-			}
-
 			// Set rErr incase this is the last attempt.
 			if apiErr.StatusCode == 500 {
 				rErr = app.NewServerResponseError(
-					fmt.Errorf("not supported by api: %w", apiErr),
-					"Not a supported area",
-					http.StatusBadRequest)
+					fmt.Errorf("hourly forecast unavailable: %w", apiErr),
+					"Forecast is temporarily unavailable",
+					http.StatusBadGateway)
 
 				attempts++
+				if attempts < 2 && !s.allowRetry() {
+					return HourlyAPIResource{}, rErr
+				}
 			} else {
 				return HourlyAPIResource{}, fmt.Errorf("unexpected status code: %w", apiErr)
 			}
@@ -250,3 +884,83 @@ func (s *Service) hourly(p hourlyParams) (HourlyAPIResource, error) {
 
 	return HourlyAPIResource{}, rErr
 }
+
+// getHourly calls the GetHourlyForecast method of ForecastAPI, or the
+// GetHourlyForecastIfModified method if p has IfModifiedSince set and
+// the API implements ConditionalForecastAPI.
+func (s *Service) getHourly(ctx context.Context, p hourlyParams) (HourlyAPIResource, error) {
+	if api, ok := s.API.(ConditionalForecastAPI); ok && p.IfModifiedSince != nil {
+		return api.GetHourlyForecastIfModified(ctx, p.GridID, p.GridX, p.GridY, *p.IfModifiedSince)
+	}
+
+	return s.API.GetHourlyForecast(ctx, p.GridID, p.GridX, p.GridY)
+}
+
+// extend extends the expiration of a gridpoint whose forecast has not
+// been modified and returns its stored forecast. The forecast expires
+// at expires, or an hour from now if expires is not in the future.
+// The time it was generated at is kept.
+func (s *Service) extend(ctx context.Context, gridpoint GridpointEntity, expires time.Time) (Forecast, error) {
+	now := s.now().UTC()
+	gridpoint.Timeline.ExpiresAt = now.Add(time.Hour)
+	if expires.After(now) {
+		gridpoint.Timeline.ExpiresAt = expires.UTC()
+	}
+
+	if err := s.Store.UpdateGridpoint(ctx, &gridpoint); err != nil {
+		return Forecast{}, fmt.Errorf("extend: updating gridpoint (gridpoint.ID=%d): %w", gridpoint.ID, err)
+	}
+
+	return s.stored(ctx, gridpoint)
+}
+
+// allowRetry reports if a failed request to the API may be retried.
+// If the API does not implement RetryBudgeter retries are allowed.
+func (s *Service) allowRetry() bool {
+	if b, ok := s.API.(RetryBudgeter); ok {
+		return b.AllowRetry()
+	}
+
+	return true
+}
+
+// twelveHour calls the GetForecast method of ForecastAPI for a gridpoint
+// that has no hourly forecast. The 12-hour periods are returned with
+// StartTime and EndTime in the timezone tz. They are not written to the
+// database, since the database only holds hourly periods.
+//
+// If a 404 status code is returned the gridpoint is located in the ocean
+// and it will return an Error with a safe message.
+func (s *Service) twelveHour(ctx context.Context, p hourlyParams, tz string) (Forecast, error) {
+	resource, err := s.API.GetForecast(ctx, p.GridID, p.GridX, p.GridY)
+	if err != nil {
+		// The NWS API does not yet support forecasts for oceanic
+		// points.
+		if errors.Is(err, app.ErrNWSNotFound) {
+			return Forecast{}, app.NewServerResponseError(
+				fmt.Errorf("not supported by api: %w", err),
+				"Oceanic points are not yet supported",
+				http.StatusBadRequest)
+		}
+
+		return Forecast{}, fmt.Errorf("fetching 12-hour forecast (GridID=%s, GridX=%d, GridY=%d): %w",
+			p.GridID,
+			p.GridX,
+			p.GridY,
+			err)
+	}
+
+	location, err := loadLocation(tz)
+	if err != nil {
+		return Forecast{}, fmt.Errorf("loading location (name=%s): %w", tz, err)
+	}
+
+	periods := resource.ToPeriodEntityCollection()
+
+	return Forecast{
+		Periods:    periods.ToPeriods(location),
+		ValidUntil: resource.Timeline().ExpiresAt,
+		Elevation:  resource.Elevation.Meters(),
+		TwelveHour: true,
+	}, nil
+}
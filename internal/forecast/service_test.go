@@ -0,0 +1,231 @@
+package forecast
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/cicconee/weather-app/internal/app"
+	"github.com/cicconee/weather-app/internal/geometry"
+)
+
+// fakeForecastAPI is a ForecastAPI that returns canned responses and
+// counts the calls made to it.
+type fakeForecastAPI struct {
+	gridpoint    GridpointAPIResource
+	gridpointErr error
+
+	// hourlyErrs are returned by each call to GetHourlyForecast in
+	// order. Once they are used up, hourly is returned.
+	hourlyErrs  []error
+	hourly      HourlyAPIResource
+	hourlyCalls int
+
+	forecast      HourlyAPIResource
+	forecastErr   error
+	forecastCalls int
+
+	// retries is the number of retries AllowRetry allows. A negative
+	// value allows every retry.
+	retries int
+}
+
+func (f *fakeForecastAPI) GetGridpoint(ctx context.Context, lon, lat float64) (GridpointAPIResource, error) {
+	return f.gridpoint, f.gridpointErr
+}
+
+func (f *fakeForecastAPI) GetHourlyForecast(ctx context.Context, id string, x, y int) (HourlyAPIResource, error) {
+	f.hourlyCalls++
+	if f.hourlyCalls <= len(f.hourlyErrs) {
+		return HourlyAPIResource{}, f.hourlyErrs[f.hourlyCalls-1]
+	}
+
+	return f.hourly, nil
+}
+
+func (f *fakeForecastAPI) GetForecast(ctx context.Context, id string, x, y int) (HourlyAPIResource, error) {
+	f.forecastCalls++
+	return f.forecast, f.forecastErr
+}
+
+func (f *fakeForecastAPI) AllowRetry() bool {
+	if f.retries < 0 {
+		return true
+	}
+
+	if f.retries == 0 {
+		return false
+	}
+
+	f.retries--
+	return true
+}
+
+func statusErr(code int) error {
+	return &app.NWSAPIStatusCodeError{StatusCode: code, Detail: http.StatusText(code)}
+}
+
+// assertResponseError fails t unless err is a *app.ServerResponseError with
+// the status code and a message containing msg.
+func assertResponseError(t *testing.T, err error, code int, msg string) {
+	t.Helper()
+
+	var resErr *app.ServerResponseError
+	if !errors.As(err, &resErr) {
+		t.Fatalf("got error %v, want a *app.ServerResponseError", err)
+	}
+
+	if resErr.StatusCode != code {
+		t.Errorf("got status code %d, want %d", resErr.StatusCode, code)
+	}
+
+	if !strings.Contains(resErr.Msg, msg) {
+		t.Errorf("got message %q, want it to contain %q", resErr.Msg, msg)
+	}
+}
+
+func TestWriteErrors(t *testing.T) {
+	grid := GridpointAPIResource{GridID: "BOU", GridX: 62, GridY: 60, TimeZone: "UTC"}
+
+	tests := []struct {
+		name string
+		api  *fakeForecastAPI
+		code int
+		msg  string
+	}{
+		{
+			name: "gridpoint 404",
+			api:  &fakeForecastAPI{gridpointErr: statusErr(http.StatusNotFound)},
+			code: http.StatusBadRequest,
+			msg:  "is not a supported area",
+		},
+		{
+			name: "empty grid id",
+			api:  &fakeForecastAPI{},
+			code: http.StatusBadRequest,
+			msg:  "is not a supported area",
+		},
+		{
+			name: "hourly and 12-hour 404",
+			api: &fakeForecastAPI{
+				gridpoint:   grid,
+				hourlyErrs:  []error{statusErr(http.StatusNotFound)},
+				forecastErr: statusErr(http.StatusNotFound),
+			},
+			code: http.StatusBadRequest,
+			msg:  "Oceanic points are not yet supported",
+		},
+		{
+			name: "hourly 500 twice",
+			api: &fakeForecastAPI{
+				gridpoint:  grid,
+				hourlyErrs: []error{statusErr(http.StatusInternalServerError), statusErr(http.StatusInternalServerError)},
+				retries:    -1,
+			},
+			code: http.StatusBadGateway,
+			msg:  "Forecast is temporarily unavailable",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &Service{API: tt.api}
+
+			_, err := s.write(context.Background(), geometry.NewPoint(-105.0, 39.7))
+			assertResponseError(t, err, tt.code, tt.msg)
+		})
+	}
+}
+
+func TestWriteHourly404FallsBackToTwelveHour(t *testing.T) {
+	api := &fakeForecastAPI{
+		gridpoint:  GridpointAPIResource{GridID: "BOU", GridX: 62, GridY: 60, TimeZone: "UTC"},
+		hourlyErrs: []error{statusErr(http.StatusNotFound)},
+	}
+	s := &Service{API: api}
+
+	fc, err := s.write(context.Background(), geometry.NewPoint(-105.0, 39.7))
+	if err != nil {
+		t.Fatalf("got error %v, want nil", err)
+	}
+
+	if !fc.TwelveHour {
+		t.Error("got TwelveHour false, want true")
+	}
+
+	if api.forecastCalls != 1 {
+		t.Errorf("got %d 12-hour forecast calls, want 1", api.forecastCalls)
+	}
+}
+
+func TestHourlyRetries(t *testing.T) {
+	serverErr := statusErr(http.StatusInternalServerError)
+
+	tests := []struct {
+		name      string
+		errs      []error
+		retries   int
+		wantCalls int
+		wantErr   bool
+	}{
+		{
+			name:      "500 then success",
+			errs:      []error{serverErr},
+			retries:   -1,
+			wantCalls: 2,
+		},
+		{
+			name:      "500 twice stops after two attempts",
+			errs:      []error{serverErr, serverErr, serverErr},
+			retries:   -1,
+			wantCalls: 2,
+			wantErr:   true,
+		},
+		{
+			name:      "500 with the retry budget spent",
+			errs:      []error{serverErr},
+			retries:   0,
+			wantCalls: 1,
+			wantErr:   true,
+		},
+		{
+			name:      "503 is not retried",
+			errs:      []error{statusErr(http.StatusServiceUnavailable)},
+			retries:   -1,
+			wantCalls: 1,
+			wantErr:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			api := &fakeForecastAPI{hourlyErrs: tt.errs, retries: tt.retries}
+			s := &Service{API: api}
+
+			_, err := s.hourly(context.Background(), hourlyParams{GridID: "BOU", GridX: 62, GridY: 60})
+			if (err != nil) != tt.wantErr {
+				t.Errorf("got error %v, want error %t", err, tt.wantErr)
+			}
+
+			if api.hourlyCalls != tt.wantCalls {
+				t.Errorf("got %d hourly calls, want %d", api.hourlyCalls, tt.wantCalls)
+			}
+		})
+	}
+}
+
+func TestHourly404(t *testing.T) {
+	api := &fakeForecastAPI{hourlyErrs: []error{statusErr(http.StatusNotFound)}, retries: -1}
+	s := &Service{API: api}
+
+	_, err := s.hourly(context.Background(), hourlyParams{GridID: "BOU", GridX: 62, GridY: 60})
+	if !errors.Is(err, errNoHourly) {
+		t.Errorf("got error %v, want errNoHourly", err)
+	}
+
+	if api.hourlyCalls != 1 {
+		t.Errorf("got %d hourly calls, want 1", api.hourlyCalls)
+	}
+}
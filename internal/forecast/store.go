@@ -4,6 +4,7 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"time"
 
 	"github.com/cicconee/weather-app/internal/geometry"
 )
@@ -45,6 +46,114 @@ func (s *Store) SelectGridpoint(ctx context.Context, point geometry.Point) (Grid
 	return gridpoint, gridpoint.Select(ctx, s.DB, point)
 }
 
+// SelectGridpointByGrid reads the GridpointEntity with the grid identifier
+// gridID and grid coordinates gridX and gridY from the database. If no rows
+// are found a sql.ErrNoRows error is returned.
+func (s *Store) SelectGridpointByGrid(ctx context.Context, gridID string, gridX int, gridY int) (GridpointEntity, error) {
+	gridpoint := GridpointEntity{}
+	if err := gridpoint.SelectByGrid(ctx, s.DB, gridID, gridX, gridY); err != nil {
+		return GridpointEntity{}, err
+	}
+
+	return gridpoint, nil
+}
+
+// SelectGridpointWithGeometry reads a GridpointEntity like SelectGridpoint
+// with the Geometry field set.
+func (s *Store) SelectGridpointWithGeometry(ctx context.Context, point geometry.Point) (GridpointEntity, error) {
+	gridpoint := GridpointEntity{}
+	return gridpoint, gridpoint.SelectWithGeometry(ctx, s.DB, point)
+}
+
+// RefreshBatchSize is the most gridpoints returned by a single call to
+// SelectExpiringGridpoints.
+const RefreshBatchSize = 100
+
+// SelectExpiringGridpoints reads the gridpoints whose forecast expires before
+// the time before, oldest first. At most RefreshBatchSize gridpoints are
+// returned.
+func (s *Store) SelectExpiringGridpoints(ctx context.Context, before time.Time) (GridpointEntityCollection, error) {
+	gridpoints := GridpointEntityCollection{}
+	if err := gridpoints.SelectExpiring(ctx, s.DB, before, RefreshBatchSize); err != nil {
+		return nil, err
+	}
+
+	return gridpoints, nil
+}
+
+// SelectGridpointSample reads at most limit randomly chosen gridpoints,
+// each with the center of its geometric bounds.
+func (s *Store) SelectGridpointSample(ctx context.Context, limit int) (GridpointSampleCollection, error) {
+	samples := GridpointSampleCollection{}
+	if err := samples.Select(ctx, s.DB, limit); err != nil {
+		return nil, err
+	}
+
+	return samples, nil
+}
+
+// SelectOverlappingGridpoints reads the pairs of stored gridpoints whose
+// geometric bounds overlap.
+func (s *Store) SelectOverlappingGridpoints(ctx context.Context) (GridpointOverlapCollection, error) {
+	overlaps := GridpointOverlapCollection{}
+	if err := overlaps.Select(ctx, s.DB); err != nil {
+		return nil, err
+	}
+
+	return overlaps, nil
+}
+
+// SelectGridpointsOverlaps reads a page of the gridpoints that overlap
+// bbox, with their geometry. The total number of gridpoints that overlap
+// bbox is also returned.
+func (s *Store) SelectGridpointsOverlaps(ctx context.Context, bbox geometry.BBox, limit int, offset int) (GridpointEntityCollection, int, error) {
+	gridpoints := GridpointEntityCollection{}
+	total, err := gridpoints.CountOverlaps(ctx, s.DB, bbox)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	if err := gridpoints.SelectOverlaps(ctx, s.DB, bbox, limit, offset); err != nil {
+		return nil, 0, err
+	}
+
+	return gridpoints, total, nil
+}
+
+// UpdateGridpoint writes the Timeline, Elevation and LastModified of
+// gridpoint to the database. The periods of the gridpoint are not
+// changed.
+func (s *Store) UpdateGridpoint(ctx context.Context, gridpoint *GridpointEntity) error {
+	return gridpoint.Update(ctx, s.DB)
+}
+
+// AddGridpointFetches adds count to the fetch count of the gridpoint with
+// the id gridpointID and sets the time it was last fetched to t, unless it
+// was already fetched later.
+func (s *Store) AddGridpointFetches(ctx context.Context, gridpointID int, count int64, t time.Time) error {
+	gridpoint := GridpointEntity{ID: gridpointID}
+	return gridpoint.AddFetches(ctx, s.DB, count, t)
+}
+
+// SelectFreshness reads the freshness of the stored gridpoints at now.
+func (s *Store) SelectFreshness(ctx context.Context, now time.Time) (Freshness, error) {
+	freshness := Freshness{}
+	return freshness, freshness.Select(ctx, s.DB, now)
+}
+
+// DeleteStaleGridpoints deletes all gridpoints that were generated and last
+// fetched before t, along with their periods. It returns the number of
+// gridpoints deleted.
+func (s *Store) DeleteStaleGridpoints(ctx context.Context, t time.Time) (int64, error) {
+	gridpoints := GridpointEntityCollection{}
+	result, err := gridpoints.DeleteStale(ctx, s.DB, t)
+	if err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected()
+}
+
 // SelectPeriodCollection reads the PeriodEntity that belong to a gridpoint
 // from the database and returns them in a PeriodEntityCollection.
 func (s *Store) SelectPeriodCollection(ctx context.Context, gridpointID int) (PeriodEntityCollection, error) {
@@ -62,14 +171,23 @@ type GridpointPeriodsTxParams struct {
 // to the database. The GridpointEntity ID field will be set and all PeriodEntity in
 // the PeriodEntityCollection will have the GridpointID field set.
 //
+// If the gridpoint is already stored, the GridpointEntity ID field is set to the
+// stored gridpoint and the periods are not written. The periods were written with
+// the stored gridpoint.
+//
 // InsertGridpointPeriodsTx is wrapped in a database transaction. If any database
 // operations fail, the database will rollback.
 func (s *Store) InsertGridpointPeriodsTx(ctx context.Context, p GridpointPeriodsTxParams) error {
 	return s.tx(ctx, func(tx *sql.Tx) error {
-		if err := p.Gridpoint.Insert(ctx, tx); err != nil {
+		inserted, err := p.Gridpoint.Insert(ctx, tx)
+		if err != nil {
 			return err
 		}
 
+		if !inserted {
+			return nil
+		}
+
 		if err := p.Periods.Insert(ctx, tx, p.Gridpoint.ID); err != nil {
 			return err
 		}
@@ -0,0 +1,137 @@
+package forecast
+
+import (
+	"encoding/json"
+	"math"
+	"time"
+
+	"github.com/cicconee/weather-app/internal/app"
+)
+
+// SunDay is the sunrise and sunset of a single day at a point. During a
+// polar day the sun never sets and during a polar night it never rises,
+// so Sunrise and Sunset are nil and PolarDay or PolarNight is true.
+type SunDay struct {
+	// Date is the day in the time zone of the point.
+	Date time.Time
+
+	Sunrise *time.Time
+	Sunset  *time.Time
+
+	PolarDay   bool
+	PolarNight bool
+}
+
+// MarshalJSON formats the Date as "2006-01-02" and the Sunrise and
+// Sunset as app.Time. A nil time is omitted.
+func (s SunDay) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Date       string    `json:"date"`
+		Sunrise    *app.Time `json:"sunrise,omitempty"`
+		Sunset     *app.Time `json:"sunset,omitempty"`
+		PolarDay   bool      `json:"polar_day,omitempty"`
+		PolarNight bool      `json:"polar_night,omitempty"`
+	}{
+		Date:       s.Date.Format("2006-01-02"),
+		Sunrise:    app.TimePtr(s.Sunrise),
+		Sunset:     app.TimePtr(s.Sunset),
+		PolarDay:   s.PolarDay,
+		PolarNight: s.PolarNight,
+	})
+}
+
+// sunriseZenith is the zenith of the sun at sunrise and sunset in
+// degrees. It accounts for atmospheric refraction and the radius of
+// the sun.
+const sunriseZenith = 90.833
+
+// Sun returns the sunrise and sunset at lat and lon on the day of date,
+// in the location of date. It uses the NOAA solar calculations, which
+// are accurate to about a minute away from the poles.
+func Sun(lat float64, lon float64, date time.Time) SunDay {
+	loc := date.Location()
+	y, m, d := date.Date()
+	day := SunDay{Date: time.Date(y, m, d, 0, 0, 0, 0, loc)}
+
+	// The calculations are made at the solar noon of the day, which
+	// is about lon/360 days from noon UTC.
+	midnight := time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+	jd := float64(midnight.Unix())/86400 + 2440587.5 + 0.5 - lon/360
+	jc := (jd - 2451545) / 36525
+
+	meanLong := math.Mod(280.46646+jc*(36000.76983+jc*0.0003032), 360)
+	meanAnom := 357.52911 + jc*(35999.05029-0.0001537*jc)
+	eccent := 0.016708634 - jc*(0.000042037+0.0000001267*jc)
+	eqCenter := sin(meanAnom)*(1.914602-jc*(0.004817+0.000014*jc)) +
+		sin(2*meanAnom)*(0.019993-0.000101*jc) +
+		sin(3*meanAnom)*0.000289
+	omega := 125.04 - 1934.136*jc
+	appLong := meanLong + eqCenter - 0.00569 - 0.00478*sin(omega)
+	meanObliq := 23 + (26+(21.448-jc*(46.815+jc*(0.00059-jc*0.001813)))/60)/60
+	obliq := meanObliq + 0.00256*cos(omega)
+	decl := degrees(math.Asin(sin(obliq) * sin(appLong)))
+
+	v := math.Pow(math.Tan(radians(obliq/2)), 2)
+	eqTime := 4 * degrees(v*sin(2*meanLong)-
+		2*eccent*sin(meanAnom)+
+		4*eccent*v*sin(meanAnom)*cos(2*meanLong)-
+		0.5*v*v*sin(4*meanLong)-
+		1.25*eccent*eccent*sin(2*meanAnom))
+
+	cosHourAngle := cos(sunriseZenith)/(cos(lat)*cos(decl)) - math.Tan(radians(lat))*math.Tan(radians(decl))
+	switch {
+	case cosHourAngle > 1:
+		day.PolarNight = true
+		return day
+	case cosHourAngle < -1:
+		day.PolarDay = true
+		return day
+	}
+
+	hourAngle := degrees(math.Acos(cosHourAngle))
+	noon := 720 - 4*lon - eqTime
+	sunrise := midnight.Add(minutes(noon - 4*hourAngle)).In(loc)
+	sunset := midnight.Add(minutes(noon + 4*hourAngle)).In(loc)
+	day.Sunrise = &sunrise
+	day.Sunset = &sunset
+
+	return day
+}
+
+// SunDays returns the sunrise and sunset at point for each day the
+// periods cover, in order. The days are in the time zone of the periods.
+func SunDays(lat float64, lon float64, periods PeriodCollection) []SunDay {
+	days := []SunDay{}
+	seen := map[string]bool{}
+	for _, period := range periods {
+		date := period.StartTime.Format("2006-01-02")
+		if seen[date] {
+			continue
+		}
+		seen[date] = true
+
+		days = append(days, Sun(lat, lon, period.StartTime))
+	}
+
+	return days
+}
+
+func radians(deg float64) float64 {
+	return deg * math.Pi / 180
+}
+
+func degrees(rad float64) float64 {
+	return rad * 180 / math.Pi
+}
+
+func sin(deg float64) float64 {
+	return math.Sin(radians(deg))
+}
+
+func cos(deg float64) float64 {
+	return math.Cos(radians(deg))
+}
+
+func minutes(m float64) time.Duration {
+	return time.Duration(m * float64(time.Minute))
+}
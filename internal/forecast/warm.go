@@ -0,0 +1,72 @@
+package forecast
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/cicconee/weather-app/internal/app"
+	"github.com/cicconee/weather-app/internal/geometry"
+	"golang.org/x/sync/errgroup"
+)
+
+// MaxWarmPoints is the most points that can be warmed by a single
+// call to Warm.
+const MaxWarmPoints = 50
+
+// warmConcurrency is the most points a single call to Warm fetches at
+// once.
+const warmConcurrency = 5
+
+// WarmResult is the result of warming a single point. Err is nil if
+// the forecast of Point is stored.
+type WarmResult struct {
+	Point geometry.Point
+	Err   error
+}
+
+// Warm gets the forecast of each point the same as Get, so points
+// without a stored or current forecast are fetched from the NWS API
+// and stored before they are first requested. Up to warmConcurrency
+// points are fetched at once. Pool is not used, so warming never waits
+// on or holds up the background refreshes.
+//
+// The points are rounded the same as in Get, and points that round to
+// the same Key are only fetched once. A result is returned for each
+// distinct point, in the order they are first listed in points. A point
+// failing does not stop the others. Between 1 and MaxWarmPoints points
+// are required.
+func (s *Service) Warm(ctx context.Context, points []geometry.Point) ([]WarmResult, error) {
+	if len(points) == 0 || len(points) > MaxWarmPoints {
+		return nil, app.NewServerResponseError(
+			fmt.Errorf("warming %d points, must be between 1 and %d", len(points), MaxWarmPoints),
+			fmt.Sprintf("Between 1 and %d points are required", MaxWarmPoints),
+			http.StatusBadRequest)
+	}
+
+	distinct := []geometry.Point{}
+	seen := map[string]bool{}
+	for _, point := range points {
+		point = point.Round(s.precision())
+		if seen[point.Key()] {
+			continue
+		}
+		seen[point.Key()] = true
+		distinct = append(distinct, point)
+	}
+
+	results := make([]WarmResult, len(distinct))
+	var g errgroup.Group
+	g.SetLimit(warmConcurrency)
+	for i, point := range distinct {
+		i, point := i, point
+		g.Go(func() error {
+			_, err := s.get(ctx, point, false)
+			results[i] = WarmResult{Point: point, Err: err}
+			return nil
+		})
+	}
+	g.Wait()
+
+	return results, nil
+}
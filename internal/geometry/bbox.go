@@ -0,0 +1,49 @@
+package geometry
+
+import "math"
+
+// BBox is a bounding box of longitudes and latitudes. Boxes that cross
+// the antimeridian are not supported, so MinLon is always west of
+// MaxLon.
+type BBox struct {
+	MinLon float64
+	MinLat float64
+	MaxLon float64
+	MaxLat float64
+}
+
+// IsValid reports if this box has finite coordinates in range, and its
+// minimums are less than its maximums.
+func (b BBox) IsValid() bool {
+	for _, v := range []float64{b.MinLon, b.MinLat, b.MaxLon, b.MaxLat} {
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			return false
+		}
+	}
+
+	return b.MinLon >= -180 && b.MaxLon <= 180 &&
+		b.MinLat >= -90 && b.MaxLat <= 90 &&
+		b.MinLon < b.MaxLon && b.MinLat < b.MaxLat
+}
+
+// Width returns the degrees of longitude this box spans.
+func (b BBox) Width() float64 {
+	return b.MaxLon - b.MinLon
+}
+
+// Height returns the degrees of latitude this box spans.
+func (b BBox) Height() float64 {
+	return b.MaxLat - b.MinLat
+}
+
+// Polygon returns this box as a closed polygon, starting at the south
+// west corner and going counterclockwise.
+func (b BBox) Polygon() Polygon {
+	return Polygon{PointCollection{
+		NewPoint(b.MinLon, b.MinLat),
+		NewPoint(b.MaxLon, b.MinLat),
+		NewPoint(b.MaxLon, b.MaxLat),
+		NewPoint(b.MinLon, b.MaxLat),
+		NewPoint(b.MinLon, b.MinLat),
+	}}
+}
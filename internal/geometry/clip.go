@@ -0,0 +1,129 @@
+package geometry
+
+import "math"
+
+// Clip returns the part of this polygon inside bbox using the
+// Sutherland-Hodgman algorithm. Each ring is clipped separately and a
+// hole outside bbox is dropped. If the perimeter is outside bbox, or
+// only touches it, nil is returned.
+//
+// A concave polygon that leaves and reenters bbox is returned as a
+// single polygon whose parts are joined by edges along the border of
+// bbox, rather than as several polygons.
+func (p Polygon) Clip(bbox BBox) Polygon {
+	perimeter := p.Permiter().clip(bbox)
+	if perimeter == nil {
+		return nil
+	}
+
+	clipped := Polygon{perimeter}
+	for _, hole := range p.Holes() {
+		if h := hole.clip(bbox); h != nil {
+			clipped = append(clipped, h)
+		}
+	}
+
+	return clipped
+}
+
+// Clip returns each polygon of this multi polygon clipped to bbox,
+// the same as Polygon.Clip. Polygons outside bbox are dropped.
+func (m MultiPolygon) Clip(bbox BBox) MultiPolygon {
+	clipped := MultiPolygon{}
+	for _, p := range m {
+		if c := p.Clip(bbox); c != nil {
+			clipped = append(clipped, c)
+		}
+	}
+
+	return clipped
+}
+
+// clipEdge is a side of a bounding box. inside reports if a point is
+// on the inner side of the edge and intersect returns where the segment
+// a->b crosses it.
+type clipEdge struct {
+	inside    func(pt Point) bool
+	intersect func(a, b Point) Point
+}
+
+// clip returns this ring clipped to bbox as a closed ring. It returns
+// nil if less than a ring with an area is left.
+func (p PointCollection) clip(bbox BBox) PointCollection {
+	edges := []clipEdge{
+		{
+			inside:    func(pt Point) bool { return pt.Lon() >= bbox.MinLon },
+			intersect: func(a, b Point) Point { return atLon(a, b, bbox.MinLon) },
+		},
+		{
+			inside:    func(pt Point) bool { return pt.Lon() <= bbox.MaxLon },
+			intersect: func(a, b Point) Point { return atLon(a, b, bbox.MaxLon) },
+		},
+		{
+			inside:    func(pt Point) bool { return pt.Lat() >= bbox.MinLat },
+			intersect: func(a, b Point) Point { return atLat(a, b, bbox.MinLat) },
+		},
+		{
+			inside:    func(pt Point) bool { return pt.Lat() <= bbox.MaxLat },
+			intersect: func(a, b Point) Point { return atLat(a, b, bbox.MaxLat) },
+		},
+	}
+
+	ring := p.open()
+	for _, edge := range edges {
+		if len(ring) == 0 {
+			break
+		}
+
+		clipped := PointCollection{}
+		for i, cur := range ring {
+			prev := ring[(i+len(ring)-1)%len(ring)]
+			switch {
+			case edge.inside(cur):
+				if !edge.inside(prev) {
+					clipped = clipped.appendDistinct(edge.intersect(prev, cur))
+				}
+				clipped = clipped.appendDistinct(cur)
+			case edge.inside(prev):
+				clipped = clipped.appendDistinct(edge.intersect(prev, cur))
+			}
+		}
+
+		// An intersection on a corner of bbox can repeat the first
+		// point at the end.
+		if len(clipped) > 1 && clipped[0].Equal(clipped[len(clipped)-1]) {
+			clipped = clipped[:len(clipped)-1]
+		}
+		ring = clipped
+	}
+
+	if len(ring) < 3 || math.Abs(ring.signedArea()) < Epsilon {
+		return nil
+	}
+
+	return ring.close()
+}
+
+// appendDistinct appends pt to this ring unless it is equal to the
+// last point.
+func (p PointCollection) appendDistinct(pt Point) PointCollection {
+	if len(p) > 0 && p[len(p)-1].Equal(pt) {
+		return p
+	}
+
+	return append(p, pt)
+}
+
+// atLon returns the point at the longitude lon on the segment a->b.
+// The segment must cross lon.
+func atLon(a, b Point, lon float64) Point {
+	t := (lon - a.Lon()) / (b.Lon() - a.Lon())
+	return NewPoint(lon, a.Lat()+t*(b.Lat()-a.Lat()))
+}
+
+// atLat returns the point at the latitude lat on the segment a->b.
+// The segment must cross lat.
+func atLat(a, b Point, lat float64) Point {
+	t := (lat - a.Lat()) / (b.Lat() - a.Lat())
+	return NewPoint(a.Lon()+t*(b.Lon()-a.Lon()), lat)
+}
@@ -0,0 +1,49 @@
+package geometry
+
+import "math"
+
+// Densify returns a copy of this Polygon where no segment of any
+// ring is longer than maxSegmentLength. Long segments are split
+// into equal parts by inserting intermediate points along them,
+// so the shape of the polygon is unchanged. Rings that are closed
+// remain closed.
+//
+// The length is measured in the units of the coordinates (degrees
+// for NWS geometry). If maxSegmentLength is not positive a copy of
+// this Polygon is returned unchanged.
+func (p Polygon) Densify(maxSegmentLength float64) Polygon {
+	densified := Polygon{}
+	for _, ring := range p {
+		densified = append(densified, ring.densify(maxSegmentLength))
+	}
+	return densified
+}
+
+func (p PointCollection) densify(maxSegmentLength float64) PointCollection {
+	if len(p) == 0 {
+		return PointCollection{}
+	}
+
+	if maxSegmentLength <= 0 {
+		ring := make(PointCollection, len(p))
+		copy(ring, p)
+		return ring
+	}
+
+	ring := PointCollection{p[0]}
+	for i := 1; i < len(p); i++ {
+		from, to := p[i-1], p[i]
+		length := math.Hypot(to.X()-from.X(), to.Y()-from.Y())
+		parts := int(math.Ceil(length / maxSegmentLength))
+
+		for j := 1; j < parts; j++ {
+			t := float64(j) / float64(parts)
+			ring = append(ring, NewPoint(
+				from.X()+t*(to.X()-from.X()),
+				from.Y()+t*(to.Y()-from.Y())))
+		}
+		ring = append(ring, to)
+	}
+
+	return ring
+}
@@ -0,0 +1,28 @@
+package geometry
+
+// GeoJSON is a GeoJSON geometry object.
+type GeoJSON struct {
+	Type        string `json:"type"`
+	Coordinates any    `json:"coordinates"`
+}
+
+// GeoJSON returns this polygon as a GeoJSON Polygon. Polygons
+// read from the NWS API, or parsed from the database, hold their
+// points in GeoJSON order, so the rings are used as is.
+func (p Polygon) GeoJSON() GeoJSON {
+	if p == nil {
+		p = Polygon{}
+	}
+
+	return GeoJSON{Type: "Polygon", Coordinates: p}
+}
+
+// GeoJSON returns this multi polygon as a GeoJSON MultiPolygon. The
+// polygons are used as is, the same as Polygon.GeoJSON.
+func (m MultiPolygon) GeoJSON() GeoJSON {
+	if m == nil {
+		m = MultiPolygon{}
+	}
+
+	return GeoJSON{Type: "MultiPolygon", Coordinates: m}
+}
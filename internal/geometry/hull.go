@@ -0,0 +1,76 @@
+package geometry
+
+import "sort"
+
+// ConvexHull returns the smallest convex polygon containing every
+// point in this collection, computed with Andrew's monotone chain
+// algorithm. The hull is a closed ring wound counter clockwise.
+// Points that lie on an edge of the hull are not included.
+//
+// If the collection has fewer than three distinct points, or all
+// the points are collinear, there is no hull and a empty Polygon
+// is returned.
+func (p PointCollection) ConvexHull() Polygon {
+	points := make(PointCollection, 0, len(p))
+	for _, pt := range p {
+		if len(pt) >= 2 {
+			points = append(points, pt)
+		}
+	}
+
+	sort.Slice(points, func(i, j int) bool {
+		if points[i].X() != points[j].X() {
+			return points[i].X() < points[j].X()
+		}
+		return points[i].Y() < points[j].Y()
+	})
+
+	// Remove duplicate points, they are adjacent after sorting.
+	unique := PointCollection{}
+	for i, pt := range points {
+		if i > 0 && pt.Equal(points[i-1]) {
+			continue
+		}
+		unique = append(unique, pt)
+	}
+
+	if len(unique) < 3 {
+		return Polygon{}
+	}
+
+	// Build the lower and upper hulls. A cross product that is not
+	// positive is a clockwise or collinear turn, so the middle point
+	// is removed.
+	hull := PointCollection{}
+	for _, pt := range unique {
+		for len(hull) >= 2 && cross(hull[len(hull)-2], hull[len(hull)-1], pt) <= 0 {
+			hull = hull[:len(hull)-1]
+		}
+		hull = append(hull, pt)
+	}
+
+	lower := len(hull) + 1
+	for i := len(unique) - 2; i >= 0; i-- {
+		pt := unique[i]
+		for len(hull) >= lower && cross(hull[len(hull)-2], hull[len(hull)-1], pt) <= 0 {
+			hull = hull[:len(hull)-1]
+		}
+		hull = append(hull, pt)
+	}
+
+	// The last point is the first point, closing the ring. A hull
+	// of collinear points collapses to a single segment.
+	if len(hull) < 4 {
+		return Polygon{}
+	}
+
+	return Polygon{hull}
+}
+
+// cross returns the z component of the cross product of the
+// vectors o->a and o->b. It is positive if o, a, b make a
+// counter clockwise turn, negative if clockwise, and zero if
+// the points are collinear.
+func cross(o, a, b Point) float64 {
+	return (a.X()-o.X())*(b.Y()-o.Y()) - (a.Y()-o.Y())*(b.X()-o.X())
+}
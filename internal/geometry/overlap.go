@@ -0,0 +1,75 @@
+package geometry
+
+import "math"
+
+// Overlaps reports if the interiors of the perimeters of this polygon and
+// other overlap. Polygons that only share edges or vertices, such as
+// adjacent gridpoints, do not overlap. Holes are ignored.
+func (p Polygon) Overlaps(other Polygon) bool {
+	a := p.Permiter().open()
+	b := other.Permiter().open()
+	if len(a) < 3 || len(b) < 3 {
+		return false
+	}
+
+	// An edge of one perimeter crossing an edge of the other.
+	for i := range a {
+		a1, a2 := a[i], a[(i+1)%len(a)]
+		for j := range b {
+			b1, b2 := b[j], b[(j+1)%len(b)]
+			if properlyCross(a1, a2, b1, b2) {
+				return true
+			}
+		}
+	}
+
+	// A perimeter inside the other without any edges crossing. A
+	// perimeter sharing edges with the other can have every vertex on
+	// the other, so the midpoint of each edge and the mean of the
+	// vertices are checked as well.
+	return a.strictlyContainsAny(b) || b.strictlyContainsAny(a)
+}
+
+// strictlyContainsAny reports if a vertex of the ring other, the midpoint
+// of one of its edges, or the mean of its vertices, is inside this ring
+// and not on its boundary.
+func (p PointCollection) strictlyContainsAny(other PointCollection) bool {
+	var x, y float64
+	for i, pt := range other {
+		next := other[(i+1)%len(other)]
+		mid := NewPoint((pt.X()+next.X())/2, (pt.Y()+next.Y())/2)
+		if p.strictlyContains(pt) || p.strictlyContains(mid) {
+			return true
+		}
+		x += pt.X()
+		y += pt.Y()
+	}
+
+	n := float64(len(other))
+	return p.strictlyContains(NewPoint(x/n, y/n))
+}
+
+// strictlyContains reports if pt is inside this ring and not within
+// Epsilon of its boundary.
+func (p PointCollection) strictlyContains(pt Point) bool {
+	for i := range p {
+		a, b := p[i], p[(i+1)%len(p)]
+		if math.Abs(cross(a, b, pt)) < Epsilon && onSegment(a, b, pt) {
+			return false
+		}
+	}
+
+	return p.ringContains(pt)
+}
+
+// properlyCross reports if the segments a1->a2 and b1->b2 cross at a
+// single point that is not an endpoint of either segment.
+func properlyCross(a1, a2, b1, b2 Point) bool {
+	d1 := cross(b1, b2, a1)
+	d2 := cross(b1, b2, a2)
+	d3 := cross(a1, a2, b1)
+	d4 := cross(a1, a2, b2)
+
+	return ((d1 > Epsilon && d2 < -Epsilon) || (d1 < -Epsilon && d2 > Epsilon)) &&
+		((d3 > Epsilon && d4 < -Epsilon) || (d3 < -Epsilon && d4 > Epsilon))
+}
@@ -3,21 +3,33 @@ package geometry
 import (
 	"fmt"
 	"math"
+	"strconv"
 	"strings"
 )
 
+// Point is a longitude and latitude in GeoJSON order,
+// [longitude, latitude], so the points of a GeoJSON
+// geometry decode as is. X is the longitude and Y is
+// the latitude, and points are written to the database
+// as (x,y).
+//
+// The NWS API orders points latitude first (e.g.
+// /points/{latitude},{longitude}), so a point must be
+// passed to it by Lat and Lon, not by index.
 type Point []float64
 
+// NewPoint returns the point at the longitude x and the
+// latitude y.
 func NewPoint(x, y float64) Point {
-	return Point{y, x}
+	return Point{x, y}
 }
 
 func (p Point) X() float64 {
-	return p[1]
+	return p[0]
 }
 
 func (p Point) Y() float64 {
-	return p[0]
+	return p[1]
 }
 
 func (p Point) Lon() float64 {
@@ -28,16 +40,42 @@ func (p Point) Lat() float64 {
 	return p.Y()
 }
 
+// IsValid returns true if this point has two
+// coordinates and neither is NaN or infinite.
+func (p Point) IsValid() bool {
+	if len(p) != 2 {
+		return false
+	}
+
+	for _, c := range p {
+		if math.IsNaN(c) || math.IsInf(c, 0) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// DefaultPrecision is the number of decimal places points are
+// rounded to by RoundedLon, RoundedLat and RoundedString.
+const DefaultPrecision = 4
+
 // RoundedLon returns the longitude rounded to the 4th
 // decimal place.
 func (p Point) RoundedLon() float64 {
-	return round(p.Lon(), 4)
+	return round(p.Lon(), DefaultPrecision)
 }
 
 // RoundedLat returns the latitude rounded to the 4th
 // decimal place.
 func (p Point) RoundedLat() float64 {
-	return round(p.Lat(), 4)
+	return round(p.Lat(), DefaultPrecision)
+}
+
+// Round returns this point with the longitude and latitude
+// rounded to precision decimal places.
+func (p Point) Round(precision uint) Point {
+	return NewPoint(round(p.Lon(), precision), round(p.Lat(), precision))
 }
 
 func round(val float64, precision uint) float64 {
@@ -63,8 +101,71 @@ func (p Point) RoundedString() string {
 	return fmt.Sprintf("(%f, %f)", p.RoundedLon(), p.RoundedLat())
 }
 
+// Epsilon is the tolerance used when comparing coordinates.
+// Coordinates that differ by less than Epsilon are equal.
+const Epsilon = 1e-9
+
+// Equal reports if this point and other have the same longitude
+// and latitude, within Epsilon. Points with fewer than two
+// coordinates are only equal to each other.
+func (p Point) Equal(other Point) bool {
+	if len(p) < 2 || len(other) < 2 {
+		return len(p) < 2 && len(other) < 2
+	}
+
+	return math.Abs(p.Lon()-other.Lon()) < Epsilon &&
+		math.Abs(p.Lat()-other.Lat()) < Epsilon
+}
+
+// Key returns the canonical string of this point, suitable
+// for use as a map key. The longitude and latitude are
+// written with the fewest digits that represent them
+// exactly, so only points with the same coordinates have
+// the same key. Round a point first to give nearby points
+// the same key.
+func (p Point) Key() string {
+	if len(p) < 2 {
+		return ""
+	}
+
+	return keyCoordinate(p.Lon()) + "," + keyCoordinate(p.Lat())
+}
+
+// keyCoordinate formats v for Key. Negative zero is written
+// as zero so it keys the same.
+func keyCoordinate(v float64) string {
+	if v == 0 {
+		v = 0
+	}
+
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
+
 type PointCollection []Point
 
+// ParsePointCollection parses the Postgres native
+// format of a polygon or path (e.g. "((1,2),(3,4))"),
+// the format produced by PointCollection.String.
+func ParsePointCollection(s string) (PointCollection, error) {
+	s = strings.ReplaceAll(s, " ", "")
+	if len(s) < 2 || s[0] != '(' || s[len(s)-1] != ')' {
+		return nil, fmt.Errorf("invalid point collection %q", s)
+	}
+
+	inner := strings.TrimSuffix(strings.TrimPrefix(s[1:len(s)-1], "("), ")")
+
+	p := PointCollection{}
+	for _, pt := range strings.Split(inner, "),(") {
+		var x, y float64
+		if _, err := fmt.Sscanf(pt, "%g,%g", &x, &y); err != nil {
+			return nil, fmt.Errorf("invalid point %q: %w", pt, err)
+		}
+		p = append(p, NewPoint(x, y))
+	}
+
+	return p, nil
+}
+
 func (p PointCollection) String() string {
 	if len(p) == 0 {
 		return ""
@@ -0,0 +1,126 @@
+package geometry
+
+import (
+	"math"
+	"sort"
+)
+
+// Contains reports if pt is inside this polygon and not inside
+// any of its holes. Points on a boundary may be reported either
+// way.
+func (p Polygon) Contains(pt Point) bool {
+	if !p.Permiter().ringContains(pt) {
+		return false
+	}
+
+	for _, hole := range p.Holes() {
+		if hole.ringContains(pt) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// PointOnSurface returns a point guaranteed to be inside this
+// polygon and outside its holes. Unlike the centroid, it cannot
+// land outside a concave polygon or inside a hole, so it can be
+// used to label a zone or request its forecast.
+//
+// A horizontal line is drawn through the polygon near the latitude
+// of its centroid, and the midpoint of the widest span of the line
+// inside the polygon is returned. The line is moved to pass halfway
+// between vertices, so it never runs through one.
+//
+// It returns nil if the polygon has no area.
+func (p Polygon) PointOnSurface() Point {
+	perimeter := p.Permiter().open()
+	if len(perimeter) < 3 {
+		return nil
+	}
+
+	y, ok := p.scanY(perimeter)
+	if !ok {
+		return nil
+	}
+
+	// The crossings of the scanline with every ring, sorted, pair up
+	// into the spans inside the polygon by the even odd rule.
+	xs := []float64{}
+	for _, ring := range p {
+		xs = append(xs, ring.open().crossings(y)...)
+	}
+	sort.Float64s(xs)
+
+	best, width := -1, 0.0
+	for i := 0; i+1 < len(xs); i += 2 {
+		if w := xs[i+1] - xs[i]; w > width {
+			best, width = i, w
+		}
+	}
+	if best < 0 {
+		return nil
+	}
+
+	return NewPoint((xs[best]+xs[best+1])/2, y)
+}
+
+// scanY returns the latitude PointOnSurface scans along. It is
+// halfway between the vertex latitudes nearest the centroid of
+// perimeter, below and above it. It reports false if no vertex is
+// above the centroid, which is only the case without area.
+func (p Polygon) scanY(perimeter PointCollection) (float64, bool) {
+	cy, ok := perimeter.centroidY()
+	if !ok {
+		return 0, false
+	}
+
+	lo, hi := math.Inf(-1), math.Inf(1)
+	for _, ring := range p {
+		for _, pt := range ring {
+			switch y := pt.Y(); {
+			case y <= cy && y > lo:
+				lo = y
+			case y > cy && y < hi:
+				hi = y
+			}
+		}
+	}
+	if math.IsInf(lo, 0) || math.IsInf(hi, 0) {
+		return 0, false
+	}
+
+	return (lo + hi) / 2, true
+}
+
+// centroidY returns the latitude of the centroid of this open
+// ring. It reports false if the ring has no area.
+func (p PointCollection) centroidY() (float64, bool) {
+	area := p.signedArea()
+	if math.Abs(area) < Epsilon {
+		return 0, false
+	}
+
+	sum := 0.0
+	for i := range p {
+		a, b := p[i], p[(i+1)%len(p)]
+		sum += (a.Y() + b.Y()) * (a.X()*b.Y() - b.X()*a.Y())
+	}
+
+	return sum / (6 * area), true
+}
+
+// crossings returns the longitudes where the horizontal line at
+// the latitude y crosses the edges of this open ring. y should not
+// be the latitude of a vertex.
+func (p PointCollection) crossings(y float64) []float64 {
+	xs := []float64{}
+	for i := range p {
+		a, b := p[i], p[(i+1)%len(p)]
+		if (a.Y() > y) != (b.Y() > y) {
+			xs = append(xs, a.X()+(y-a.Y())*(b.X()-a.X())/(b.Y()-a.Y()))
+		}
+	}
+
+	return xs
+}
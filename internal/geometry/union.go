@@ -0,0 +1,184 @@
+package geometry
+
+import "fmt"
+
+// Union dissolves the polygons of this MultiPolygon that share
+// edges into a single outline. It is intended for merging
+// adjacent zones into a simplified outline for map display.
+//
+// Union is an approximation of a full geometric union. Polygons
+// are merged only where they share identical edges (the same two
+// vertices), which is how adjacent NWS zones are drawn. Polygons
+// that overlap, or touch along edges with different vertices, are
+// not merged and remain separate outlines. Holes of the input
+// polygons are ignored, but gaps fully enclosed by merged polygons
+// are returned as holes.
+func (m MultiPolygon) Union() MultiPolygon {
+	// Collect the edges of each perimeter, all wound in the same
+	// direction. An edge shared by two adjacent polygons appears
+	// once in each direction and is interior to the union.
+	edges := []edge{}
+	for _, polygon := range m {
+		ring := polygon.Permiter().open()
+		if len(ring) < 3 {
+			continue
+		}
+
+		if ring.signedArea() < 0 {
+			ring = ring.reverse()
+		}
+
+		for i := range ring {
+			edges = append(edges, edge{ring[i], ring[(i+1)%len(ring)]})
+		}
+	}
+
+	counts := map[string]int{}
+	for _, e := range edges {
+		counts[e.key()]++
+	}
+
+	outgoing := map[string][]int{}
+	remaining := []edge{}
+	for _, e := range edges {
+		if counts[e.reverse().key()] > 0 {
+			continue
+		}
+
+		outgoing[vertexKey(e.from)] = append(outgoing[vertexKey(e.from)], len(remaining))
+		remaining = append(remaining, e)
+	}
+
+	// Chain the boundary edges into closed rings.
+	used := make([]bool, len(remaining))
+	outers := []PointCollection{}
+	holes := []PointCollection{}
+	for i := range remaining {
+		if used[i] {
+			continue
+		}
+
+		ring := PointCollection{remaining[i].from}
+		used[i] = true
+		start := vertexKey(remaining[i].from)
+		current := remaining[i].to
+		for vertexKey(current) != start {
+			next := -1
+			for _, j := range outgoing[vertexKey(current)] {
+				if !used[j] {
+					next = j
+					break
+				}
+			}
+			if next == -1 {
+				break
+			}
+
+			used[next] = true
+			ring = append(ring, current)
+			current = remaining[next].to
+		}
+
+		if len(ring) < 3 {
+			continue
+		}
+
+		if ring.signedArea() > 0 {
+			outers = append(outers, ring.close())
+		} else {
+			holes = append(holes, ring.close())
+		}
+	}
+
+	union := MultiPolygon{}
+	for _, outer := range outers {
+		union = append(union, Polygon{outer})
+	}
+
+	for _, hole := range holes {
+		for i := range union {
+			if union[i].Permiter().ringContains(hole[0]) {
+				union[i] = append(union[i], hole)
+				break
+			}
+		}
+	}
+
+	return union
+}
+
+// edge is a directed segment between two points.
+type edge struct {
+	from Point
+	to   Point
+}
+
+func (e edge) reverse() edge {
+	return edge{e.to, e.from}
+}
+
+func (e edge) key() string {
+	return vertexKey(e.from) + ">" + vertexKey(e.to)
+}
+
+// vertexKey returns the exact string of p. Unlike Key, the
+// coordinates are not rounded.
+func vertexKey(p Point) string {
+	return fmt.Sprintf("%v", []float64(p))
+}
+
+// open returns the points of this ring without the closing
+// point. If the ring is not closed it is returned as is.
+func (p PointCollection) open() PointCollection {
+	if len(p) > 1 && vertexKey(p[0]) == vertexKey(p[len(p)-1]) {
+		return p[:len(p)-1]
+	}
+
+	return p
+}
+
+// close returns the points of this ring with the first point
+// appended as the closing point.
+func (p PointCollection) close() PointCollection {
+	closed := make(PointCollection, len(p), len(p)+1)
+	copy(closed, p)
+	return append(closed, p[0])
+}
+
+// reverse returns a copy of this ring in reverse order.
+func (p PointCollection) reverse() PointCollection {
+	reversed := make(PointCollection, len(p))
+	for i := range p {
+		reversed[len(p)-1-i] = p[i]
+	}
+	return reversed
+}
+
+// signedArea returns the area of this ring using the shoelace
+// formula. The area is positive if the ring is wound counter
+// clockwise and negative if wound clockwise.
+func (p PointCollection) signedArea() float64 {
+	ring := p.open()
+	area := 0.0
+	for i := range ring {
+		j := (i + 1) % len(ring)
+		area += ring[i].X()*ring[j].Y() - ring[j].X()*ring[i].Y()
+	}
+	return area / 2
+}
+
+// ringContains reports if pt is inside this ring using the even
+// odd rule. Points on the boundary may be reported either way.
+func (p PointCollection) ringContains(pt Point) bool {
+	ring := p.open()
+	inside := false
+	for i, j := 0, len(ring)-1; i < len(ring); j, i = i, i+1 {
+		xi, yi := ring[i].X(), ring[i].Y()
+		xj, yj := ring[j].X(), ring[j].Y()
+		if (yi > pt.Y()) != (yj > pt.Y()) &&
+			pt.X() < (xj-xi)*(pt.Y()-yi)/(yj-yi)+xi {
+			inside = !inside
+		}
+	}
+	return inside
+}
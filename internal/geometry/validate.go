@@ -0,0 +1,175 @@
+package geometry
+
+import "fmt"
+
+// SelfIntersections returns the points where the segments of this
+// ring cross or overlap each other. Segments that are adjacent in
+// the ring share a vertex and are not considered intersecting. A
+// ring that does not intersect itself returns no points.
+//
+// The check compares every pair of segments, so it is quadratic in
+// the number of points.
+func (p PointCollection) SelfIntersections() PointCollection {
+	ring := p.open()
+	n := len(ring)
+	if n < 4 {
+		return nil
+	}
+
+	intersections := PointCollection{}
+	for i := 0; i < n; i++ {
+		a1, a2 := ring[i], ring[(i+1)%n]
+		for j := i + 1; j < n; j++ {
+			// Skip the segments adjacent to segment i.
+			if j == i+1 || (i == 0 && j == n-1) {
+				continue
+			}
+
+			b1, b2 := ring[j], ring[(j+1)%n]
+			if pt, ok := segmentIntersection(a1, a2, b1, b2); ok {
+				intersections = append(intersections, pt)
+			}
+		}
+	}
+
+	return intersections
+}
+
+// segmentIntersection reports if the segments a1->a2 and b1->b2
+// intersect and returns a point where they do.
+func segmentIntersection(a1, a2, b1, b2 Point) (Point, bool) {
+	d1 := cross(b1, b2, a1)
+	d2 := cross(b1, b2, a2)
+	d3 := cross(a1, a2, b1)
+	d4 := cross(a1, a2, b2)
+
+	// The segments properly cross.
+	if ((d1 > 0 && d2 < 0) || (d1 < 0 && d2 > 0)) &&
+		((d3 > 0 && d4 < 0) || (d3 < 0 && d4 > 0)) {
+		t := d1 / (d1 - d2)
+		return NewPoint(
+			a1.X()+t*(a2.X()-a1.X()),
+			a1.Y()+t*(a2.Y()-a1.Y())), true
+	}
+
+	// An endpoint touches or overlaps the other segment.
+	switch {
+	case d1 == 0 && onSegment(b1, b2, a1):
+		return a1, true
+	case d2 == 0 && onSegment(b1, b2, a2):
+		return a2, true
+	case d3 == 0 && onSegment(a1, a2, b1):
+		return b1, true
+	case d4 == 0 && onSegment(a1, a2, b2):
+		return b2, true
+	}
+
+	return nil, false
+}
+
+// onSegment reports if pt, which is collinear with a->b, lies
+// within the bounds of the segment a->b.
+func onSegment(a, b, pt Point) bool {
+	return pt.X() >= min(a.X(), b.X()) && pt.X() <= max(a.X(), b.X()) &&
+		pt.Y() >= min(a.Y(), b.Y()) && pt.Y() <= max(a.Y(), b.Y())
+}
+
+func min(a, b float64) float64 {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func max(a, b float64) float64 {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+// IsSimple reports if no ring of this Polygon intersects itself.
+func (p Polygon) IsSimple() bool {
+	for _, ring := range p {
+		if len(ring.SelfIntersections()) > 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// IsSimple reports if no ring of any Polygon in this MultiPolygon
+// intersects itself.
+func (m MultiPolygon) IsSimple() bool {
+	for _, polygon := range m {
+		if !polygon.IsSimple() {
+			return false
+		}
+	}
+	return true
+}
+
+// Issue is a problem found while validating a Polygon.
+type Issue struct {
+	// The index of the ring in the Polygon. The perimeter
+	// is ring 0 and holes start at ring 1.
+	Ring int `json:"ring"`
+
+	// A description of the problem.
+	Problem string `json:"problem"`
+}
+
+// Validate checks each ring of this Polygon and returns any issues
+// found. A ring must have valid coordinates, be closed (the first
+// and last points are equal), have at least four points, and not
+// intersect itself. A valid Polygon returns no issues.
+func (p Polygon) Validate() []Issue {
+	issues := []Issue{}
+
+	if len(p) == 0 {
+		return append(issues, Issue{Ring: 0, Problem: "polygon has no rings"})
+	}
+
+	for i, ring := range p {
+		invalid := false
+		for j, pt := range ring {
+			if len(pt) < 2 {
+				issues = append(issues, Issue{Ring: i, Problem: fmt.Sprintf("point %d has fewer than two coordinates", j)})
+				invalid = true
+			}
+		}
+		if invalid {
+			continue
+		}
+
+		if len(ring) > 0 && !ring[0].Equal(ring[len(ring)-1]) {
+			issues = append(issues, Issue{Ring: i, Problem: "ring is not closed"})
+		}
+
+		if len(ring.open()) < 3 {
+			issues = append(issues, Issue{Ring: i, Problem: "ring has fewer than three distinct points"})
+			continue
+		}
+
+		for _, pt := range ring.SelfIntersections() {
+			issues = append(issues, Issue{Ring: i, Problem: fmt.Sprintf("ring intersects itself at %s", pt)})
+		}
+	}
+
+	return issues
+}
+
+// Normalize returns a copy of this Polygon with every ring closed.
+// Rings that are already closed are unchanged.
+func (p Polygon) Normalize() Polygon {
+	normalized := Polygon{}
+	for _, ring := range p {
+		if len(ring) == 0 {
+			normalized = append(normalized, PointCollection{})
+			continue
+		}
+
+		normalized = append(normalized, ring.open().close())
+	}
+	return normalized
+}
@@ -0,0 +1,107 @@
+package geometry
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// The String methods of the geometry types produce the Postgres
+// native geometric type format (e.g. "(x,y)") used by the boundary
+// columns. The WKT methods produce standard Well-Known Text, with
+// coordinates ordered x (longitude) then y (latitude).
+
+// SRID is the spatial reference of all geometry in this app. The
+// NWS API serves WGS84 coordinates, so pass SRID to the EWKT
+// methods when writing to a typed geometry(..., 4326) column.
+const SRID = 4326
+
+// WKT returns this point as WKT (e.g. "POINT(-97.1 32.7)").
+func (p Point) WKT() string {
+	if len(p) < 2 {
+		return "POINT EMPTY"
+	}
+
+	return fmt.Sprintf("POINT(%s)", p.wktCoordinates())
+}
+
+// EWKT returns this point as WKT prefixed with srid
+// (e.g. "SRID=4326;POINT(-97.1 32.7)").
+func (p Point) EWKT(srid int) string {
+	return ewkt(srid, p.WKT())
+}
+
+func (p Point) wktCoordinates() string {
+	return strconv.FormatFloat(p.X(), 'f', -1, 64) + " " + strconv.FormatFloat(p.Y(), 'f', -1, 64)
+}
+
+// WKT returns this collection as a WKT line string
+// (e.g. "LINESTRING(0 0, 1 0, 1 1, 0 0)").
+func (p PointCollection) WKT() string {
+	if len(p) == 0 {
+		return "LINESTRING EMPTY"
+	}
+
+	return "LINESTRING" + p.wktRing()
+}
+
+// EWKT returns this collection as a WKT line string prefixed
+// with srid.
+func (p PointCollection) EWKT(srid int) string {
+	return ewkt(srid, p.WKT())
+}
+
+func (p PointCollection) wktRing() string {
+	ss := []string{}
+	for _, pt := range p {
+		ss = append(ss, pt.wktCoordinates())
+	}
+	return "(" + strings.Join(ss, ", ") + ")"
+}
+
+// WKT returns this polygon as WKT, with the perimeter as the
+// first ring followed by any holes
+// (e.g. "POLYGON((0 0, 4 0, 4 4, 0 0), (1 1, 2 1, 2 2, 1 1))").
+func (p Polygon) WKT() string {
+	if len(p) == 0 {
+		return "POLYGON EMPTY"
+	}
+
+	return "POLYGON" + p.wktRings()
+}
+
+// EWKT returns this polygon as WKT prefixed with srid.
+func (p Polygon) EWKT(srid int) string {
+	return ewkt(srid, p.WKT())
+}
+
+func (p Polygon) wktRings() string {
+	ss := []string{}
+	for _, ring := range p {
+		ss = append(ss, ring.wktRing())
+	}
+	return "(" + strings.Join(ss, ", ") + ")"
+}
+
+// WKT returns this multi polygon as WKT
+// (e.g. "MULTIPOLYGON(((0 0, 1 0, 1 1, 0 0)), ((2 2, 3 2, 3 3, 2 2)))").
+func (m MultiPolygon) WKT() string {
+	if len(m) == 0 {
+		return "MULTIPOLYGON EMPTY"
+	}
+
+	ss := []string{}
+	for _, polygon := range m {
+		ss = append(ss, polygon.wktRings())
+	}
+	return "MULTIPOLYGON(" + strings.Join(ss, ", ") + ")"
+}
+
+// EWKT returns this multi polygon as WKT prefixed with srid.
+func (m MultiPolygon) EWKT(srid int) string {
+	return ewkt(srid, m.WKT())
+}
+
+func ewkt(srid int, wkt string) string {
+	return fmt.Sprintf("SRID=%d;%s", srid, wkt)
+}
@@ -1,11 +1,81 @@
 package nws
 
 import (
+	"fmt"
+	"net/url"
+	"strings"
 	"time"
 
 	"github.com/cicconee/weather-app/internal/geometry"
 )
 
+// AlertStatusActual is the status of alerts that are not
+// exercises, tests, system messages or drafts.
+const AlertStatusActual = "actual"
+
+// AlertScope is the query parameter that scopes the active
+// alerts to a set of codes.
+type AlertScope string
+
+const (
+	// AlertScopeArea scopes alerts to states or marine areas
+	// (e.g. "KS", "AM").
+	AlertScopeArea AlertScope = "area"
+
+	// AlertScopeZone scopes alerts to zones (e.g. "KSZ033",
+	// "KSC091").
+	AlertScopeZone AlertScope = "zone"
+
+	// AlertScopeRegion scopes alerts to marine regions (e.g.
+	// "AL", "GM").
+	AlertScopeRegion AlertScope = "region"
+)
+
+// ParseAlertScope returns s as a AlertScope. An error is
+// returned if s is not a supported scope.
+func ParseAlertScope(s string) (AlertScope, error) {
+	switch scope := AlertScope(s); scope {
+	case AlertScopeArea, AlertScopeZone, AlertScopeRegion:
+		return scope, nil
+	default:
+		return "", fmt.Errorf("unsupported alert scope %q", s)
+	}
+}
+
+// AlertQuery is the query for active alerts. An empty Status
+// is AlertStatusActual and an empty Scope is AlertScopeArea.
+type AlertQuery struct {
+	// The alert status (e.g. "actual", "exercise", "test").
+	Status string
+
+	// The query parameter Codes are sent as.
+	Scope AlertScope
+
+	// The codes of the states, zones or regions to get
+	// alerts for.
+	Codes []string
+}
+
+// url returns the url of the active alerts endpoint for this
+// query.
+func (q AlertQuery) url() string {
+	status := q.Status
+	if status == "" {
+		status = AlertStatusActual
+	}
+
+	scope := q.Scope
+	if scope == "" {
+		scope = AlertScopeArea
+	}
+
+	return fmt.Sprintf("%s/alerts/active?status=%s&%s=%s",
+		API,
+		url.QueryEscape(status),
+		scope,
+		strings.Join(q.Codes, ","))
+}
+
 type Alert struct {
 	URI           string
 	ID            string           `json:"id"`
@@ -26,9 +96,16 @@ type Alert struct {
 	Description   string           `json:"description"`
 	Instruction   string           `json:"instruction"`
 	Response      string           `json:"response"`
+	SenderName    string           `json:"senderName"`
+	Parameters    AlertParameters  `json:"parameters"`
 	Geometry      geometry.Polygon
 }
 
+// AlertParameters are the parameters of a alert, keyed by
+// name (e.g. "VTEC", "eventEndingTime"). Each parameter may
+// have more than one value.
+type AlertParameters map[string][]string
+
 type AlertReference struct {
 	ID string `json:"identifier"`
 }
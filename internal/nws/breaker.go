@@ -0,0 +1,136 @@
+package nws
+
+import (
+	"sync"
+	"time"
+
+	"github.com/cicconee/weather-app/internal/app"
+)
+
+const (
+	// DefaultBreakerThreshold is the number of consecutive failed
+	// requests that opens a Breaker with no Threshold.
+	DefaultBreakerThreshold = 5
+
+	// DefaultBreakerCooldown is how long a Breaker with no Cooldown
+	// stays open.
+	DefaultBreakerCooldown = 30 * time.Second
+)
+
+// Breaker is a circuit breaker for requests to the NWS API. It is safe
+// for concurrent use.
+//
+// Each endpoint (e.g. EndpointHourly) has its own circuit, so failures
+// of one endpoint do not block requests to the others. A circuit starts
+// closed and lets every request through. After Threshold consecutive
+// failures it opens, and requests fail with a
+// *app.UpstreamUnavailableError without being sent. Once Cooldown has
+// passed the circuit is half-open and lets a single request through as
+// a probe. If the probe succeeds the circuit closes, otherwise it opens
+// for another Cooldown.
+//
+// A failure is a request that does not receive a response or receives
+// a 5xx status code.
+type Breaker struct {
+	// The number of consecutive failures that opens a circuit.
+	// Zero uses DefaultBreakerThreshold.
+	Threshold int
+
+	// How long a circuit stays open. Zero uses
+	// DefaultBreakerCooldown.
+	Cooldown time.Duration
+
+	mu       sync.Mutex
+	circuits map[string]*circuit
+}
+
+// circuit is the state of the requests to a single endpoint.
+type circuit struct {
+	failures int
+	openedAt time.Time
+	open     bool
+	probing  bool
+}
+
+func (b *Breaker) threshold() int {
+	if b.Threshold == 0 {
+		return DefaultBreakerThreshold
+	}
+
+	return b.Threshold
+}
+
+func (b *Breaker) cooldown() time.Duration {
+	if b.Cooldown == 0 {
+		return DefaultBreakerCooldown
+	}
+
+	return b.Cooldown
+}
+
+// circuit returns the circuit of endpoint. b.mu must be held.
+func (b *Breaker) circuit(endpoint string) *circuit {
+	if b.circuits == nil {
+		b.circuits = map[string]*circuit{}
+	}
+
+	c, ok := b.circuits[endpoint]
+	if !ok {
+		c = &circuit{}
+		b.circuits[endpoint] = c
+	}
+
+	return c
+}
+
+// allow returns a *app.UpstreamUnavailableError if a request to
+// endpoint should not be sent. When the circuit is half-open, only the
+// first request allowed is the probe.
+func (b *Breaker) allow(endpoint string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	c := b.circuit(endpoint)
+	if !c.open {
+		return nil
+	}
+
+	retryAt := c.openedAt.Add(b.cooldown())
+	if c.probing || time.Now().Before(retryAt) {
+		return &app.UpstreamUnavailableError{RetryAt: retryAt}
+	}
+
+	c.probing = true
+	return nil
+}
+
+// record records the result of a request to endpoint that was allowed.
+func (b *Breaker) record(endpoint string, failed bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	c := b.circuit(endpoint)
+	if !failed {
+		c.failures = 0
+		c.open = false
+		c.probing = false
+		return
+	}
+
+	c.failures++
+	if c.probing || c.failures >= b.threshold() {
+		c.open = true
+		c.probing = false
+		c.openedAt = time.Now()
+	}
+}
+
+// release gives up a request to endpoint that was allowed without recording its
+// result, such as a request canceled by the caller. If it was the probe,
+// the next request after it is allowed as the probe instead.
+func (b *Breaker) release(endpoint string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.circuit(endpoint).probing = false
+}
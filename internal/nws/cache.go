@@ -0,0 +1,80 @@
+package nws
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// expires returns when the response with the headers h becomes stale.
+// The max-age directive of the Cache-Control header is used if set,
+// otherwise the Expires header. It returns the zero time if neither is
+// set, can be parsed, or the response must not be cached or is already
+// stale.
+//
+// The max-age is counted from the Date header of the response, or now
+// if it is not set, less the Age header.
+func expires(h http.Header, now time.Time) time.Time {
+	if maxAge, ok := maxAge(h.Get("Cache-Control")); ok {
+		if maxAge < 0 {
+			return time.Time{}
+		}
+
+		date := now
+		if t, err := http.ParseTime(h.Get("Date")); err == nil {
+			date = t
+		}
+
+		if age, err := strconv.Atoi(h.Get("Age")); err == nil && age > 0 {
+			maxAge -= age
+		}
+
+		// A response already stale says nothing about when
+		// the data will be updated.
+		if maxAge <= 0 {
+			return time.Time{}
+		}
+
+		return date.Add(time.Duration(maxAge) * time.Second).UTC()
+	}
+
+	t, err := http.ParseTime(h.Get("Expires"))
+	if err != nil {
+		return time.Time{}
+	}
+
+	return t.UTC()
+}
+
+// maxAge returns the max-age directive of the Cache-Control header cc in
+// seconds. It reports false if cc does not set max-age. A response that
+// must not be cached returns -1.
+func maxAge(cc string) (int, bool) {
+	for _, directive := range strings.Split(cc, ",") {
+		name, value, _ := strings.Cut(strings.TrimSpace(directive), "=")
+		switch strings.ToLower(name) {
+		case "no-store", "no-cache":
+			return -1, true
+		case "max-age":
+			seconds, err := strconv.Atoi(strings.Trim(value, `"`))
+			if err != nil {
+				return 0, false
+			}
+			return seconds, true
+		}
+	}
+
+	return 0, false
+}
+
+// lastModified returns the Last-Modified header of h. It returns the
+// zero time if it is not set or cannot be parsed.
+func lastModified(h http.Header) time.Time {
+	t, err := http.ParseTime(h.Get("Last-Modified"))
+	if err != nil {
+		return time.Time{}
+	}
+
+	return t
+}
@@ -1,10 +1,12 @@
 package nws
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
+	"io"
 	"net/http"
-	"strings"
+	"time"
 
 	"github.com/cicconee/weather-app/internal/app"
 	"github.com/cicconee/weather-app/internal/forecast"
@@ -16,15 +18,87 @@ type HTTPDoer interface {
 	Do(*http.Request) (*http.Response, error)
 }
 
+// DefaultAccept is the Accept header sent by a Client that
+// does not configure one. It pins the GeoJSON version so
+// responses do not change when the NWS API changes its default.
+const DefaultAccept = "application/geo+json;version=1"
+
 type Client struct {
 	HTTP      HTTPDoer
 	UserAgent string
+
+	// Timeout is the time limit for each request, including
+	// reading the response body. Zero only uses the time limit
+	// of HTTP, if it has one.
+	Timeout time.Duration
+
+	// Timeouts overrides Timeout per endpoint (e.g.
+	// EndpointHourly). HTTP should not have a shorter time
+	// limit than any override.
+	Timeouts map[string]time.Duration
+
+	// Accept is the Accept header sent with each request. Empty
+	// uses DefaultAccept.
+	Accept string
+
+	// AcceptLanguage is optional. If set, it is sent as the
+	// Accept-Language header with each request.
+	AcceptLanguage string
+
+	// Metrics is optional. If set, the outcome of each request
+	// to the NWS API is recorded.
+	Metrics MetricsHook
+
+	// Breaker is optional. If set, requests to an endpoint are
+	// not sent while its circuit is open and fail with a *app.UpstreamUnavailableError.
+	Breaker *Breaker
+
+	// Retries is optional. If set, it caps the retries callers
+	// make through AllowRetry.
+	Retries *RetryBudget
 }
 
 var DefaultClient = &Client{
 	HTTP: defaultHTTP(),
 }
 
+// NewClient returns a Client that sets userAgent on each request
+// and times out requests after timeout. A zero timeout will use
+// DefaultTimeout. The connection pool uses DefaultTransportConfig.
+// Requests go through a Breaker with the default threshold and
+// cooldown, and retries are capped by a RetryBudget with the
+// default size.
+func NewClient(userAgent string, timeout time.Duration) *Client {
+	return NewClientWithTransport(userAgent, timeout, DefaultTransportConfig)
+}
+
+// NewClientWithTransport returns a Client like NewClient with the
+// connection pool tuned by config.
+func NewClientWithTransport(userAgent string, timeout time.Duration, config TransportConfig) *Client {
+	if timeout == 0 {
+		timeout = DefaultTimeout
+	}
+
+	return &Client{
+		HTTP:      newHTTP(0, config),
+		UserAgent: userAgent,
+		Timeout:   timeout,
+		Breaker:   &Breaker{},
+		Retries:   &RetryBudget{},
+	}
+}
+
+// AllowRetry reports if a caller may retry a failed request. Each
+// call that returns true spends from the Retries budget. Without a
+// budget retries are always allowed.
+func (c *Client) AllowRetry() bool {
+	if c.Retries == nil {
+		return true
+	}
+
+	return c.Retries.Allow()
+}
+
 func (c *Client) http() HTTPDoer {
 	if c.HTTP == nil {
 		return DefaultClient.HTTP
@@ -33,9 +107,50 @@ func (c *Client) http() HTTPDoer {
 	return c.HTTP
 }
 
-func (c *Client) get(url string) (*http.Response, error) {
-	req, err := http.NewRequest(http.MethodGet, url, nil)
+func (c *Client) accept() string {
+	if c.Accept == "" {
+		return DefaultAccept
+	}
+
+	return c.Accept
+}
+
+// timeout returns the time limit for a request to endpoint.
+func (c *Client) timeout(endpoint string) time.Duration {
+	if t, ok := c.Timeouts[endpoint]; ok {
+		return t
+	}
+
+	return c.Timeout
+}
+
+// cancelBody is a response body that cancels the context of its
+// request once closed.
+type cancelBody struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b *cancelBody) Close() error {
+	defer b.cancel()
+	return b.ReadCloser.Close()
+}
+
+// get executes a GET request to url. endpoint names the NWS API
+// endpoint being requested and is used to record metrics and pick
+// the timeout. The request is canceled if ctx is done.
+//
+// If since is not the zero time it is sent as the If-Modified-Since
+// header, and the NWS API may respond with a 304 status code.
+func (c *Client) get(ctx context.Context, endpoint string, url string, since time.Time) (*http.Response, error) {
+	reqCtx, cancel := ctx, context.CancelFunc(func() {})
+	if t := c.timeout(endpoint); t > 0 {
+		reqCtx, cancel = context.WithTimeout(ctx, t)
+	}
+
+	req, err := http.NewRequestWithContext(reqCtx, http.MethodGet, url, nil)
 	if err != nil {
+		cancel()
 		return nil, fmt.Errorf("failed creating GET request: %w", err)
 	}
 
@@ -43,16 +158,65 @@ func (c *Client) get(url string) (*http.Response, error) {
 		req.Header.Set("User-Agent", c.UserAgent)
 	}
 
+	req.Header.Set("Accept", c.accept())
+	if c.AcceptLanguage != "" {
+		req.Header.Set("Accept-Language", c.AcceptLanguage)
+	}
+
+	if !since.IsZero() {
+		req.Header.Set("If-Modified-Since", since.UTC().Format(http.TimeFormat))
+	}
+
+	if c.Breaker != nil {
+		if err := c.Breaker.allow(endpoint); err != nil {
+			cancel()
+			c.observe(endpoint, OutcomeOpen)
+			return nil, err
+		}
+	}
+
 	res, err := c.http().Do(req)
 	if err != nil {
+		cancel()
+		// A request canceled by the caller says nothing about the
+		// NWS API, so no result is recorded. A request that timed
+		// out or failed on the network does.
+		if ctx.Err() != nil {
+			c.release(endpoint)
+		} else {
+			c.record(endpoint, true)
+		}
+		c.observe(endpoint, OutcomeError)
 		return nil, fmt.Errorf("failed to execute GET request: %w", err)
 	}
+	res.Body = &cancelBody{ReadCloser: res.Body, cancel: cancel}
+
+	c.record(endpoint, res.StatusCode >= 500)
+	c.observe(endpoint, statusClass(res.StatusCode))
 
 	return res, nil
 }
 
-func (c *Client) featureCollection(url string) (*featureCollection, error) {
-	res, err := c.get(url)
+func (c *Client) record(endpoint string, failed bool) {
+	if c.Breaker != nil {
+		c.Breaker.record(endpoint, failed)
+	}
+}
+
+func (c *Client) release(endpoint string) {
+	if c.Breaker != nil {
+		c.Breaker.release(endpoint)
+	}
+}
+
+func (c *Client) observe(endpoint string, outcome string) {
+	if c.Metrics != nil {
+		c.Metrics.ObserveCall(endpoint, outcome)
+	}
+}
+
+func (c *Client) featureCollection(ctx context.Context, endpoint string, url string) (*featureCollection, error) {
+	res, err := c.get(ctx, endpoint, url, time.Time{})
 	if err != nil {
 		return nil, fmt.Errorf("failed to getting http response: %w", err)
 	}
@@ -68,20 +232,36 @@ func (c *Client) featureCollection(url string) (*featureCollection, error) {
 	}
 
 	var collection featureCollection
-	if err := json.NewDecoder(res.Body).Decode(&collection); err != nil {
-		return nil, fmt.Errorf("failed decoding http response: %w", err)
+	if err := decode(endpoint, "response", res.Body, &collection); err != nil {
+		return nil, err
 	}
 
 	return &collection, nil
 }
 
-func (c *Client) feature(url string) (*feature, error) {
-	res, err := c.get(url)
+func (c *Client) feature(ctx context.Context, endpoint string, url string) (*feature, error) {
+	return c.featureIfModified(ctx, endpoint, url, time.Time{})
+}
+
+// featureIfModified gets the feature at url like feature. If since is not
+// the zero time a conditional request is made. If the NWS API responds that
+// the feature has not been modified since, the returned feature only has
+// notModified and its caching fields set.
+func (c *Client) featureIfModified(ctx context.Context, endpoint string, url string, since time.Time) (*feature, error) {
+	res, err := c.get(ctx, endpoint, url, since)
 	if err != nil {
 		return nil, fmt.Errorf("failed getting http response: %w", err)
 	}
 	defer res.Body.Close()
 
+	if res.StatusCode == http.StatusNotModified && !since.IsZero() {
+		return &feature{
+			notModified:  true,
+			expires:      expires(res.Header, time.Now()),
+			lastModified: since,
+		}, nil
+	}
+
 	if res.StatusCode != http.StatusOK {
 		var statusErr *app.NWSAPIStatusCodeError
 		if err := json.NewDecoder(res.Body).Decode(&statusErr); err != nil {
@@ -93,22 +273,37 @@ func (c *Client) feature(url string) (*feature, error) {
 	}
 
 	var f feature
-	if err := json.NewDecoder(res.Body).Decode(&f); err != nil {
-		return nil, fmt.Errorf("failed decoding http response: %w", err)
+	if err := decode(endpoint, "response", res.Body, &f); err != nil {
+		return nil, err
 	}
+	f.expires = expires(res.Header, time.Now())
+	f.lastModified = lastModified(res.Header)
 
 	return &f, nil
 }
 
-func (c *Client) GetZoneCollection(area string) ([]Zone, error) {
-	collection, err := c.featureCollection(fmt.Sprintf("%s/zones?area=%s", API, area))
+func (c *Client) GetZoneCollection(ctx context.Context, area string) ([]Zone, error) {
+	return c.zoneCollection(ctx, fmt.Sprintf("%s/zones?area=%s", API, area))
+}
+
+// GetZoneCollectionWithGeometry gets the zones of area like
+// GetZoneCollection, but asks the NWS API to include the
+// geometry of each zone. This replaces a GetZone call per
+// zone with a single, larger, request. A zone the NWS API
+// returns without geometry will have an empty Geometry.
+func (c *Client) GetZoneCollectionWithGeometry(ctx context.Context, area string) ([]Zone, error) {
+	return c.zoneCollection(ctx, fmt.Sprintf("%s/zones?area=%s&include_geometry=true", API, area))
+}
+
+func (c *Client) zoneCollection(ctx context.Context, url string) ([]Zone, error) {
+	collection, err := c.featureCollection(ctx, EndpointZones, url)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get feature collection: %w", err)
 	}
 
 	var zoneCollection []Zone
 	for _, f := range collection.Features {
-		zone, err := f.parseZone()
+		zone, err := f.parseZone(EndpointZones)
 		if err != nil {
 			return nil, fmt.Errorf("failed to parse Zone (URI: %s): %w", f.ID, err)
 		}
@@ -119,13 +314,13 @@ func (c *Client) GetZoneCollection(area string) ([]Zone, error) {
 	return zoneCollection, nil
 }
 
-func (c *Client) GetZone(zoneType string, zoneCode string) (Zone, error) {
-	feat, err := c.feature(fmt.Sprintf("%s/zones/%s/%s", API, zoneType, zoneCode))
+func (c *Client) GetZone(ctx context.Context, zoneType string, zoneCode string) (Zone, error) {
+	feat, err := c.feature(ctx, EndpointZone, fmt.Sprintf("%s/zones/%s/%s", API, zoneType, zoneCode))
 	if err != nil {
 		return Zone{}, fmt.Errorf("failed to get feature: %w", err)
 	}
 
-	zone, err := feat.parseZone()
+	zone, err := feat.parseZone(EndpointZone)
 	if err != nil {
 		return Zone{}, fmt.Errorf("failed to parse Zone: %w", err)
 	}
@@ -133,15 +328,22 @@ func (c *Client) GetZone(zoneType string, zoneCode string) (Zone, error) {
 	return zone, nil
 }
 
-func (c *Client) GetActiveAlerts(states ...string) ([]Alert, error) {
-	if len(states) == 0 {
+func (c *Client) GetActiveAlerts(ctx context.Context, states ...string) ([]Alert, error) {
+	return c.GetAlerts(ctx, AlertQuery{
+		Status: AlertStatusActual,
+		Scope:  AlertScopeArea,
+		Codes:  states,
+	})
+}
+
+// GetAlerts gets the active alerts matching q. If q has no Codes
+// no request is made and no alerts are returned.
+func (c *Client) GetAlerts(ctx context.Context, q AlertQuery) ([]Alert, error) {
+	if len(q.Codes) == 0 {
 		return []Alert{}, nil
 	}
 
-	collection, err := c.featureCollection(
-		fmt.Sprintf("%s/alerts/active?status=actual&area=%s",
-			API,
-			strings.Join(states, ",")))
+	collection, err := c.featureCollection(ctx, EndpointAlerts, q.url())
 	if err != nil {
 		return nil, fmt.Errorf("failed to get feature collection: %w", err)
 	}
@@ -149,8 +351,8 @@ func (c *Client) GetActiveAlerts(states ...string) ([]Alert, error) {
 	var alerts []Alert
 	for _, f := range collection.Features {
 		var alert Alert
-		if err := json.Unmarshal(f.Properties, &alert); err != nil {
-			return nil, fmt.Errorf("failed to unmarshal alert properties: %w", err)
+		if err := unmarshal(EndpointAlerts, "properties", f.Properties, &alert); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal alert properties (URI: %s): %w", f.ID, err)
 		}
 
 		geo, err := f.Geometry.ParsePolygon()
@@ -166,29 +368,49 @@ func (c *Client) GetActiveAlerts(states ...string) ([]Alert, error) {
 	return alerts, nil
 }
 
-func (c *Client) GetGridpoint(x, y float64) (forecast.GridpointAPIResource, error) {
-	feature, err := c.feature(fmt.Sprintf("%s/points/%f,%f", API, x, y))
+// GetGridpoint gets the gridpoint of the point at the longitude lon
+// and latitude lat. The NWS API orders points latitude first, so the
+// url is /points/{lat},{lon}.
+func (c *Client) GetGridpoint(ctx context.Context, lon, lat float64) (forecast.GridpointAPIResource, error) {
+	feature, err := c.feature(ctx, EndpointPoints, fmt.Sprintf("%s/points/%f,%f", API, lat, lon))
 	if err != nil {
 		return forecast.GridpointAPIResource{}, err
 	}
 
 	gridpoint := forecast.GridpointAPIResource{}
-	if err := json.Unmarshal(feature.Properties, &gridpoint); err != nil {
+	if err := unmarshal(EndpointPoints, "properties", feature.Properties, &gridpoint); err != nil {
 		return forecast.GridpointAPIResource{}, fmt.Errorf("parsing gridpoint: %w", err)
 	}
 
 	return gridpoint, nil
 }
 
-func (c *Client) GetHourlyForecast(id string, x, y int) (forecast.HourlyAPIResource, error) {
-	feature, err := c.feature(fmt.Sprintf("%s/gridpoints/%s/%d,%d/forecast/hourly?units=us",
-		API, id, x, y))
+func (c *Client) GetHourlyForecast(ctx context.Context, id string, x, y int) (forecast.HourlyAPIResource, error) {
+	return c.GetHourlyForecastIfModified(ctx, id, x, y, time.Time{})
+}
+
+// GetHourlyForecastIfModified gets the hourly forecast for a gridpoint like
+// GetHourlyForecast. If since is not the zero time it is sent as the
+// If-Modified-Since header. If the forecast has not been modified since,
+// the returned forecast.HourlyAPIResource only has NotModified and Expires
+// set.
+func (c *Client) GetHourlyForecastIfModified(ctx context.Context, id string, x, y int, since time.Time) (forecast.HourlyAPIResource, error) {
+	feature, err := c.featureIfModified(ctx, EndpointHourly, fmt.Sprintf("%s/gridpoints/%s/%d,%d/forecast/hourly?units=us",
+		API, id, x, y), since)
 	if err != nil {
 		return forecast.HourlyAPIResource{}, err
 	}
 
+	if feature.notModified {
+		return forecast.HourlyAPIResource{
+			NotModified:  true,
+			Expires:      feature.expires,
+			LastModified: feature.lastModified,
+		}, nil
+	}
+
 	hourly := forecast.HourlyAPIResource{}
-	if err := json.Unmarshal(feature.Properties, &hourly); err != nil {
+	if err := unmarshal(EndpointHourly, "properties", feature.Properties, &hourly); err != nil {
 		return forecast.HourlyAPIResource{}, fmt.Errorf("nws: failed to parse forecast.Hourly: %w", err)
 	}
 
@@ -198,6 +420,53 @@ func (c *Client) GetHourlyForecast(id string, x, y int) (forecast.HourlyAPIResou
 	}
 
 	hourly.Geometry = polygon
+	hourly.Expires = feature.expires
+	hourly.LastModified = feature.lastModified
 
 	return hourly, nil
 }
+
+// GetForecast gets the 12-hour forecast for a gridpoint. The periods
+// have the same shape as the hourly forecast, so it is returned as a
+// forecast.HourlyAPIResource.
+func (c *Client) GetForecast(ctx context.Context, id string, x, y int) (forecast.HourlyAPIResource, error) {
+	feature, err := c.feature(ctx, EndpointForecast, fmt.Sprintf("%s/gridpoints/%s/%d,%d/forecast?units=us",
+		API, id, x, y))
+	if err != nil {
+		return forecast.HourlyAPIResource{}, err
+	}
+
+	twelveHour := forecast.HourlyAPIResource{}
+	if err := unmarshal(EndpointForecast, "properties", feature.Properties, &twelveHour); err != nil {
+		return forecast.HourlyAPIResource{}, fmt.Errorf("nws: failed to parse 12-hour forecast: %w", err)
+	}
+
+	polygon, err := feature.Geometry.ParsePolygon()
+	if err != nil {
+		return forecast.HourlyAPIResource{}, fmt.Errorf("nws: failed to parse forecast geometry: %w", err)
+	}
+
+	twelveHour.Geometry = polygon
+	twelveHour.Expires = feature.expires
+	twelveHour.LastModified = feature.lastModified
+
+	return twelveHour, nil
+}
+
+// GetGridData gets the raw data layers of a gridpoint. Only the layers
+// read by forecast.GridDataAPIResource are parsed.
+// The request is canceled if ctx is done.
+func (c *Client) GetGridData(ctx context.Context, id string, x, y int) (forecast.GridDataAPIResource, error) {
+	feature, err := c.feature(ctx, EndpointGridData, fmt.Sprintf("%s/gridpoints/%s/%d,%d",
+		API, id, x, y))
+	if err != nil {
+		return forecast.GridDataAPIResource{}, err
+	}
+
+	data := forecast.GridDataAPIResource{}
+	if err := unmarshal(EndpointGridData, "properties", feature.Properties, &data); err != nil {
+		return forecast.GridDataAPIResource{}, fmt.Errorf("nws: failed to parse grid data: %w", err)
+	}
+
+	return data, nil
+}
@@ -0,0 +1,50 @@
+package nws
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+// fakeHTTP is a HTTPDoer that records the requests made to it and
+// responds to each with a 200 status code and body.
+type fakeHTTP struct {
+	body     string
+	requests []*http.Request
+}
+
+func (f *fakeHTTP) Do(req *http.Request) (*http.Response, error) {
+	f.requests = append(f.requests, req)
+
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Header:     http.Header{},
+		Body:       io.NopCloser(strings.NewReader(f.body)),
+		Request:    req,
+	}, nil
+}
+
+func TestGetGridpointURLOrder(t *testing.T) {
+	doer := &fakeHTTP{body: `{"properties": {"gridId": "BOU", "gridX": 62, "gridY": 60}}`}
+	c := &Client{HTTP: doer}
+
+	gridpoint, err := c.GetGridpoint(context.Background(), -105.0, 39.7)
+	if err != nil {
+		t.Fatalf("GetGridpoint: %v", err)
+	}
+
+	if len(doer.requests) != 1 {
+		t.Fatalf("got %d requests, want 1", len(doer.requests))
+	}
+
+	want := API + "/points/39.700000,-105.000000"
+	if got := doer.requests[0].URL.String(); got != want {
+		t.Errorf("got url %q, want %q", got, want)
+	}
+
+	if gridpoint.GridID != "BOU" || gridpoint.GridX != 62 || gridpoint.GridY != 60 {
+		t.Errorf("got gridpoint %s/%d,%d, want BOU/62,60", gridpoint.GridID, gridpoint.GridX, gridpoint.GridY)
+	}
+}
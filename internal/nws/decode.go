@@ -0,0 +1,110 @@
+package nws
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// MaxDecodeSnippet is the most bytes of a response body included
+// in a DecodeError. Bodies can be megabytes of geometry, so only
+// the part around the failure is kept.
+const MaxDecodeSnippet = 256
+
+// DecodeError is returned when a NWS API response cannot be decoded,
+// most likely because the NWS API changed its format. It holds where
+// decoding failed and a snippet of the body around the failure.
+type DecodeError struct {
+	// The endpoint requested (e.g. EndpointHourly).
+	Endpoint string
+
+	// The part of the response being decoded (e.g. "properties").
+	Part string
+
+	// The JSON field that failed to decode. It is empty if the
+	// body is not valid JSON.
+	Field string
+
+	// The byte offset of the failure in the decoded part.
+	Offset int64
+
+	// At most MaxDecodeSnippet bytes of the decoded part around
+	// Offset.
+	Snippet string
+
+	Err error
+}
+
+func (e *DecodeError) Error() string {
+	field := ""
+	if e.Field != "" {
+		field = fmt.Sprintf(", field=%s", e.Field)
+	}
+
+	return fmt.Sprintf("failed decoding %s %s (offset=%d%s): %v: %q",
+		e.Endpoint,
+		e.Part,
+		e.Offset,
+		field,
+		e.Err,
+		e.Snippet)
+}
+
+func (e *DecodeError) Unwrap() error {
+	return e.Err
+}
+
+// decode reads r and decodes it into v. If the body cannot be read
+// or decoded a DecodeError is returned.
+func decode(endpoint string, part string, r io.Reader, v any) error {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return &DecodeError{Endpoint: endpoint, Part: part, Err: err}
+	}
+
+	return unmarshal(endpoint, part, data, v)
+}
+
+// unmarshal decodes data into v. If data cannot be decoded a
+// DecodeError is returned.
+func unmarshal(endpoint string, part string, data []byte, v any) error {
+	err := json.Unmarshal(data, v)
+	if err == nil {
+		return nil
+	}
+
+	decodeErr := &DecodeError{Endpoint: endpoint, Part: part, Err: err}
+
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	switch {
+	case errors.As(err, &syntaxErr):
+		decodeErr.Offset = syntaxErr.Offset
+	case errors.As(err, &typeErr):
+		decodeErr.Offset = typeErr.Offset
+		decodeErr.Field = typeErr.Field
+	}
+	decodeErr.Snippet = snippet(data, decodeErr.Offset)
+
+	return decodeErr
+}
+
+// snippet returns at most MaxDecodeSnippet bytes of data centered
+// on offset.
+func snippet(data []byte, offset int64) string {
+	start := int(offset) - MaxDecodeSnippet/2
+	if start < 0 {
+		start = 0
+	}
+	if start > len(data) {
+		start = len(data)
+	}
+
+	end := start + MaxDecodeSnippet
+	if end > len(data) {
+		end = len(data)
+	}
+
+	return string(data[start:end])
+}
@@ -0,0 +1,16 @@
+package nws
+
+import "github.com/cicconee/weather-app/internal/app"
+
+// The errors a Client returns for a status code, matched with
+// errors.Is. They are the app errors so packages the nws package
+// depends on can match them too.
+var (
+	// ErrNotFound is returned when the NWS API responds with a
+	// 404 status code.
+	ErrNotFound = app.ErrNWSNotFound
+
+	// ErrBadRequest is returned when the NWS API responds with a
+	// 400 status code.
+	ErrBadRequest = app.ErrNWSBadRequest
+)
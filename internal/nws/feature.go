@@ -3,17 +3,35 @@ package nws
 import (
 	"encoding/json"
 	"fmt"
+	"time"
 )
 
 type feature struct {
 	ID         string          `json:"id"`
 	Geometry   geo             `json:"geometry"`
 	Properties json.RawMessage `json:"properties"`
+
+	// expires is when the response holding this feature becomes
+	// stale, as reported by its caching headers. It is the zero
+	// time if the headers did not report it.
+	expires time.Time
+
+	// lastModified is when the feature was last modified, as
+	// reported by the Last-Modified header. It is the zero time
+	// if the header was not set.
+	lastModified time.Time
+
+	// notModified is true if the feature was not modified since
+	// a conditional request was made. Only the caching fields are
+	// set.
+	notModified bool
 }
 
-func (f *feature) parseZone() (Zone, error) {
+// parseZone parses this feature as a Zone. endpoint is the endpoint
+// the feature was returned by and is included in decode errors.
+func (f *feature) parseZone(endpoint string) (Zone, error) {
 	var zone Zone
-	if err := json.Unmarshal(f.Properties, &zone); err != nil {
+	if err := unmarshal(endpoint, "properties", f.Properties, &zone); err != nil {
 		return zone, fmt.Errorf("failed unmarshalling *feature Properties field into Zone: %w", err)
 	}
 
@@ -55,3 +55,19 @@ func (g *geo) ParsePolygon() (geometry.Polygon, error) {
 
 	return geo, nil
 }
+
+// ParseGeoJSON parses a GeoJSON geometry object of type Polygon or
+// MultiPolygon as a MultiPolygon. A Polygon is returned as a
+// MultiPolygon with a single Polygon.
+func ParseGeoJSON(data []byte) (geometry.MultiPolygon, error) {
+	var g geo
+	if err := json.Unmarshal(data, &g); err != nil {
+		return nil, fmt.Errorf("failed unmarshalling geometry: %w", err)
+	}
+
+	if g.Type == "" {
+		return nil, fmt.Errorf("missing geometry type")
+	}
+
+	return g.ParseMultiPolygon()
+}
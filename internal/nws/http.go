@@ -5,17 +5,82 @@ import (
 	"time"
 )
 
-func defaultTransport() *http.Transport {
+// TransportConfig tunes the connection pool of the
+// transport a Client uses to reach the NWS API. Zero
+// fields use the value in DefaultTransportConfig.
+//
+// Syncing a state fans out many concurrent requests
+// to the same host. Keeping enough idle connections
+// per host lets them be reused instead of paying for
+// a new TLS handshake on each request.
+type TransportConfig struct {
+	// The most idle connections kept across all hosts.
+	MaxIdleConns int
+
+	// The most idle connections kept per host.
+	MaxIdleConnsPerHost int
+
+	// The most connections per host, in any state.
+	MaxConnsPerHost int
+
+	// How long a idle connection is kept before it
+	// is closed.
+	IdleConnTimeout time.Duration
+}
+
+// DefaultTransportConfig is the TransportConfig used by
+// a Client that does not configure one.
+var DefaultTransportConfig = TransportConfig{
+	MaxIdleConns:        100,
+	MaxIdleConnsPerHost: 100,
+	MaxConnsPerHost:     100,
+	IdleConnTimeout:     90 * time.Second,
+}
+
+// withDefaults returns this config with zero fields
+// set to the value in DefaultTransportConfig.
+func (c TransportConfig) withDefaults() TransportConfig {
+	if c.MaxIdleConns == 0 {
+		c.MaxIdleConns = DefaultTransportConfig.MaxIdleConns
+	}
+
+	if c.MaxIdleConnsPerHost == 0 {
+		c.MaxIdleConnsPerHost = DefaultTransportConfig.MaxIdleConnsPerHost
+	}
+
+	if c.MaxConnsPerHost == 0 {
+		c.MaxConnsPerHost = DefaultTransportConfig.MaxConnsPerHost
+	}
+
+	if c.IdleConnTimeout == 0 {
+		c.IdleConnTimeout = DefaultTransportConfig.IdleConnTimeout
+	}
+
+	return c
+}
+
+func newTransport(config TransportConfig) *http.Transport {
+	config = config.withDefaults()
+
 	t := http.DefaultTransport.(*http.Transport).Clone()
-	t.MaxIdleConns = 100
-	t.MaxConnsPerHost = 100
-	t.MaxIdleConnsPerHost = 100
+	t.MaxIdleConns = config.MaxIdleConns
+	t.MaxIdleConnsPerHost = config.MaxIdleConnsPerHost
+	t.MaxConnsPerHost = config.MaxConnsPerHost
+	t.IdleConnTimeout = config.IdleConnTimeout
 	return t
 }
 
+// DefaultTimeout is the time limit for requests made by a
+// Client that does not configure a timeout.
+const DefaultTimeout = 30 * time.Second
+
 func defaultHTTP() *http.Client {
+	return newHTTP(DefaultTimeout, DefaultTransportConfig)
+}
+
+func newHTTP(timeout time.Duration, config TransportConfig) *http.Client {
 	return &http.Client{
-		Transport: defaultTransport(),
-		Timeout:   30 * time.Second,
+		Transport: newTransport(config),
+		Timeout:   timeout,
 	}
 }
@@ -0,0 +1,79 @@
+package nws
+
+import (
+	"fmt"
+	"sync"
+)
+
+// Endpoints of the NWS API recorded by a MetricsHook.
+const (
+	EndpointZones    = "zones"
+	EndpointZone     = "zone"
+	EndpointAlerts   = "alerts"
+	EndpointPoints   = "points"
+	EndpointHourly   = "hourly"
+	EndpointForecast = "forecast"
+	EndpointGridData = "griddata"
+)
+
+// OutcomeError is the outcome recorded when a request to the
+// NWS API does not receive a response.
+const OutcomeError = "error"
+
+// OutcomeOpen is the outcome recorded when a request to the NWS
+// API is not sent because its circuit in the Breaker is open.
+const OutcomeOpen = "open"
+
+// MetricsHook is the interface that wraps the ObserveCall method.
+//
+// ObserveCall is called after each request to the NWS API with the
+// endpoint requested and the outcome. The outcome is the status
+// class of the response (e.g. "2xx", "5xx"), "error" if no
+// response was received, or "open" if the request was not sent.
+type MetricsHook interface {
+	ObserveCall(endpoint string, outcome string)
+}
+
+// statusClass returns the status class of code (e.g. "4xx").
+func statusClass(code int) string {
+	return fmt.Sprintf("%dxx", code/100)
+}
+
+// CallCounter is a MetricsHook that counts calls per endpoint
+// and outcome. It is safe for concurrent use.
+type CallCounter struct {
+	mu     sync.Mutex
+	counts map[string]map[string]int
+}
+
+func (c *CallCounter) ObserveCall(endpoint string, outcome string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.counts == nil {
+		c.counts = map[string]map[string]int{}
+	}
+
+	if c.counts[endpoint] == nil {
+		c.counts[endpoint] = map[string]int{}
+	}
+
+	c.counts[endpoint][outcome]++
+}
+
+// Counts returns a copy of the call counts keyed by endpoint
+// and then outcome.
+func (c *CallCounter) Counts() map[string]map[string]int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	counts := map[string]map[string]int{}
+	for endpoint, outcomes := range c.counts {
+		counts[endpoint] = map[string]int{}
+		for outcome, n := range outcomes {
+			counts[endpoint][outcome] = n
+		}
+	}
+
+	return counts
+}
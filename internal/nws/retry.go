@@ -0,0 +1,77 @@
+package nws
+
+import (
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultRetryMax is the most retries a RetryBudget with no
+	// Max allows at once.
+	DefaultRetryMax = 10
+
+	// DefaultRetryPer is how long a RetryBudget with no Per
+	// takes to refill.
+	DefaultRetryPer = time.Minute
+)
+
+// RetryBudget caps the retries of failed requests across all
+// concurrent callers of a Client. It is safe for concurrent use.
+//
+// RetryBudget is a token bucket. It starts full with Max tokens and
+// refills at a steady rate of Max tokens every Per. Each retry takes
+// a token. When the bucket is empty retries are refused, so during
+// a partial outage failing requests fail fast instead of multiplying
+// the load on the NWS API.
+type RetryBudget struct {
+	// The most tokens in the bucket. Zero uses DefaultRetryMax.
+	Max int
+
+	// How long an empty bucket takes to refill. Zero uses
+	// DefaultRetryPer.
+	Per time.Duration
+
+	mu     sync.Mutex
+	tokens float64
+	last   time.Time
+}
+
+func (b *RetryBudget) max() float64 {
+	if b.Max == 0 {
+		return DefaultRetryMax
+	}
+
+	return float64(b.Max)
+}
+
+func (b *RetryBudget) per() time.Duration {
+	if b.Per == 0 {
+		return DefaultRetryPer
+	}
+
+	return b.Per
+}
+
+// Allow takes a token and reports if a retry is allowed.
+func (b *RetryBudget) Allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := time.Now()
+	if b.last.IsZero() {
+		b.tokens = b.max()
+	} else {
+		b.tokens += now.Sub(b.last).Seconds() * b.max() / b.per().Seconds()
+		if b.tokens > b.max() {
+			b.tokens = b.max()
+		}
+	}
+	b.last = now
+
+	if b.tokens < 1 {
+		return false
+	}
+
+	b.tokens--
+	return true
+}
@@ -1,6 +1,7 @@
 package nws
 
 import (
+	"strings"
 	"time"
 
 	"github.com/cicconee/weather-app/internal/geometry"
@@ -15,3 +16,36 @@ type Zone struct {
 	State         string    `json:"state"`
 	Geometry      geometry.MultiPolygon
 }
+
+// ZoneURI is the type and code of a zone parsed from its URI, such
+// as "forecast" and "COZ039" from
+// https://api.weather.gov/zones/forecast/COZ039. The type is the path
+// segment of the URI, not the Type field of a Zone.
+type ZoneURI struct {
+	Type string
+	Code string
+}
+
+// String returns the URI of the zone as the NWS API lists it, such as
+// https://api.weather.gov/zones/forecast/COZ039.
+func (u ZoneURI) String() string {
+	return API + "/zones/" + u.Type + "/" + u.Code
+}
+
+// ParseZoneURI returns the type and code of a zone from its URI. The
+// String of the returned ZoneURI is the normalized URI, so a URI that
+// differs only by host, scheme or a trailing slash matches the URI of
+// the stored zone exactly. It reports false if uri is not a zone URI.
+func ParseZoneURI(uri string) (ZoneURI, bool) {
+	_, path, found := strings.Cut(uri, "/zones/")
+	if !found {
+		return ZoneURI{}, false
+	}
+
+	zoneType, code, found := strings.Cut(strings.TrimSuffix(path, "/"), "/")
+	if !found || zoneType == "" || code == "" || strings.Contains(code, "/") {
+		return ZoneURI{}, false
+	}
+
+	return ZoneURI{Type: zoneType, Code: code}, true
+}
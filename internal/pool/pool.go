@@ -1,8 +1,11 @@
 package pool
 
+import "sync/atomic"
+
 type Pool struct {
 	workers int
 	jobCh   chan func()
+	started atomic.Bool
 }
 
 func New(workerCount int, jobChanSize int) *Pool {
@@ -12,7 +15,14 @@ func New(workerCount int, jobChanSize int) *Pool {
 	}
 }
 
+// Start spawns the workers of the pool. It must be called
+// before Add or TryAdd. Calling Start more than once is a
+// no-op.
 func (p *Pool) Start() {
+	if p.started.Swap(true) {
+		return
+	}
+
 	for i := 0; i < p.workers; i++ {
 		go func() {
 			for job := range p.jobCh {
@@ -22,6 +32,34 @@ func (p *Pool) Start() {
 	}
 }
 
+// Started reports if Start has been called.
+func (p *Pool) Started() bool {
+	return p.started.Load()
+}
+
+// Add adds f to the pool, waiting for room if the pool is
+// full. It panics if the pool has not been started, since
+// nothing would ever run f.
 func (p *Pool) Add(f func()) {
+	p.mustStarted()
 	p.jobCh <- f
 }
+
+// TryAdd adds f to the pool if there is room for it without
+// waiting. It reports if f was added. It panics if the pool
+// has not been started.
+func (p *Pool) TryAdd(f func()) bool {
+	p.mustStarted()
+	select {
+	case p.jobCh <- f:
+		return true
+	default:
+		return false
+	}
+}
+
+func (p *Pool) mustStarted() {
+	if !p.started.Load() {
+		panic("pool: job added before Start was called")
+	}
+}
@@ -0,0 +1,80 @@
+package server
+
+import (
+	"context"
+	"log"
+	"net/http"
+
+	"github.com/cicconee/weather-app/internal/admin"
+	"github.com/go-chi/chi/v5"
+)
+
+// statusRecorder is a http.ResponseWriter that records the
+// status code written to it.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (s *statusRecorder) WriteHeader(code int) {
+	s.status = code
+	s.ResponseWriter.WriteHeader(code)
+}
+
+// Auditor is a middleware that is wrapped around mutating admin
+// paths. It must be wrapped inside AdminValidater.Validate so the
+// request context holds the admin id.
+type Auditor struct {
+	admins *admin.Service
+	logger *log.Logger
+}
+
+// Audit records the action performed by the validated admin after
+// next has run. The target func extracts what the action was
+// performed on from the request. The result is determined by the
+// status code next responds with.
+func (a *Auditor) Audit(action string, target func(*http.Request) string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		next(rec, r)
+
+		entry := admin.AuditEntry{
+			AdminID: adminID(r.Context()),
+			Action:  action,
+			Target:  target(r),
+			Result:  admin.AuditSuccess,
+			Status:  rec.status,
+		}
+		if rec.status >= http.StatusBadRequest {
+			entry.Result = admin.AuditFailure
+		}
+
+		// The request context may be canceled once the response is
+		// written, the audit entry should still be recorded.
+		if err := a.admins.Audit(context.Background(), entry); err != nil {
+			a.logger.Printf("%s %s Auditor.Audit: failed to record audit entry: %v\n", r.Method, r.URL.Path, err)
+		}
+	}
+}
+
+// queryTarget returns a target func that extracts the query
+// parameter key.
+func queryTarget(key string) func(*http.Request) string {
+	return func(r *http.Request) string {
+		return r.URL.Query().Get(key)
+	}
+}
+
+// urlTarget returns a target func that extracts the URL
+// parameter key.
+func urlTarget(key string) func(*http.Request) string {
+	return func(r *http.Request) string {
+		return chi.URLParam(r, key)
+	}
+}
+
+// noTarget is a target func for actions that are not
+// performed on a specific resource.
+func noTarget(*http.Request) string {
+	return ""
+}
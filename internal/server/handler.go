@@ -2,24 +2,36 @@ package server
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"log"
 	"net/http"
-	"time"
+	"strings"
 
 	"github.com/cicconee/weather-app/internal/admin"
 	"github.com/cicconee/weather-app/internal/alert"
 	"github.com/cicconee/weather-app/internal/app"
+	"github.com/cicconee/weather-app/internal/database"
 	"github.com/cicconee/weather-app/internal/forecast"
+	"github.com/cicconee/weather-app/internal/geometry"
+	"github.com/cicconee/weather-app/internal/nws"
 	"github.com/cicconee/weather-app/internal/state"
+	"github.com/go-chi/chi/v5"
 )
 
 type Handler struct {
-	logger    *log.Logger
-	states    *state.Service
-	alerts    *alert.Service
-	forecasts *forecast.Service
-	admins    *admin.Service
+	logger         *log.Logger
+	secureCookie   bool
+	exposeUpstream bool
+	states         *state.Service
+	alerts         *alert.Service
+	forecasts      *forecast.Service
+	admins         *admin.Service
+	metrics        *nws.CallCounter
+	freshness      *forecast.FreshnessGauge
+	resetter       *database.Resetter
+	worker         *worker
 }
 
 func NewHandler(l *log.Logger) *Handler {
@@ -28,8 +40,13 @@ func NewHandler(l *log.Logger) *Handler {
 	}
 }
 
+// NewLogWriter returns a LogWriter for the request. If the handler exposes
+// upstream errors and the request was made by a validated admin, the
+// LogWriter will include NWS API errors in its error responses.
 func (h *Handler) NewLogWriter(w http.ResponseWriter, r *http.Request) *LogWriter {
-	return NewLogWriter(h.logger, w, r)
+	lw := NewLogWriter(h.logger, w, r)
+	lw.upstream = h.exposeUpstream && adminID(r.Context()) != 0
+	return lw
 }
 
 func (h *Handler) HelloWorld() http.HandlerFunc {
@@ -45,13 +62,19 @@ func (h *Handler) HelloWorld() http.HandlerFunc {
 	}
 }
 
+// HandleCreateState is the handler for POST /admins/states. The "q" query
+// parameter is the state id. If any zones of the state failed to save the
+// response is a 207 with "partial" set to true, and the failed zones are
+// listed in "fails".
 func (h *Handler) HandleCreateState() http.HandlerFunc {
 	type res struct {
 		State       string                  `json:"state"`
 		TotalZones  int                     `json:"total_zones"`
 		TotalWrites int                     `json:"total_writes"`
+		TotalFails  int                     `json:"total_fails"`
+		Partial     bool                    `json:"partial"`
 		Fails       []state.SaveZoneFailure `json:"fails"`
-		CreatedAt   time.Time               `json:"created_at"`
+		CreatedAt   app.Time                `json:"created_at"`
 	}
 
 	return func(w http.ResponseWriter, r *http.Request) {
@@ -66,14 +89,21 @@ func (h *Handler) HandleCreateState() http.HandlerFunc {
 			return
 		}
 
+		status := http.StatusOK
+		if result.IsPartial() {
+			status = http.StatusMultiStatus
+		}
+
 		writer.Write(Response{
-			Status: http.StatusOK,
+			Status: status,
 			Body: res{
 				State:       result.State,
 				TotalZones:  result.TotalZones(),
 				TotalWrites: len(result.Writes),
+				TotalFails:  len(result.Fails),
+				Partial:     result.IsPartial(),
 				Fails:       result.Fails,
-				CreatedAt:   result.CreatedAt,
+				CreatedAt:   app.Time(result.CreatedAt),
 			},
 		})
 	}
@@ -85,8 +115,9 @@ func (h *Handler) HandleSyncState() http.HandlerFunc {
 		TotalInserts int                     `json:"total_inserts"`
 		TotalUpdates int                     `json:"total_updates"`
 		TotalDeletes int                     `json:"total_deletes"`
+		Changes      []state.ZoneChange      `json:"changes"`
 		Fails        []state.SyncZoneFailure `json:"fails"`
-		UpdatedAt    time.Time               `json:"updated_at"`
+		UpdatedAt    app.Time                `json:"updated_at"`
 	}
 
 	return func(w http.ResponseWriter, r *http.Request) {
@@ -108,36 +139,84 @@ func (h *Handler) HandleSyncState() http.HandlerFunc {
 				TotalInserts: len(result.Inserts),
 				TotalUpdates: len(result.Updates),
 				TotalDeletes: len(result.Deletes),
+				Changes:      result.Changes,
 				Fails:        result.Fails,
-				UpdatedAt:    result.UpdatedAt,
+				UpdatedAt:    app.Time(result.UpdatedAt),
 			},
 		})
 	}
 }
 
-func (h *Handler) HandleGetAlerts() http.HandlerFunc {
+// HandleRetryZones is the handler for POST /admins/states/retry. The "q" query
+// parameter is the state id. The handler expects the body to be in JSON format
+// with a "uris" attribute holding the URIs of the zones to retry, such as the
+// failed zones of a state save or sync. Only those zones are fetched and written.
+func (h *Handler) HandleRetryZones() http.HandlerFunc {
+	type req struct {
+		URIs []string `json:"uris"`
+	}
+
 	type res struct {
-		Lon    float64          `json:"lon"`
-		Lat    float64          `json:"lat"`
-		Alerts []alert.Response `json:"alerts"`
+		State        string                  `json:"state"`
+		TotalInserts int                     `json:"total_inserts"`
+		TotalUpdates int                     `json:"total_updates"`
+		Fails        []state.SyncZoneFailure `json:"fails"`
+		UpdatedAt    app.Time                `json:"updated_at"`
 	}
 
 	return func(w http.ResponseWriter, r *http.Request) {
-		ctx := r.Context()
-		lon := r.URL.Query().Get("lon")
-		lat := r.URL.Query().Get("lat")
+		stateID := r.URL.Query().Get("q")
 		writer := h.NewLogWriter(w, r)
 
-		point, err := ParsePoint(lon, lat)
+		var body req
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			appErr := &app.ServerResponseError{
+				Err:        fmt.Errorf("HandleRetryZones: Decoding request body: %w", err),
+				Msg:        "Invalid request body",
+				StatusCode: http.StatusBadRequest,
+			}
+
+			h.logger.Println(appErr.Err)
+			writer.WriteError(appErr)
+			return
+		}
+
+		result, err := h.states.RetryZones(r.Context(), stateID, body.URIs)
 		if err != nil {
-			h.logger.Printf("HandleGetAlerts: failed to extract point (lon=%q, lat=%q): %v", lon, lat, err)
+			h.logger.Printf("HandleRetryZones: failed to retry zones (stateID=%q): %v", stateID, err)
 			writer.WriteError(err)
 			return
 		}
 
-		alerts, err := h.alerts.Get(ctx, point)
+		writer.Write(Response{
+			Status: http.StatusOK,
+			Body: res{
+				State:        result.State,
+				TotalInserts: len(result.Inserts),
+				TotalUpdates: len(result.Updates),
+				Fails:        result.Fails,
+				UpdatedAt:    app.Time(result.UpdatedAt),
+			},
+		})
+	}
+}
+
+// HandleGetGeometryAudit is the handler for GET /admins/states/geometry. The
+// "q" query parameter is the state id. It responds with the zones of the state
+// that have missing or invalid geometry.
+func (h *Handler) HandleGetGeometryAudit() http.HandlerFunc {
+	type res struct {
+		State  string                        `json:"state"`
+		Issues state.GeometryIssueCollection `json:"issues"`
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		stateID := r.URL.Query().Get("q")
+		writer := h.NewLogWriter(w, r)
+
+		issues, err := h.states.AuditGeometry(r.Context(), stateID)
 		if err != nil {
-			h.logger.Printf("HandleGetAlerts: failed to get alerts (point=%v): %v", point, err)
+			h.logger.Printf("HandleGetGeometryAudit: failed to audit geometry (stateID=%q): %v", stateID, err)
 			writer.WriteError(err)
 			return
 		}
@@ -145,163 +224,1563 @@ func (h *Handler) HandleGetAlerts() http.HandlerFunc {
 		writer.Write(Response{
 			Status: http.StatusOK,
 			Body: res{
-				Lon:    point.Lon(),
-				Lat:    point.Lat(),
-				Alerts: alerts,
+				State:  strings.ToUpper(stateID),
+				Issues: issues,
 			},
 		})
 	}
 }
 
-func (h *Handler) HandleGetForecast() http.HandlerFunc {
+// HandleSearchZones is the handler for GET /admins/states/{state}/zones. It
+// responds with a page of the stored zones of the state whose name or code
+// contains the "q" query parameter. Without "q" every zone of the state is
+// listed. The "effectiveBefore" and "effectiveAfter" query parameters are
+// optional RFC 3339 times that limit the zones to those with an effective
+// date before or after them.
+func (h *Handler) HandleSearchZones() http.HandlerFunc {
 	type res struct {
-		Lon      float64                   `json:"lon"`
-		Lat      float64                   `json:"lat"`
-		Forecast forecast.PeriodCollection `json:"forecast"`
+		State string `json:"state"`
+		Query string `json:"q"`
+		PageMeta
+		Zones state.ZoneMatchCollection `json:"zones"`
 	}
 
 	return func(w http.ResponseWriter, r *http.Request) {
-		ctx := r.Context()
-		lon := r.URL.Query().Get("lon")
-		lat := r.URL.Query().Get("lat")
+		stateID := strings.ToUpper(chi.URLParam(r, "state"))
+		query := r.URL.Query()
+		q := query.Get("q")
 		writer := h.NewLogWriter(w, r)
 
-		point, err := ParsePoint(lon, lat)
+		before, err := ParseTime("effectiveBefore", query.Get("effectiveBefore"))
 		if err != nil {
-			h.logger.Printf("HandleGetForecast: extracting point (lon=%q, lat=%q): %v\n", lon, lat, err)
+			h.logger.Printf("HandleSearchZones: failed to extract effectiveBefore: %v", err)
 			writer.WriteError(err)
 			return
 		}
 
-		periods, err := h.forecasts.Get(ctx, point)
+		after, err := ParseTime("effectiveAfter", query.Get("effectiveAfter"))
 		if err != nil {
-			h.logger.Printf("HandleGetForecast: getting forecast (point=%v): %v\n", point, err)
+			h.logger.Printf("HandleSearchZones: failed to extract effectiveAfter: %v", err)
+			writer.WriteError(err)
+			return
+		}
+
+		page, err := ParsePage(query.Get("limit"), query.Get("offset"))
+		if err != nil {
+			h.logger.Printf("HandleSearchZones: failed to extract page: %v", err)
+			writer.WriteError(err)
+			return
+		}
+		page = ClampPage(page, state.DefaultZoneSearchLimit, state.MaxZoneSearchLimit)
+
+		filter := state.ZoneFilter{
+			Query:           q,
+			EffectiveBefore: before,
+			EffectiveAfter:  after,
+		}
+		search, err := h.states.SearchZones(r.Context(), stateID, filter, page.Limit, page.Offset)
+		if err != nil {
+			h.logger.Printf("HandleSearchZones: failed to search zones (stateID=%q, q=%q): %v", stateID, q, err)
 			writer.WriteError(err)
 			return
 		}
 
+		meta := NewPageMeta(search.Total, page)
 		writer.Write(Response{
 			Status: http.StatusOK,
 			Body: res{
-				Lon:      point.RoundedLon(),
-				Lat:      point.RoundedLat(),
-				Forecast: periods,
+				State:    stateID,
+				Query:    q,
+				PageMeta: meta,
+				Zones:    search.Zones,
 			},
+			Page: &meta,
 		})
 	}
 }
 
-// HandlePostLogin is the handler for POST /admins/login. The handler expects
-// the body to be in JSON format.
-//
-// The "username" attribute needs a string value and should be the username of
-// the user logging in. The "password" attribute needs a string value and should
-// be the password of the user logging in. Password should be the raw value, not
-// the hashed value.
-//
-// Upon success the admin token will be stored as an http only cookie.
-func (h *Handler) HandlePostLogin() http.HandlerFunc {
-	type req struct {
-		Username string `json:"username"`
-		Password string `json:"password"`
+// HandleGetStateConsistency is the handler for GET
+// /admins/states/{state}/consistency. It responds with the total zones of the
+// state and the zones stored for it. When they differ the state was partially
+// saved or synced and a resync is suggested.
+func (h *Handler) HandleGetStateConsistency() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		stateID := chi.URLParam(r, "state")
+		writer := h.NewLogWriter(w, r)
+
+		consistency, err := h.states.Consistency(r.Context(), stateID)
+		if err != nil {
+			h.logger.Printf("HandleGetStateConsistency: failed to check consistency (stateID=%q): %v", stateID, err)
+			writer.WriteError(err)
+			return
+		}
+
+		writer.Write(Response{
+			Status: http.StatusOK,
+			Body:   consistency,
+		})
 	}
+}
 
+// HandleSetStatePriority is the handler for PUT /admins/states/priority. The
+// "q" query parameter is the state id. The "priority" query parameter is
+// "true" to mark the state as a priority state or "false" to remove the mark.
+// When any states are marked, alerts are only synced for the priority states.
+func (h *Handler) HandleSetStatePriority() http.HandlerFunc {
 	type res struct {
-		Msg   string `json:"msg"`
-		Token string `json:"token"`
+		State    string `json:"state"`
+		Priority bool   `json:"priority"`
 	}
 
 	return func(w http.ResponseWriter, r *http.Request) {
+		stateID := r.URL.Query().Get("q")
 		writer := h.NewLogWriter(w, r)
-		ctx := r.Context()
 
-		var body req
-		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
-			appErr := &app.ServerResponseError{
-				Err:        fmt.Errorf("HandlePostLogin: Decoding request body: %w", err),
-				Msg:        "Invalid request body",
-				StatusCode: http.StatusBadRequest,
-			}
+		priority, err := ParseBool("priority", r.URL.Query().Get("priority"))
+		if err != nil {
+			h.logger.Printf("HandleSetStatePriority: extracting priority: %v", err)
+			writer.WriteError(err)
+			return
+		}
 
-			h.logger.Println(appErr.Err)
-			writer.WriteError(appErr)
+		if err := h.states.SetPriority(r.Context(), stateID, priority); err != nil {
+			h.logger.Printf("HandleSetStatePriority: failed to set priority (stateID=%q, priority=%t): %v", stateID, priority, err)
+			writer.WriteError(err)
 			return
 		}
 
-		token, err := h.admins.Login(ctx, body.Username, body.Password)
+		writer.Write(Response{
+			Status: http.StatusOK,
+			Body: res{
+				State:    strings.ToUpper(stateID),
+				Priority: priority,
+			},
+		})
+	}
+}
+
+// HandleGetAlertCount is the handler for GET /alerts/count. The "lon" and "lat"
+// query parameters are required. It responds with the number of alerts at the
+// point and their highest severity, without the alerts.
+func (h *Handler) HandleGetAlertCount() http.HandlerFunc {
+	type res struct {
+		Lon float64 `json:"lon"`
+		Lat float64 `json:"lat"`
+		alert.Summary
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		lon := r.URL.Query().Get("lon")
+		lat := r.URL.Query().Get("lat")
+		writer := h.NewLogWriter(w, r)
+
+		point, err := ParsePoint(lon, lat)
 		if err != nil {
-			err = fmt.Errorf("HandlePostLogin: Logging in user (username=%q): %w", body.Username, err)
-			h.logger.Println(err)
+			h.logger.Printf("HandleGetAlertCount: failed to extract point (lon=%q, lat=%q): %v", lon, lat, err)
 			writer.WriteError(err)
 			return
 		}
 
-		http.SetCookie(w, &http.Cookie{
-			Name:     adminTokenCookieKey,
-			HttpOnly: true,
-			Value:    token,
+		summary, err := h.alerts.Count(r.Context(), point)
+		if err != nil {
+			h.logger.Printf("HandleGetAlertCount: failed to count alerts (point=%v): %v", point, err)
+			writer.WriteError(err)
+			return
+		}
+
+		writer.Write(Response{
+			Status: http.StatusOK,
+			Body: res{
+				Lon:     point.Lon(),
+				Lat:     point.Lat(),
+				Summary: summary,
+			},
 		})
+	}
+}
+
+// HandleGetAlerts is the handler for GET /alerts. The "lon" and "lat" query
+// parameters are required. It responds with a page of the alerts at the
+// point. If the client accepts CAPMediaType, the page of alerts is written
+// as CAP 1.2 XML instead of JSON.
+func (h *Handler) HandleGetAlerts() http.HandlerFunc {
+	type res struct {
+		Lon float64 `json:"lon"`
+		Lat float64 `json:"lat"`
+		PageMeta
+		Alerts []alert.Response `json:"alerts"`
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		lon := r.URL.Query().Get("lon")
+		lat := r.URL.Query().Get("lat")
+		writer := h.NewLogWriter(w, r)
+
+		point, err := ParsePoint(lon, lat)
+		if err != nil {
+			h.logger.Printf("HandleGetAlerts: failed to extract point (lon=%q, lat=%q): %v", lon, lat, err)
+			writer.WriteError(err)
+			return
+		}
+
+		page, err := ParsePage(r.URL.Query().Get("limit"), r.URL.Query().Get("offset"))
+		if err != nil {
+			h.logger.Printf("HandleGetAlerts: failed to extract page: %v", err)
+			writer.WriteError(err)
+			return
+		}
+
+		loc, err := ParseTimeZone(r.URL.Query().Get("tz"))
+		if err != nil {
+			h.logger.Printf("HandleGetAlerts: failed to extract tz: %v", err)
+			writer.WriteError(err)
+			return
+		}
 
+		list, err := h.alerts.Get(ctx, point, page)
+		if err != nil {
+			h.logger.Printf("HandleGetAlerts: failed to get alerts (point=%v): %v", point, err)
+			writer.WriteError(err)
+			return
+		}
+
+		for i, a := range list.Alerts {
+			list.Alerts[i] = a.In(loc)
+		}
+
+		if writer.accepts(CAPMediaType) {
+			writer.WriteXML(Response{
+				Status: http.StatusOK,
+				Body:   alert.CAPCollectionFrom(list.Alerts),
+			}, CAPMediaType)
+			return
+		}
+
+		meta := NewPageMeta(list.Total, list.Page)
 		writer.Write(Response{
 			Status: http.StatusOK,
 			Body: res{
-				Msg:   "Success",
-				Token: token,
+				Lon:      point.Lon(),
+				Lat:      point.Lat(),
+				PageMeta: meta,
+				Alerts:   list.Alerts,
 			},
+			Page: &meta,
 		})
 	}
 }
 
-// HandlePostSignup is the handler for POST /admins/signup. The handler expects
-// the body to be in JSON format.
-//
-// The "username" attribute needs a string value and should be the desired username
-// of the user signing up. The "password" attribute needs a string value and should
-// be the password of the user signing up. Password should be the raw value, not
-// the hashed value.
-//
-// Upon success the admin will be stored as a unapproved admin. They will need to
-// be approved in order to login.
-func (h *Handler) HandlePostSignup() http.HandlerFunc {
-	type req struct {
-		Username string `json:"username"`
-		Password string `json:"password"`
+// HandleGetAlertFeed is the handler for GET /alerts/feed. The "lon" and "lat"
+// query parameters are required. It responds with a Atom feed of the alerts
+// at the point, so users can subscribe to the point in a feed reader.
+func (h *Handler) HandleGetAlertFeed() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		lon := r.URL.Query().Get("lon")
+		lat := r.URL.Query().Get("lat")
+		writer := h.NewLogWriter(w, r)
+
+		point, err := ParsePoint(lon, lat)
+		if err != nil {
+			h.logger.Printf("HandleGetAlertFeed: failed to extract point (lon=%q, lat=%q): %v", lon, lat, err)
+			writer.WriteError(err)
+			return
+		}
+
+		list, err := h.alerts.Get(r.Context(), point, alert.Page{Limit: alert.MaxLimit})
+		if err != nil {
+			h.logger.Printf("HandleGetAlertFeed: failed to get alerts (point=%v): %v", point, err)
+			writer.WriteError(err)
+			return
+		}
+
+		writer.WriteXML(Response{
+			Status: http.StatusOK,
+			Body:   alert.FeedFrom(point, list.Alerts, app.Now(h.alerts.Clock)),
+		}, AtomMediaType)
 	}
+}
 
+// HandleGetBBoxAlerts is the handler for GET /alerts/bbox. It responds with a
+// page of the active alerts that overlap the bounding box of the "minlon",
+// "minlat", "maxlon" and "maxlat" query parameters, so a map can show every
+// alert in view.
+func (h *Handler) HandleGetBBoxAlerts() http.HandlerFunc {
 	type res struct {
-		Msg string `json:"msg"`
+		MinLon float64 `json:"minlon"`
+		MinLat float64 `json:"minlat"`
+		MaxLon float64 `json:"maxlon"`
+		MaxLat float64 `json:"maxlat"`
+		PageMeta
+		Alerts []alert.Response `json:"alerts"`
 	}
 
 	return func(w http.ResponseWriter, r *http.Request) {
-		writer := h.NewLogWriter(w, r)
 		ctx := r.Context()
+		query := r.URL.Query()
+		writer := h.NewLogWriter(w, r)
 
-		var body req
-		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
-			appErr := &app.ServerResponseError{
-				Err:        fmt.Errorf("HandlePostSignup: Decoding request body: %w", err),
-				Msg:        "Invalid request body",
-				StatusCode: http.StatusBadRequest,
-			}
+		bbox, err := ParseBBoxParams(query.Get("minlon"), query.Get("minlat"), query.Get("maxlon"), query.Get("maxlat"))
+		if err != nil {
+			h.logger.Printf("HandleGetBBoxAlerts: failed to extract bbox: %v", err)
+			writer.WriteError(err)
+			return
+		}
 
-			h.logger.Println(appErr.Err)
-			writer.WriteError(appErr)
+		page, err := ParsePage(query.Get("limit"), query.Get("offset"))
+		if err != nil {
+			h.logger.Printf("HandleGetBBoxAlerts: failed to extract page: %v", err)
+			writer.WriteError(err)
 			return
 		}
 
-		err := h.admins.Signup(ctx, body.Username, body.Password)
+		list, err := h.alerts.GetInBBox(ctx, bbox, page)
 		if err != nil {
-			err = fmt.Errorf("HandlePostSignup: Signing up user (username=%q): %w", body.Username, err)
-			h.logger.Println(err)
+			h.logger.Printf("HandleGetBBoxAlerts: failed to get alerts (bbox=%+v): %v", bbox, err)
 			writer.WriteError(err)
 			return
 		}
 
+		meta := NewPageMeta(list.Total, list.Page)
 		writer.Write(Response{
 			Status: http.StatusOK,
 			Body: res{
-				Msg: "Success",
+				MinLon:   bbox.MinLon,
+				MinLat:   bbox.MinLat,
+				MaxLon:   bbox.MaxLon,
+				MaxLat:   bbox.MaxLat,
+				PageMeta: meta,
+				Alerts:   list.Alerts,
 			},
+			Page: &meta,
+		})
+	}
+}
+
+// HandleGetAlertsGeoJSON is the handler for GET /alerts/geojson. It responds
+// with the active alerts as a GeoJSON FeatureCollection. The optional "state"
+// query parameter limits the alerts to a state, and the optional "bbox" query
+// parameter, "minlon,minlat,maxlon,maxlat", to a bounding box. The geometry
+// of each alert is clipped to the bounding box.
+//
+// The features are streamed as they are read, so the body is never wrapped in
+// a Envelope. If reading the alerts fails after the first feature is written
+// the response is cut short.
+func (h *Handler) HandleGetAlertsGeoJSON() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		bboxStr := r.URL.Query().Get("bbox")
+		writer := h.NewLogWriter(w, r)
+
+		bbox, err := ParseBBox(bboxStr)
+		if err != nil {
+			h.logger.Printf("HandleGetAlertsGeoJSON: failed to extract bbox (bbox=%q): %v", bboxStr, err)
+			writer.WriteError(err)
+			return
+		}
+
+		scope := alert.FeatureScope{
+			StateID: r.URL.Query().Get("state"),
+			BBox:    bbox,
+		}
+		fw := &featureWriter{rw: w}
+		err = h.alerts.EachFeature(r.Context(), scope, fw.Write)
+		if err == nil {
+			err = fw.Close()
+		}
+		if err != nil {
+			h.logger.Printf("HandleGetAlertsGeoJSON: failed to write features (state=%q, bbox=%q): %v", scope.StateID, bboxStr, err)
+			if !fw.started {
+				writer.WriteError(err)
+			}
+		}
+	}
+}
+
+// HandleGetNearbyAlerts is the handler for GET /alerts/nearby. It responds
+// with the active alerts within "radius" meters of the "lon" and "lat" query
+// parameters, ordered closest first.
+func (h *Handler) HandleGetNearbyAlerts() http.HandlerFunc {
+	type res struct {
+		Lon    float64                `json:"lon"`
+		Lat    float64                `json:"lat"`
+		Radius float64                `json:"radius"`
+		Alerts []alert.NearbyResponse `json:"alerts"`
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		lon := r.URL.Query().Get("lon")
+		lat := r.URL.Query().Get("lat")
+		radiusStr := r.URL.Query().Get("radius")
+		writer := h.NewLogWriter(w, r)
+
+		point, err := ParsePoint(lon, lat)
+		if err != nil {
+			h.logger.Printf("HandleGetNearbyAlerts: failed to extract point (lon=%q, lat=%q): %v", lon, lat, err)
+			writer.WriteError(err)
+			return
+		}
+
+		radius, err := ParseRadius(radiusStr)
+		if err != nil {
+			h.logger.Printf("HandleGetNearbyAlerts: failed to extract radius (radius=%q): %v", radiusStr, err)
+			writer.WriteError(err)
+			return
+		}
+
+		alerts, err := h.alerts.Nearest(ctx, point, radius)
+		if err != nil {
+			h.logger.Printf("HandleGetNearbyAlerts: failed to get alerts (point=%v, radius=%f): %v", point, radius, err)
+			writer.WriteError(err)
+			return
+		}
+
+		writer.Write(Response{
+			Status: http.StatusOK,
+			Body: res{
+				Lon:    point.Lon(),
+				Lat:    point.Lat(),
+				Radius: radius,
+				Alerts: alerts,
+			},
+		})
+	}
+}
+
+// HandleGetAlert is the handler for GET /alerts/{id}. It responds with
+// the active alert with the id. The geometry of the alert, or of the zones
+// it is mapped to, is only included when the "geometry" query parameter is
+// "true".
+func (h *Handler) HandleGetAlert() http.HandlerFunc {
+	type res struct {
+		Alert    alert.Response    `json:"alert"`
+		Geometry *geometry.GeoJSON `json:"geometry,omitempty"`
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := chi.URLParam(r, "id")
+		geometryStr := r.URL.Query().Get("geometry")
+		writer := h.NewLogWriter(w, r)
+
+		withGeometry := false
+		if geometryStr != "" {
+			var err error
+			withGeometry, err = ParseBool("geometry", geometryStr)
+			if err != nil {
+				h.logger.Printf("HandleGetAlert: failed to extract geometry (geometry=%q): %v", geometryStr, err)
+				writer.WriteError(err)
+				return
+			}
+		}
+
+		feature, err := h.alerts.GetByID(r.Context(), id)
+		if err != nil {
+			h.logger.Printf("HandleGetAlert: failed to get alert (id=%q): %v", id, err)
+			writer.WriteError(err)
+			return
+		}
+
+		body := res{Alert: feature.AsResponse()}
+		if withGeometry {
+			body.Geometry = feature.GeoJSONGeometry()
+		}
+
+		writer.Write(Response{
+			Status: http.StatusOK,
+			Body:   body,
+		})
+	}
+}
+
+// HandleGetStateAlerts is the handler for GET /alerts/state/{state}. It
+// responds with all the active alerts for the state.
+func (h *Handler) HandleGetStateAlerts() http.HandlerFunc {
+	type res struct {
+		State string `json:"state"`
+		PageMeta
+		Alerts []alert.Response `json:"alerts"`
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		stateID := strings.ToUpper(chi.URLParam(r, "state"))
+		writer := h.NewLogWriter(w, r)
+
+		page, err := ParsePage(r.URL.Query().Get("limit"), r.URL.Query().Get("offset"))
+		if err != nil {
+			h.logger.Printf("HandleGetStateAlerts: failed to extract page: %v", err)
+			writer.WriteError(err)
+			return
+		}
+
+		list, err := h.alerts.GetByState(ctx, stateID, page)
+		if err != nil {
+			h.logger.Printf("HandleGetStateAlerts: failed to get alerts (stateID=%q): %v", stateID, err)
+			writer.WriteError(err)
+			return
+		}
+
+		meta := NewPageMeta(list.Total, list.Page)
+		writer.Write(Response{
+			Status: http.StatusOK,
+			Body: res{
+				State:    stateID,
+				PageMeta: meta,
+				Alerts:   list.Alerts,
+			},
+			Page: &meta,
+		})
+	}
+}
+
+// HandleSearchAlerts is the handler for GET /alerts/search. The "q" query
+// parameter is the text to search alerts for.
+func (h *Handler) HandleSearchAlerts() http.HandlerFunc {
+	type res struct {
+		Query string `json:"query"`
+		PageMeta
+		Alerts []alert.Response `json:"alerts"`
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		q := r.URL.Query().Get("q")
+		writer := h.NewLogWriter(w, r)
+
+		page, err := ParsePage(r.URL.Query().Get("limit"), r.URL.Query().Get("offset"))
+		if err != nil {
+			h.logger.Printf("HandleSearchAlerts: failed to extract page: %v", err)
+			writer.WriteError(err)
+			return
+		}
+
+		list, err := h.alerts.Search(ctx, q, page)
+		if err != nil {
+			h.logger.Printf("HandleSearchAlerts: failed to search alerts (q=%q): %v", q, err)
+			writer.WriteError(err)
+			return
+		}
+
+		meta := NewPageMeta(list.Total, list.Page)
+		writer.Write(Response{
+			Status: http.StatusOK,
+			Body: res{
+				Query:    q,
+				PageMeta: meta,
+				Alerts:   list.Alerts,
+			},
+			Page: &meta,
+		})
+	}
+}
+
+// HandleGetForecast is the handler for GET /forecasts. The "lon" and "lat"
+// query parameters are required. A request with the no-cache directive in
+// its Cache-Control header, such as when a user explicitly refreshes, updates
+// a stored forecast from the NWS API before responding. Each gridpoint is
+// only updated early at most once per forecast.Service.ForceRefreshInterval.
+// The "source" field tells if the forecast was just fetched, served from
+// the cache, or served stale after it expired.
+func (h *Handler) HandleGetForecast() http.HandlerFunc {
+	type res struct {
+		Lon        float64                    `json:"lon"`
+		Lat        float64                    `json:"lat"`
+		ValidUntil app.Time                   `json:"validUntil"`
+		Elevation  *float64                   `json:"elevation_meters,omitempty"`
+		Relative   *forecast.RelativeLocation `json:"relative_location,omitempty"`
+		TwelveHour bool                       `json:"twelve_hour"`
+		Source     string                     `json:"source"`
+		Forecast   forecast.PeriodCollection  `json:"forecast"`
+		Sun        []forecast.SunDay          `json:"sun,omitempty"`
+		SkyCover   []forecast.HourlyValue     `json:"sky_cover,omitempty"`
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		lon := r.URL.Query().Get("lon")
+		lat := r.URL.Query().Get("lat")
+		writer := h.NewLogWriter(w, r)
+
+		point, err := ParsePoint(lon, lat)
+		if err != nil {
+			h.logger.Printf("HandleGetForecast: extracting point (lon=%q, lat=%q): %v\n", lon, lat, err)
+			writer.WriteError(err)
+			return
+		}
+
+		maxPeriods, err := ParseMaxPeriods(r.URL.Query().Get("maxPeriods"))
+		if err != nil {
+			h.logger.Printf("HandleGetForecast: extracting maxPeriods: %v\n", err)
+			writer.WriteError(err)
+			return
+		}
+
+		get := h.forecasts.Get
+		if noCache(r) {
+			get = h.forecasts.GetFresh
+		}
+
+		fc, err := get(ctx, point, maxPeriods)
+		if err != nil {
+			h.logger.Printf("HandleGetForecast: getting forecast (point=%v): %v\n", point, err)
+			writer.WriteError(err)
+			return
+		}
+
+		writer.Write(Response{
+			Status: http.StatusOK,
+			Body: res{
+				Lon:        fc.Point.Lon(),
+				Lat:        fc.Point.Lat(),
+				ValidUntil: app.Time(fc.ValidUntil),
+				Elevation:  fc.Elevation,
+				Relative:   fc.RelativeLocation,
+				TwelveHour: fc.TwelveHour,
+				Source:     fc.Source,
+				Forecast:   fc.Periods,
+				Sun:        fc.Sun,
+				SkyCover:   fc.SkyCover,
+			},
+		})
+	}
+}
+
+// HandleGetCurrentForecast is the handler for GET /forecasts/now. The "lon"
+// and "lat" query parameters are required. It responds with only the period
+// of the forecast that covers the current time, or the next period to start
+// if none does.
+func (h *Handler) HandleGetCurrentForecast() http.HandlerFunc {
+	type res struct {
+		Lon        float64                    `json:"lon"`
+		Lat        float64                    `json:"lat"`
+		ValidUntil app.Time                   `json:"validUntil"`
+		Relative   *forecast.RelativeLocation `json:"relative_location,omitempty"`
+		TwelveHour bool                       `json:"twelve_hour"`
+		Source     string                     `json:"source"`
+		Period     forecast.Period            `json:"period"`
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		lon := r.URL.Query().Get("lon")
+		lat := r.URL.Query().Get("lat")
+		writer := h.NewLogWriter(w, r)
+
+		point, err := ParsePoint(lon, lat)
+		if err != nil {
+			h.logger.Printf("HandleGetCurrentForecast: extracting point (lon=%q, lat=%q): %v\n", lon, lat, err)
+			writer.WriteError(err)
+			return
+		}
+
+		fc, err := h.forecasts.GetCurrent(r.Context(), point)
+		if err != nil {
+			h.logger.Printf("HandleGetCurrentForecast: getting forecast (point=%v): %v\n", point, err)
+			writer.WriteError(err)
+			return
+		}
+
+		writer.Write(Response{
+			Status: http.StatusOK,
+			Body: res{
+				Lon:        fc.Point.Lon(),
+				Lat:        fc.Point.Lat(),
+				ValidUntil: app.Time(fc.ValidUntil),
+				Relative:   fc.RelativeLocation,
+				TwelveHour: fc.TwelveHour,
+				Source:     fc.Source,
+				Period:     fc.Periods[0],
+			},
+		})
+	}
+}
+
+// HandleGetGridForecast is the handler for GET /forecasts/grid. The "id", "x"
+// and "y" query parameters are required and are the grid identifier and grid
+// coordinates of a gridpoint, such as from a prior response. It responds with
+// the forecast of the gridpoint if it is stored, otherwise with a 404.
+func (h *Handler) HandleGetGridForecast() http.HandlerFunc {
+	type res struct {
+		forecast.Grid
+		ValidUntil app.Time                   `json:"validUntil"`
+		Elevation  *float64                   `json:"elevation_meters,omitempty"`
+		Relative   *forecast.RelativeLocation `json:"relative_location,omitempty"`
+		TwelveHour bool                       `json:"twelve_hour"`
+		Source     string                     `json:"source"`
+		Forecast   forecast.PeriodCollection  `json:"forecast"`
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		writer := h.NewLogWriter(w, r)
+
+		grid, err := ParseGrid(query.Get("id"), query.Get("x"), query.Get("y"))
+		if err != nil {
+			h.logger.Printf("HandleGetGridForecast: extracting grid: %v\n", err)
+			writer.WriteError(err)
+			return
+		}
+
+		maxPeriods, err := ParseMaxPeriods(query.Get("maxPeriods"))
+		if err != nil {
+			h.logger.Printf("HandleGetGridForecast: extracting maxPeriods: %v\n", err)
+			writer.WriteError(err)
+			return
+		}
+
+		fc, err := h.forecasts.GetByGrid(r.Context(), grid.ID, grid.X, grid.Y, maxPeriods)
+		if err != nil {
+			h.logger.Printf("HandleGetGridForecast: getting forecast (grid=%+v): %v\n", grid, err)
+			writer.WriteError(err)
+			return
+		}
+
+		writer.Write(Response{
+			Status: http.StatusOK,
+			Body: res{
+				Grid:       grid,
+				ValidUntil: app.Time(fc.ValidUntil),
+				Elevation:  fc.Elevation,
+				Relative:   fc.RelativeLocation,
+				TwelveHour: fc.TwelveHour,
+				Source:     fc.Source,
+				Forecast:   fc.Periods,
+			},
+		})
+	}
+}
+
+// HandleGetForecastComparison is the handler for GET /forecasts/compare.
+// The "a" and "b" query parameters are required and are points in the
+// form "lon,lat". It responds with the forecast of each point and the
+// difference in temperature and condition for each hour they share.
+func (h *Handler) HandleGetForecastComparison() http.HandlerFunc {
+	type point struct {
+		Lon        float64                   `json:"lon"`
+		Lat        float64                   `json:"lat"`
+		ValidUntil app.Time                  `json:"validUntil"`
+		TwelveHour bool                      `json:"twelve_hour"`
+		Forecast   forecast.PeriodCollection `json:"forecast"`
+	}
+
+	type res struct {
+		A     point                 `json:"a"`
+		B     point                 `json:"b"`
+		Diffs []forecast.PeriodDiff `json:"diffs"`
+	}
+
+	asPoint := func(fc forecast.Forecast) point {
+		return point{
+			Lon:        fc.Point.Lon(),
+			Lat:        fc.Point.Lat(),
+			ValidUntil: app.Time(fc.ValidUntil),
+			TwelveHour: fc.TwelveHour,
+			Forecast:   fc.Periods,
+		}
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		writer := h.NewLogWriter(w, r)
+
+		a, err := ParsePointPair("a", r.URL.Query().Get("a"))
+		if err != nil {
+			h.logger.Printf("HandleGetForecastComparison: extracting a: %v\n", err)
+			writer.WriteError(err)
+			return
+		}
+
+		b, err := ParsePointPair("b", r.URL.Query().Get("b"))
+		if err != nil {
+			h.logger.Printf("HandleGetForecastComparison: extracting b: %v\n", err)
+			writer.WriteError(err)
+			return
+		}
+
+		c, err := h.forecasts.Compare(r.Context(), a, b)
+		if err != nil {
+			h.logger.Printf("HandleGetForecastComparison: comparing forecasts (a=%v, b=%v): %v\n", a, b, err)
+			writer.WriteError(err)
+			return
+		}
+
+		writer.Write(Response{
+			Status: http.StatusOK,
+			Body: res{
+				A:     asPoint(c.A),
+				B:     asPoint(c.B),
+				Diffs: c.Diffs,
+			},
+		})
+	}
+}
+
+// HandlePostLogin is the handler for POST /admins/login. The handler expects
+// the body to be in JSON format.
+//
+// The "username" attribute needs a string value and should be the username of
+// the user logging in. The "password" attribute needs a string value and should
+// be the password of the user logging in. Password should be the raw value, not
+// the hashed value.
+//
+// Upon success the admin token will be stored as an http only cookie. The
+// cookie is restricted to same site requests and expires with the token. If
+// the handler is configured with secure cookies, the cookie will only be sent
+// over HTTPS.
+func (h *Handler) HandlePostLogin() http.HandlerFunc {
+	type req struct {
+		Username string `json:"username"`
+		Password string `json:"password"`
+	}
+
+	type res struct {
+		Msg   string `json:"msg"`
+		Token string `json:"token"`
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		writer := h.NewLogWriter(w, r)
+		ctx := r.Context()
+
+		var body req
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			appErr := &app.ServerResponseError{
+				Err:        fmt.Errorf("HandlePostLogin: Decoding request body: %w", err),
+				Msg:        "Invalid request body",
+				StatusCode: http.StatusBadRequest,
+			}
+
+			h.logger.Println(appErr.Err)
+			writer.WriteError(appErr)
+			return
+		}
+
+		token, err := h.admins.Login(ctx, body.Username, body.Password)
+		if err != nil {
+			err = fmt.Errorf("HandlePostLogin: Logging in user (username=%q): %w", body.Username, err)
+			h.logger.Println(err)
+			writer.WriteError(err)
+			return
+		}
+
+		http.SetCookie(w, &http.Cookie{
+			Name:     adminTokenCookieKey,
+			Value:    token,
+			Path:     "/",
+			MaxAge:   int(admin.TokenTTL.Seconds()),
+			HttpOnly: true,
+			Secure:   h.secureCookie,
+			SameSite: http.SameSiteStrictMode,
+		})
+
+		writer.Write(Response{
+			Status: http.StatusOK,
+			Body: res{
+				Msg:   "Success",
+				Token: token,
+			},
+		})
+	}
+}
+
+// HandlePostSignup is the handler for POST /admins/signup. The handler expects
+// the body to be in JSON format.
+//
+// The "username" attribute needs a string value and should be the desired username
+// of the user signing up. The "password" attribute needs a string value and should
+// be the password of the user signing up. Password should be the raw value, not
+// the hashed value.
+//
+// Upon success the admin will be stored as a unapproved admin. They will need to
+// be approved in order to login.
+func (h *Handler) HandlePostSignup() http.HandlerFunc {
+	type req struct {
+		Username string `json:"username"`
+		Password string `json:"password"`
+	}
+
+	type res struct {
+		Msg string `json:"msg"`
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		writer := h.NewLogWriter(w, r)
+		ctx := r.Context()
+
+		var body req
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			appErr := &app.ServerResponseError{
+				Err:        fmt.Errorf("HandlePostSignup: Decoding request body: %w", err),
+				Msg:        "Invalid request body",
+				StatusCode: http.StatusBadRequest,
+			}
+
+			h.logger.Println(appErr.Err)
+			writer.WriteError(appErr)
+			return
+		}
+
+		err := h.admins.Signup(ctx, body.Username, body.Password)
+		if err != nil {
+			err = fmt.Errorf("HandlePostSignup: Signing up user (username=%q): %w", body.Username, err)
+			h.logger.Println(err)
+			writer.WriteError(err)
+			return
+		}
+
+		writer.Write(Response{
+			Status: http.StatusOK,
+			Body: res{
+				Msg: "Success",
+			},
+		})
+	}
+}
+
+// HandleDeleteAdmin is the handler for DELETE /admins/{id}. The admin making
+// the request must be a superadmin.
+//
+// If the "soft" query parameter is "true" the admin will be deactivated rather
+// than permanently deleted. In both cases the admins tokens stop working.
+func (h *Handler) HandleDeleteAdmin() http.HandlerFunc {
+	type res struct {
+		Msg string `json:"msg"`
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		writer := h.NewLogWriter(w, r)
+		ctx := r.Context()
+		actorID := adminID(ctx)
+
+		targetID, err := ParseAdminID(chi.URLParam(r, "id"))
+		if err != nil {
+			h.logger.Printf("HandleDeleteAdmin: extracting admin id: %v", err)
+			writer.WriteError(err)
+			return
+		}
+
+		soft := r.URL.Query().Get("soft") == "true"
+		if soft {
+			err = h.admins.Deactivate(ctx, actorID, targetID)
+		} else {
+			err = h.admins.Delete(ctx, actorID, targetID)
+		}
+		if err != nil {
+			err = fmt.Errorf("HandleDeleteAdmin: Deleting admin (actorID=%d, targetID=%d, soft=%t): %w", actorID, targetID, soft, err)
+			h.logger.Println(err)
+			writer.WriteError(err)
+			return
+		}
+
+		writer.Write(Response{
+			Status: http.StatusOK,
+			Body: res{
+				Msg: "Success",
+			},
+		})
+	}
+}
+
+// HandleGetGridpoint is the handler for GET /admins/forecasts/gridpoint. The
+// "lon" and "lat" query parameters are required. It responds with the stored
+// gridpoint the point maps to, including its geometry as GeoJSON, without the
+// periods. It responds with a 404 if no stored gridpoint covers the point.
+func (h *Handler) HandleGetGridpoint() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		lon := r.URL.Query().Get("lon")
+		lat := r.URL.Query().Get("lat")
+		writer := h.NewLogWriter(w, r)
+
+		point, err := ParsePoint(lon, lat)
+		if err != nil {
+			h.logger.Printf("HandleGetGridpoint: failed to extract point (lon=%q, lat=%q): %v", lon, lat, err)
+			writer.WriteError(err)
+			return
+		}
+
+		gridpoint, err := h.forecasts.Gridpoint(r.Context(), point)
+		if err != nil {
+			h.logger.Printf("HandleGetGridpoint: failed to get gridpoint (point=%v): %v", point, err)
+			writer.WriteError(err)
+			return
+		}
+
+		writer.Write(Response{
+			Status: http.StatusOK,
+			Body:   gridpoint,
+		})
+	}
+}
+
+// HandleGetForecastDiagnosis is the handler for GET /admins/forecasts/diagnose.
+// The "lon" and "lat" query parameters are required. It responds with the
+// status codes of the NWS API lookups made for the point and the decision
+// path that explains why the point does or does not have a forecast.
+func (h *Handler) HandleGetForecastDiagnosis() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		lon := r.URL.Query().Get("lon")
+		lat := r.URL.Query().Get("lat")
+		writer := h.NewLogWriter(w, r)
+
+		point, err := ParsePoint(lon, lat)
+		if err != nil {
+			h.logger.Printf("HandleGetForecastDiagnosis: failed to extract point (lon=%q, lat=%q): %v", lon, lat, err)
+			writer.WriteError(err)
+			return
+		}
+
+		diagnosis, err := h.forecasts.Diagnose(r.Context(), point)
+		if err != nil {
+			h.logger.Printf("HandleGetForecastDiagnosis: failed to diagnose point (point=%v): %v", point, err)
+			writer.WriteError(err)
+			return
+		}
+
+		writer.Write(Response{
+			Status: http.StatusOK,
+			Body:   diagnosis,
+		})
+	}
+}
+
+// HandleGetForecastAsOf is the handler for GET /admins/forecasts/asof. The
+// "lon" and "lat" query parameters are required. It responds with the stored
+// periods of the gridpoint the point maps to, when they were generated and
+// expire, and whether a forecast request would currently be served from the
+// database or refreshed. Nothing is refreshed. It responds with a 404 if no
+// stored gridpoint covers the point.
+func (h *Handler) HandleGetForecastAsOf() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		lon := r.URL.Query().Get("lon")
+		lat := r.URL.Query().Get("lat")
+		writer := h.NewLogWriter(w, r)
+
+		point, err := ParsePoint(lon, lat)
+		if err != nil {
+			h.logger.Printf("HandleGetForecastAsOf: failed to extract point (lon=%q, lat=%q): %v", lon, lat, err)
+			writer.WriteError(err)
+			return
+		}
+
+		asOf, err := h.forecasts.AsOf(r.Context(), point)
+		if err != nil {
+			h.logger.Printf("HandleGetForecastAsOf: failed to get forecast as of now (point=%v): %v", point, err)
+			writer.WriteError(err)
+			return
+		}
+
+		writer.Write(Response{
+			Status: http.StatusOK,
+			Body:   asOf,
+		})
+	}
+}
+
+// HandleGetBBoxGridpoints is the handler for GET /admins/forecasts/bbox. It
+// responds with a page of the stored gridpoints that overlap the bounding box
+// of the "minlon", "minlat", "maxlon" and "maxlat" query parameters, without
+// their periods, so a map can show which areas have stored forecasts.
+func (h *Handler) HandleGetBBoxGridpoints() http.HandlerFunc {
+	type res struct {
+		PageMeta
+		Gridpoints []forecast.GridpointResponse `json:"gridpoints"`
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		writer := h.NewLogWriter(w, r)
+
+		bbox, err := ParseBBoxParams(query.Get("minlon"), query.Get("minlat"), query.Get("maxlon"), query.Get("maxlat"))
+		if err != nil {
+			h.logger.Printf("HandleGetBBoxGridpoints: failed to extract bbox: %v", err)
+			writer.WriteError(err)
+			return
+		}
+
+		page, err := ParsePage(query.Get("limit"), query.Get("offset"))
+		if err != nil {
+			h.logger.Printf("HandleGetBBoxGridpoints: failed to extract page: %v", err)
+			writer.WriteError(err)
+			return
+		}
+		page = ClampPage(page, forecast.DefaultBBoxGridpoints, forecast.MaxBBoxGridpoints)
+
+		gridpoints, err := h.forecasts.GridpointsInBBox(r.Context(), bbox, page.Limit, page.Offset)
+		if err != nil {
+			h.logger.Printf("HandleGetBBoxGridpoints: failed to get gridpoints (bbox=%+v): %v", bbox, err)
+			writer.WriteError(err)
+			return
+		}
+
+		meta := NewPageMeta(gridpoints.Total, page)
+		writer.Write(Response{
+			Status: http.StatusOK,
+			Body: res{
+				PageMeta:   meta,
+				Gridpoints: gridpoints.Gridpoints,
+			},
+			Page: &meta,
+		})
+	}
+}
+
+// HandleGetRemappedGridpoints is the handler for GET /admins/forecasts/remapped.
+// A random sample of stored gridpoints is checked against the NWS API and the
+// gridpoints now served by a different grid are reported so they can be
+// evicted. The "sample" query parameter is the number of gridpoints checked.
+func (h *Handler) HandleGetRemappedGridpoints() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		writer := h.NewLogWriter(w, r)
+
+		sample, err := ParseSample(r.URL.Query().Get("sample"))
+		if err != nil {
+			h.logger.Printf("HandleGetRemappedGridpoints: extracting sample: %v", err)
+			writer.WriteError(err)
+			return
+		}
+
+		report, err := h.forecasts.VerifyGridpoints(r.Context(), sample)
+		if err != nil {
+			err = fmt.Errorf("HandleGetRemappedGridpoints: Verifying gridpoints (sample=%d): %w", sample, err)
+			h.logger.Println(err)
+			writer.WriteError(err)
+			return
+		}
+
+		writer.Write(Response{
+			Status: http.StatusOK,
+			Body:   report,
+		})
+	}
+}
+
+// HandleGetOverlappingGridpoints is the handler for GET /admins/forecasts/overlaps.
+// It responds with the pairs of stored gridpoints whose geometric bounds
+// overlap, so they can be evicted.
+func (h *Handler) HandleGetOverlappingGridpoints() http.HandlerFunc {
+	type res struct {
+		Overlaps forecast.GridpointOverlapCollection `json:"overlaps"`
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		writer := h.NewLogWriter(w, r)
+
+		overlaps, err := h.forecasts.Overlaps(r.Context())
+		if err != nil {
+			err = fmt.Errorf("HandleGetOverlappingGridpoints: Getting overlapping gridpoints: %w", err)
+			h.logger.Println(err)
+			writer.WriteError(err)
+			return
+		}
+
+		writer.Write(Response{
+			Status: http.StatusOK,
+			Body: res{
+				Overlaps: overlaps,
+			},
+		})
+	}
+}
+
+// HandlePostWarmForecasts is the handler for POST /admins/forecasts/warm.
+// The body is a JSON object with a list of points, e.g.
+// {"points": [{"lon": -97.0892, "lat": 39.7456}]}. The forecast of each
+// point is fetched and stored so the first request for it is fast. It
+// responds with the result of each distinct point, in the same order.
+func (h *Handler) HandlePostWarmForecasts() http.HandlerFunc {
+	type req struct {
+		Points []struct {
+			Lon float64 `json:"lon"`
+			Lat float64 `json:"lat"`
+		} `json:"points"`
+	}
+
+	type pointResult struct {
+		Lon      float64 `json:"lon"`
+		Lat      float64 `json:"lat"`
+		OK       bool    `json:"ok"`
+		Status   int     `json:"status"`
+		ErrorMsg string  `json:"error_msg,omitempty"`
+	}
+
+	type res struct {
+		Warmed int           `json:"warmed"`
+		Failed int           `json:"failed"`
+		Points []pointResult `json:"points"`
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		writer := h.NewLogWriter(w, r)
+
+		var body req
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			appErr := &app.ServerResponseError{
+				Err:        fmt.Errorf("HandlePostWarmForecasts: Decoding request body: %w", err),
+				Msg:        "Body must be a JSON object with a list of points",
+				StatusCode: http.StatusBadRequest,
+			}
+
+			h.logger.Println(appErr.Err)
+			writer.WriteError(appErr)
+			return
+		}
+
+		points := []geometry.Point{}
+		for i, p := range body.Points {
+			point := geometry.NewPoint(p.Lon, p.Lat)
+			if !point.IsValid() {
+				appErr := &app.ServerResponseError{
+					Err:        fmt.Errorf("HandlePostWarmForecasts: invalid point %d (lon=%f, lat=%f)", i, p.Lon, p.Lat),
+					Msg:        fmt.Sprintf("Invalid point at index %d", i),
+					StatusCode: http.StatusBadRequest,
+				}
+
+				h.logger.Println(appErr.Err)
+				writer.WriteError(appErr)
+				return
+			}
+			points = append(points, point)
+		}
+
+		results, err := h.forecasts.Warm(r.Context(), points)
+		if err != nil {
+			err = fmt.Errorf("HandlePostWarmForecasts: Warming forecasts: %w", err)
+			h.logger.Println(err)
+			writer.WriteError(err)
+			return
+		}
+
+		result := res{Points: []pointResult{}}
+		for _, wr := range results {
+			pr := pointResult{
+				Lon:    wr.Point.Lon(),
+				Lat:    wr.Point.Lat(),
+				OK:     wr.Err == nil,
+				Status: http.StatusOK,
+			}
+			if wr.Err != nil {
+				h.logger.Printf("HandlePostWarmForecasts: failed to warm point (point=%v): %v", wr.Point, wr.Err)
+				errResp := errorResponse(wr.Err)
+				pr.Status, pr.ErrorMsg = errResp.Status, errResp.ErrorMsg
+				result.Failed++
+			} else {
+				result.Warmed++
+			}
+			result.Points = append(result.Points, pr)
+		}
+
+		writer.Write(Response{
+			Status: http.StatusOK,
+			Body:   result,
+		})
+	}
+}
+
+// HandleDeleteStaleGridpoints is the handler for DELETE /admins/gridpoints/stale.
+// The "older_than" query parameter is required and is a duration (e.g. "72h").
+// Gridpoints generated and last fetched before then are deleted.
+func (h *Handler) HandleDeleteStaleGridpoints() http.HandlerFunc {
+	type res struct {
+		Deleted int64 `json:"deleted"`
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		writer := h.NewLogWriter(w, r)
+
+		olderThan, err := ParseDuration(r.URL.Query().Get("older_than"))
+		if err != nil {
+			h.logger.Printf("HandleDeleteStaleGridpoints: extracting older_than: %v", err)
+			writer.WriteError(err)
+			return
+		}
+
+		deleted, err := h.forecasts.EvictStale(r.Context(), olderThan)
+		if err != nil {
+			err = fmt.Errorf("HandleDeleteStaleGridpoints: Evicting gridpoints (olderThan=%v): %w", olderThan, err)
+			h.logger.Println(err)
+			writer.WriteError(err)
+			return
+		}
+
+		writer.Write(Response{
+			Status: http.StatusOK,
+			Body: res{
+				Deleted: deleted,
+			},
+		})
+	}
+}
+
+// HandleGetAudit is the handler for GET /admins/audit. The admin making the
+// request must be a superadmin. It responds with the most recent audit entries,
+// paged by the "limit" and "offset" query parameters.
+func (h *Handler) HandleGetAudit() http.HandlerFunc {
+	type res struct {
+		Entries admin.AuditLog `json:"entries"`
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		writer := h.NewLogWriter(w, r)
+		ctx := r.Context()
+		actorID := adminID(ctx)
+
+		page, err := ParsePage(r.URL.Query().Get("limit"), r.URL.Query().Get("offset"))
+		if err != nil {
+			h.logger.Printf("HandleGetAudit: extracting page: %v", err)
+			writer.WriteError(err)
+			return
+		}
+
+		entries, err := h.admins.AuditLog(ctx, actorID, page.Limit, page.Offset)
+		if err != nil {
+			err = fmt.Errorf("HandleGetAudit: Getting audit log (actorID=%d): %w", actorID, err)
+			h.logger.Println(err)
+			writer.WriteError(err)
+			return
+		}
+
+		writer.Write(Response{
+			Status: http.StatusOK,
+			Body: res{
+				Entries: entries,
+			},
+		})
+	}
+}
+
+// HandleGetAlertRuns is the handler for GET /admins/alerts/runs. It
+// responds with the most recent alert sync runs, limited by the "limit"
+// query parameter.
+func (h *Handler) HandleGetAlertRuns() http.HandlerFunc {
+	type res struct {
+		Runs alert.SyncRunCollection `json:"runs"`
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		writer := h.NewLogWriter(w, r)
+
+		page, err := ParsePage(r.URL.Query().Get("limit"), "")
+		if err != nil {
+			h.logger.Printf("HandleGetAlertRuns: extracting limit: %v", err)
+			writer.WriteError(err)
+			return
+		}
+
+		runs, err := h.alerts.Runs(r.Context(), page.Limit)
+		if err != nil {
+			err = fmt.Errorf("HandleGetAlertRuns: Getting sync runs: %w", err)
+			h.logger.Println(err)
+			writer.WriteError(err)
+			return
+		}
+
+		writer.Write(Response{
+			Status: http.StatusOK,
+			Body: res{
+				Runs: runs,
+			},
+		})
+	}
+}
+
+// HandleRebuildAlertZones is the handler for POST /admins/alerts/zones. The
+// "q" query parameter is the state id. It rewrites the alert zone mappings of
+// the state and should be called after the zones of the state are re-ingested.
+func (h *Handler) HandleRebuildAlertZones() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		stateID := r.URL.Query().Get("q")
+		writer := h.NewLogWriter(w, r)
+
+		mapping, err := h.alerts.RebuildZoneMappings(r.Context(), stateID)
+		if err != nil {
+			h.logger.Printf("HandleRebuildAlertZones: failed to rebuild zone mappings (stateID=%q): %v", stateID, err)
+			writer.WriteError(err)
+			return
+		}
+
+		writer.Write(Response{
+			Status: http.StatusOK,
+			Body:   mapping,
+		})
+	}
+}
+
+// HandleReset is the handler for POST /admins/reset. It deletes all forecast,
+// alert, and zone data, leaving the admins intact, and responds with the rows
+// deleted per table. The "confirm" query parameter must be "true" and the admin
+// must be a superadmin. It refuses every request unless the server has a
+// Resetter, which is only set for test environments.
+func (h *Handler) HandleReset() http.HandlerFunc {
+	type res struct {
+		Deleted database.ResetResult `json:"deleted"`
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		writer := h.NewLogWriter(w, r)
+		ctx := r.Context()
+		actorID := adminID(ctx)
+
+		if h.resetter == nil {
+			writer.WriteError(&app.ServerResponseError{
+				Err:        fmt.Errorf("reset disabled (actorID=%d)", actorID),
+				Msg:        "Reset is disabled on this server",
+				StatusCode: http.StatusForbidden,
+			})
+			return
+		}
+
+		if confirm := r.URL.Query().Get("confirm"); confirm != "true" {
+			err := &QueryParameterError{
+				Msg:   "Must confirm the reset with confirm=true",
+				error: fmt.Errorf("reset not confirmed (confirm=%q)", confirm),
+			}
+			h.logger.Printf("HandleReset: %v", err)
+			writer.WriteError(err)
+			return
+		}
+
+		if err := h.admins.RequireSuperadmin(ctx, actorID); err != nil {
+			h.logger.Printf("HandleReset: verifying superadmin (actorID=%d): %v", actorID, err)
+			writer.WriteError(err)
+			return
+		}
+
+		deleted, err := h.resetter.Reset(ctx)
+		if err != nil {
+			err = fmt.Errorf("HandleReset: Resetting data (actorID=%d): %w", actorID, err)
+			h.logger.Println(err)
+			writer.WriteError(err)
+			return
+		}
+
+		h.logger.Printf("HandleReset: data reset (actorID=%d, deleted=%v)", actorID, deleted)
+		writer.Write(Response{
+			Status: http.StatusOK,
+			Body: res{
+				Deleted: deleted,
+			},
+		})
+	}
+}
+
+// HandleGetWorkerStatus is the handler for GET /admins/worker/status. It
+// responds with the status of the background alert sync worker. A worker
+// that is running but has no recent last_run has stopped syncing.
+func (h *Handler) HandleGetWorkerStatus() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		writer := h.NewLogWriter(w, r)
+
+		writer.Write(Response{
+			Status: http.StatusOK,
+			Body:   h.worker.Status(),
+		})
+	}
+}
+
+// HandleGetMetrics is the handler for GET /admins/metrics. It responds
+// with the number of NWS API calls per endpoint and outcome since the
+// server started, and the last read freshness of the stored forecasts if
+// the server collects it.
+func (h *Handler) HandleGetMetrics() http.HandlerFunc {
+	type res struct {
+		NWSCalls  map[string]map[string]int `json:"nws_calls"`
+		Freshness *forecast.Freshness       `json:"forecast_freshness,omitempty"`
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		writer := h.NewLogWriter(w, r)
+
+		body := res{}
+		if h.metrics != nil {
+			body.NWSCalls = h.metrics.Counts()
+		}
+		if h.freshness != nil {
+			freshness := h.freshness.Value()
+			body.Freshness = &freshness
+		}
+
+		writer.Write(Response{
+			Status: http.StatusOK,
+			Body:   body,
+		})
+	}
+}
+
+// maxGeometryBodyBytes is the largest request body HandlePostValidateGeometry
+// reads. The largest NWS zone geometries are a few megabytes.
+const maxGeometryBodyBytes = 8 << 20
+
+// HandlePostValidateGeometry is the handler for POST /admins/geometry/validate.
+// The handler expects the body to be a GeoJSON geometry object of type Polygon or
+// MultiPolygon.
+//
+// Each polygon is checked for invalid coordinates, unclosed rings, too few points,
+// and self-intersections. The response lists the issues found for each polygon
+// along with the polygon after its rings have been closed. A body larger than
+// maxGeometryBodyBytes is rejected with a 413.
+func (h *Handler) HandlePostValidateGeometry() http.HandlerFunc {
+	type polygonResult struct {
+		Index      int              `json:"index"`
+		Valid      bool             `json:"valid"`
+		Issues     []geometry.Issue `json:"issues"`
+		Normalized geometry.Polygon `json:"normalized"`
+	}
+
+	type res struct {
+		Valid    bool            `json:"valid"`
+		Polygons []polygonResult `json:"polygons"`
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		writer := h.NewLogWriter(w, r)
+
+		body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxGeometryBodyBytes))
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			appErr := &app.ServerResponseError{
+				Err:        fmt.Errorf("HandlePostValidateGeometry: Reading request body: %w", err),
+				Msg:        fmt.Sprintf("Body must be at most %d bytes", maxGeometryBodyBytes),
+				StatusCode: http.StatusRequestEntityTooLarge,
+			}
+
+			h.logger.Println(appErr.Err)
+			writer.WriteError(appErr)
+			return
+		}
+		if err != nil {
+			h.logger.Printf("HandlePostValidateGeometry: Reading request body: %v", err)
+			writer.WriteError(err)
+			return
+		}
+
+		multiPolygon, err := nws.ParseGeoJSON(body)
+		if err != nil {
+			appErr := &app.ServerResponseError{
+				Err:        fmt.Errorf("HandlePostValidateGeometry: Parsing geometry: %w", err),
+				Msg:        "Body must be a GeoJSON Polygon or MultiPolygon",
+				StatusCode: http.StatusBadRequest,
+			}
+
+			h.logger.Println(appErr.Err)
+			writer.WriteError(appErr)
+			return
+		}
+
+		result := res{Valid: true, Polygons: []polygonResult{}}
+		for i, polygon := range multiPolygon {
+			issues := polygon.Validate()
+			result.Polygons = append(result.Polygons, polygonResult{
+				Index:      i,
+				Valid:      len(issues) == 0,
+				Issues:     issues,
+				Normalized: polygon.Normalize(),
+			})
+			if len(issues) > 0 {
+				result.Valid = false
+			}
+		}
+
+		writer.Write(Response{
+			Status: http.StatusOK,
+			Body:   result,
 		})
 	}
 }
@@ -2,9 +2,13 @@ package server
 
 import (
 	"context"
+	"crypto/rand"
+	"encoding/hex"
 	"fmt"
 	"log"
 	"net/http"
+	"sync/atomic"
+	"time"
 
 	"github.com/cicconee/weather-app/internal/admin"
 	"github.com/cicconee/weather-app/internal/app"
@@ -62,6 +66,109 @@ func (v *AdminValidater) Validate(next http.HandlerFunc) http.HandlerFunc {
 	}
 }
 
+// Deadline is a middleware that limits how long next has to respond.
+// The request context passed to next is canceled after d, which cancels
+// any service calls still in progress.
+func Deadline(d time.Duration, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithTimeout(r.Context(), d)
+		defer cancel()
+
+		next(w, r.WithContext(ctx))
+	}
+}
+
+// ReadOnly is a middleware that rejects a mutating request with a
+// 403 status code. It wraps the mutating routes when the server is
+// in read-only mode.
+func ReadOnly(logger *log.Logger) func(http.HandlerFunc) http.HandlerFunc {
+	return func(next http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			NewLogWriter(logger, w, r).WriteError(&app.ServerResponseError{
+				Err:        fmt.Errorf("%s %s: server is read-only", r.Method, r.URL.Path),
+				Msg:        "This server is read-only",
+				StatusCode: http.StatusForbidden,
+			})
+		}
+	}
+}
+
+// RequestIDHeader is the header that carries the id of a request.
+const RequestIDHeader = "X-Request-Id"
+
+// maxRequestIDLength is the longest request id accepted from a client.
+const maxRequestIDLength = 64
+
+// requestIDCounter numbers the request ids generated when a random id
+// cannot be read.
+var requestIDCounter atomic.Uint64
+
+// RequestID is a middleware that stores a id for the request in the
+// request context and sets it on the RequestIDHeader of the response.
+// A id sent by the client in the RequestIDHeader is used if it is not
+// too long, otherwise a random id is generated.
+func RequestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(RequestIDHeader)
+		if id == "" || len(id) > maxRequestIDLength {
+			id = newRequestID()
+		}
+
+		w.Header().Set(RequestIDHeader, id)
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), "request_id", id)))
+	})
+}
+
+// newRequestID returns a random request id. If a random id cannot be
+// read, the next number of requestIDCounter is used instead so every
+// request still gets a unique id.
+func newRequestID() string {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		log.Printf("failed to generate random request id: %v\n", err)
+		return fmt.Sprintf("seq-%d", requestIDCounter.Add(1))
+	}
+
+	return hex.EncodeToString(b)
+}
+
+// requestID returns the id of the request stored in ctx by RequestID.
+// If ctx does not hold a request id, a empty string is returned.
+func requestID(ctx context.Context) string {
+	id, _ := ctx.Value("request_id").(string)
+	return id
+}
+
+// adminID returns the id of the validated admin stored in ctx by
+// AdminValidater.Validate. If ctx does not hold an admin id, zero is
+// returned.
+func adminID(ctx context.Context) int {
+	id, _ := ctx.Value("admin_id").(int)
+	return id
+}
+
+// Identify will store the id of the caller in the request context if
+// they are a approved admin. Unlike Validate, the request is never
+// aborted. Callers without a valid admin token cookie are passed to next
+// unchanged.
+func (v *AdminValidater) Identify(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		cookie, err := r.Cookie(adminTokenCookieKey)
+		if err != nil {
+			next(w, r)
+			return
+		}
+
+		account, err := v.admins.Validate(r.Context(), cookie.Value)
+		if err != nil || !account.IsApproved() {
+			next(w, r)
+			return
+		}
+
+		next(w, r.WithContext(context.WithValue(r.Context(), "admin_id", account.ID)))
+	}
+}
+
 type logParams struct {
 	AccountID int
 }
@@ -0,0 +1,58 @@
+package server
+
+import "github.com/cicconee/weather-app/internal/alert"
+
+// PageMeta is the paging metadata of a list response. It is
+// embedded in the response body of list endpoints and set as
+// the Page of the Response so it is also included in the meta
+// of a Envelope.
+type PageMeta struct {
+	// The total number of items across all pages.
+	Total int `json:"total"`
+
+	// The max number of items in the page.
+	Limit int `json:"limit"`
+
+	// The number of items skipped.
+	Offset int `json:"offset"`
+
+	// The offset of the next page. It is nil on the last
+	// page.
+	NextOffset *int `json:"next_offset,omitempty"`
+}
+
+// NewPageMeta returns the PageMeta of page out of total items.
+// The page should already be clamped.
+func NewPageMeta(total int, page alert.Page) PageMeta {
+	meta := PageMeta{
+		Total:  total,
+		Limit:  page.Limit,
+		Offset: page.Offset,
+	}
+
+	if next := page.Offset + page.Limit; page.Limit > 0 && next < total {
+		meta.NextOffset = &next
+	}
+
+	return meta
+}
+
+// ClampPage returns page with a Limit of zero set to defaultLimit
+// and a Limit above maxLimit set to maxLimit. A negative Offset is
+// set to zero. List endpoints whose service does not clamp the page
+// should clamp it with ClampPage after ParsePage.
+func ClampPage(page alert.Page, defaultLimit int, maxLimit int) alert.Page {
+	if page.Limit <= 0 {
+		page.Limit = defaultLimit
+	}
+
+	if page.Limit > maxLimit {
+		page.Limit = maxLimit
+	}
+
+	if page.Offset < 0 {
+		page.Offset = 0
+	}
+
+	return page
+}
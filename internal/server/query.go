@@ -2,9 +2,15 @@ package server
 
 import (
 	"fmt"
+	"math"
 	"net/http"
 	"strconv"
+	"strings"
+	"time"
+	"unicode"
 
+	"github.com/cicconee/weather-app/internal/alert"
+	"github.com/cicconee/weather-app/internal/forecast"
 	"github.com/cicconee/weather-app/internal/geometry"
 )
 
@@ -41,5 +47,348 @@ func ParsePoint(lonStr string, latStr string) (geometry.Point, error) {
 		return geometry.Point{}, qErr
 	}
 
-	return geometry.NewPoint(lon, lat), nil
+	// ParseFloat accepts "NaN" and "Inf", which are
+	// not coordinates.
+	point := geometry.NewPoint(lon, lat)
+	if !point.IsValid() {
+		qErr := &QueryParameterError{
+			Msg:   "Invalid point",
+			error: fmt.Errorf("invalid point (lon=%q, lat=%q)", lonStr, latStr),
+		}
+		return geometry.Point{}, qErr
+	}
+
+	return point, nil
+}
+
+// ParsePointPair parses a point in the form "lon,lat" from the query
+// parameter name. If parsing fails an error is returned as a
+// QueryParameterError.
+func ParsePointPair(name string, pairStr string) (geometry.Point, error) {
+	lon, lat, ok := strings.Cut(pairStr, ",")
+	if !ok {
+		return geometry.Point{}, &QueryParameterError{
+			Msg:   fmt.Sprintf("Invalid %s, must be lon,lat", name),
+			error: fmt.Errorf("failed to parse %s %q: missing comma", name, pairStr),
+		}
+	}
+
+	point, err := ParsePoint(lon, lat)
+	if err != nil {
+		return geometry.Point{}, &QueryParameterError{
+			Msg:   fmt.Sprintf("Invalid %s, must be lon,lat", name),
+			error: fmt.Errorf("failed to parse %s %q: %w", name, pairStr, err),
+		}
+	}
+
+	return point, nil
+}
+
+// ParsePage takes the limit and offset as strings
+// (limitStr, offsetStr) and returns them as a
+// alert.Page. Empty strings are treated as zero,
+// which results in the default page.
+//
+// If parsing fails or a value is negative an error
+// is returned as a QueryParameterError.
+func ParsePage(limitStr string, offsetStr string) (alert.Page, error) {
+	var page alert.Page
+
+	if limitStr != "" {
+		limit, err := strconv.Atoi(limitStr)
+		if err != nil || limit < 0 {
+			qErr := &QueryParameterError{
+				Msg:   "Invalid limit",
+				error: fmt.Errorf("failed to parse limit %q: %v", limitStr, err),
+			}
+			return alert.Page{}, qErr
+		}
+		page.Limit = limit
+	}
+
+	if offsetStr != "" {
+		offset, err := strconv.Atoi(offsetStr)
+		if err != nil || offset < 0 {
+			qErr := &QueryParameterError{
+				Msg:   "Invalid offset",
+				error: fmt.Errorf("failed to parse offset %q: %v", offsetStr, err),
+			}
+			return alert.Page{}, qErr
+		}
+		page.Offset = offset
+	}
+
+	return page, nil
+}
+
+// ParseRadius takes a radius in meters as a string
+// (radiusStr) and returns it as a float64.
+//
+// If parsing fails an error is returned as a
+// QueryParameterError.
+func ParseRadius(radiusStr string) (float64, error) {
+	radius, err := strconv.ParseFloat(radiusStr, 64)
+	if err != nil {
+		qErr := &QueryParameterError{
+			Msg:   "Invalid radius",
+			error: fmt.Errorf("failed to parse radius: %w", err),
+		}
+		return 0, qErr
+	}
+
+	// ParseFloat accepts "NaN" and "Inf", which are
+	// not distances.
+	if math.IsNaN(radius) || math.IsInf(radius, 0) {
+		qErr := &QueryParameterError{
+			Msg:   "Invalid radius",
+			error: fmt.Errorf("invalid radius (radius=%q)", radiusStr),
+		}
+		return 0, qErr
+	}
+
+	return radius, nil
+}
+
+// ParseAdminID takes a admin id as a string (idStr)
+// and returns it as a int.
+//
+// If parsing fails an error is returned as a
+// QueryParameterError.
+func ParseAdminID(idStr string) (int, error) {
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		qErr := &QueryParameterError{
+			Msg:   "Invalid admin id",
+			error: fmt.Errorf("failed to parse admin id: %w", err),
+		}
+		return 0, qErr
+	}
+
+	return id, nil
+}
+
+// ParseGrid takes a NWS grid identifier (idStr, e.g.
+// "BOU") and the grid coordinates as strings (xStr
+// and yStr) and returns them as a forecast.Grid.
+//
+// If the identifier is not three letters, or the
+// coordinates are not non-negative integers, an
+// error is returned as a QueryParameterError.
+func ParseGrid(idStr string, xStr string, yStr string) (forecast.Grid, error) {
+	if len(idStr) != 3 || strings.IndexFunc(idStr, func(r rune) bool {
+		return !unicode.IsLetter(r)
+	}) >= 0 {
+		return forecast.Grid{}, &QueryParameterError{
+			Msg:   "Invalid id, must be a three letter grid identifier",
+			error: fmt.Errorf("invalid grid id %q", idStr),
+		}
+	}
+
+	x, err := strconv.Atoi(xStr)
+	if err != nil || x < 0 {
+		return forecast.Grid{}, &QueryParameterError{
+			Msg:   "Invalid x",
+			error: fmt.Errorf("invalid grid x %q", xStr),
+		}
+	}
+
+	y, err := strconv.Atoi(yStr)
+	if err != nil || y < 0 {
+		return forecast.Grid{}, &QueryParameterError{
+			Msg:   "Invalid y",
+			error: fmt.Errorf("invalid grid y %q", yStr),
+		}
+	}
+
+	return forecast.Grid{ID: strings.ToUpper(idStr), X: x, Y: y}, nil
+}
+
+// ParseTimeZone takes a IANA time zone name (e.g.
+// "America/New_York") and returns its location. A
+// empty string returns UTC.
+//
+// If the name is not a known time zone an error is
+// returned as a QueryParameterError.
+func ParseTimeZone(name string) (*time.Location, error) {
+	if name == "" {
+		return time.UTC, nil
+	}
+
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, &QueryParameterError{
+			Msg:   "Invalid tz",
+			error: fmt.Errorf("failed to load location %q: %w", name, err),
+		}
+	}
+
+	return loc, nil
+}
+
+// ParseDuration takes a duration as a string (e.g.
+// "72h") and returns it as a time.Duration.
+//
+// If parsing fails an error is returned as a
+// QueryParameterError.
+func ParseDuration(durationStr string) (time.Duration, error) {
+	d, err := time.ParseDuration(durationStr)
+	if err != nil {
+		return 0, &QueryParameterError{
+			Msg:   "Invalid duration",
+			error: fmt.Errorf("failed to parse duration: %w", err),
+		}
+	}
+
+	return d, nil
+}
+
+// ParseTime takes the value of the query parameter
+// name as a RFC 3339 time (timeStr) and returns it.
+// A empty string returns nil.
+//
+// If parsing fails an error is returned as a
+// QueryParameterError.
+func ParseTime(name string, timeStr string) (*time.Time, error) {
+	if timeStr == "" {
+		return nil, nil
+	}
+
+	t, err := time.Parse(time.RFC3339, timeStr)
+	if err != nil {
+		return nil, &QueryParameterError{
+			Msg:   fmt.Sprintf("Invalid %s, must be a RFC 3339 time", name),
+			error: fmt.Errorf("failed to parse %s: %w", name, err),
+		}
+	}
+
+	return &t, nil
+}
+
+// ParseBool takes the value of the query parameter
+// name as a string (boolStr) and returns it as a
+// bool.
+//
+// If parsing fails an error is returned as a
+// QueryParameterError.
+func ParseBool(name string, boolStr string) (bool, error) {
+	b, err := strconv.ParseBool(boolStr)
+	if err != nil {
+		return false, &QueryParameterError{
+			Msg:   fmt.Sprintf("Invalid %s", name),
+			error: fmt.Errorf("failed to parse %s: %w", name, err),
+		}
+	}
+
+	return b, nil
+}
+
+// ParseMaxPeriods takes the max number of forecast
+// periods as a string (maxStr) and returns it as a
+// int. A empty string is treated as zero, which
+// results in every period.
+//
+// If parsing fails or the value is negative an error
+// is returned as a QueryParameterError.
+func ParseMaxPeriods(maxStr string) (int, error) {
+	if maxStr == "" {
+		return 0, nil
+	}
+
+	max, err := strconv.Atoi(maxStr)
+	if err != nil || max < 0 {
+		return 0, &QueryParameterError{
+			Msg:   "Invalid maxPeriods",
+			error: fmt.Errorf("failed to parse maxPeriods %q: %v", maxStr, err),
+		}
+	}
+
+	return max, nil
+}
+
+// DefaultVerifySample is the number of gridpoints verified
+// when the sample query parameter is not set.
+const DefaultVerifySample = 10
+
+// ParseSample parses the sample query parameter. If sampleStr
+// is empty DefaultVerifySample is returned. If parsing fails an
+// error is returned as a QueryParameterError.
+func ParseSample(sampleStr string) (int, error) {
+	if sampleStr == "" {
+		return DefaultVerifySample, nil
+	}
+
+	sample, err := strconv.Atoi(sampleStr)
+	if err != nil {
+		return 0, &QueryParameterError{
+			Msg:   "Invalid sample",
+			error: fmt.Errorf("failed to parse sample %q: %w", sampleStr, err),
+		}
+	}
+
+	return sample, nil
+}
+
+// ParseBBox parses the bbox query parameter in the form
+// "minlon,minlat,maxlon,maxlat". If bboxStr is empty nil is
+// returned. If parsing fails, or the box is not valid, an
+// error is returned as a QueryParameterError.
+func ParseBBox(bboxStr string) (*geometry.BBox, error) {
+	if bboxStr == "" {
+		return nil, nil
+	}
+
+	parts := strings.Split(bboxStr, ",")
+	if len(parts) != 4 {
+		return nil, &QueryParameterError{
+			Msg:   "Invalid bbox, must be minlon,minlat,maxlon,maxlat",
+			error: fmt.Errorf("bbox %q does not have 4 values", bboxStr),
+		}
+	}
+
+	bbox, err := ParseBBoxParams(parts[0], parts[1], parts[2], parts[3])
+	if err != nil {
+		return nil, err
+	}
+
+	return &bbox, nil
+}
+
+// ParseBBoxParams parses the minlon, minlat, maxlon and maxlat
+// query parameters as a bounding box. If parsing fails, or the
+// box is not valid, an error is returned as a QueryParameterError.
+func ParseBBoxParams(minLonStr, minLatStr, maxLonStr, maxLatStr string) (geometry.BBox, error) {
+	names := [4]string{"minlon", "minlat", "maxlon", "maxlat"}
+	values := [4]float64{}
+	for i, str := range [4]string{minLonStr, minLatStr, maxLonStr, maxLatStr} {
+		v, err := strconv.ParseFloat(strings.TrimSpace(str), 64)
+		if err != nil {
+			return geometry.BBox{}, &QueryParameterError{
+				Msg:   fmt.Sprintf("Invalid %s", names[i]),
+				error: fmt.Errorf("failed to parse %s %q: %w", names[i], str, err),
+			}
+		}
+		values[i] = v
+	}
+
+	bbox := geometry.BBox{MinLon: values[0], MinLat: values[1], MaxLon: values[2], MaxLat: values[3]}
+	if !bbox.IsValid() {
+		return geometry.BBox{}, &QueryParameterError{
+			Msg:   "Invalid bounding box, the minimums must be less than the maximums",
+			error: fmt.Errorf("invalid bbox %+v", bbox),
+		}
+	}
+
+	return bbox, nil
+}
+
+// noCache reports if the Cache-Control header of r has the
+// no-cache directive.
+func noCache(r *http.Request) bool {
+	for _, directive := range strings.Split(r.Header.Get("Cache-Control"), ",") {
+		if strings.EqualFold(strings.TrimSpace(directive), "no-cache") {
+			return true
+		}
+	}
+
+	return false
 }
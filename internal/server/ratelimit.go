@@ -0,0 +1,133 @@
+package server
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/cicconee/weather-app/internal/app"
+)
+
+// rateLimitSweepInterval is how often a RateLimiter removes the buckets
+// of clients that have been idle long enough to be full again.
+const rateLimitSweepInterval = time.Minute
+
+// RateLimiter is a middleware that limits how many requests each client
+// IP can make with a token bucket. A bucket holds up to burst tokens and
+// is refilled at rate tokens per second. Each request takes a token, and
+// a request made when the bucket is empty is rejected with a 429 status
+// code and a Retry-After header. It must be created with NewRateLimiter.
+type RateLimiter struct {
+	rate   float64
+	burst  float64
+	logger *log.Logger
+
+	// clock reads the current time. If nil the system time is used.
+	clock app.Clock
+
+	mu        sync.Mutex
+	buckets   map[string]*tokenBucket
+	lastSweep time.Time
+}
+
+// tokenBucket is the token bucket of a single client.
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// NewRateLimiter returns a RateLimiter that allows each client rate
+// requests per second, with bursts of up to burst requests. A burst less
+// than 1 is set to 1. If clock is nil the system time is used.
+func NewRateLimiter(rate float64, burst int, clock app.Clock, logger *log.Logger) *RateLimiter {
+	if burst < 1 {
+		burst = 1
+	}
+
+	return &RateLimiter{
+		rate:    rate,
+		burst:   float64(burst),
+		logger:  logger,
+		clock:   clock,
+		buckets: map[string]*tokenBucket{},
+	}
+}
+
+// Limit will reject the request with a 429 status code if the client
+// has no tokens left, otherwise next will execute.
+func (l *RateLimiter) Limit(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ip := clientIP(r)
+
+		ok, wait := l.allow(ip)
+		if !ok {
+			w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+			NewLogWriter(l.logger, w, r).WriteError(&app.ServerResponseError{
+				Err:        fmt.Errorf("%s %s: rate limit exceeded (ip=%s)", r.Method, r.URL.Path, ip),
+				Msg:        "Too many requests",
+				StatusCode: http.StatusTooManyRequests,
+			})
+			return
+		}
+
+		next(w, r)
+	}
+}
+
+// allow takes a token from the bucket of key. If the bucket is empty
+// false is returned with how long until a token is available.
+func (l *RateLimiter) allow(key string) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := app.Now(l.clock)
+	l.sweep(now)
+
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &tokenBucket{tokens: l.burst, last: now}
+		l.buckets[key] = b
+	}
+
+	b.tokens = math.Min(l.burst, b.tokens+now.Sub(b.last).Seconds()*l.rate)
+	b.last = now
+
+	if b.tokens < 1 {
+		return false, time.Duration((1 - b.tokens) / l.rate * float64(time.Second))
+	}
+
+	b.tokens--
+	return true, 0
+}
+
+// sweep removes the buckets that would be full at now, since a new
+// bucket is the same as a full one. It runs at most once every
+// rateLimitSweepInterval.
+func (l *RateLimiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < rateLimitSweepInterval {
+		return
+	}
+	l.lastSweep = now
+
+	for key, b := range l.buckets {
+		if b.tokens+now.Sub(b.last).Seconds()*l.rate >= l.burst {
+			delete(l.buckets, key)
+		}
+	}
+}
+
+// clientIP returns the IP of the client that made r. Forwarding headers
+// are not trusted, since any client can set them.
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+
+	return host
+}
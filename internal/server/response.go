@@ -1,13 +1,32 @@
 package server
 
+import (
+	"net/http"
+	"time"
+
+	"github.com/cicconee/weather-app/internal/app"
+)
+
 type Response struct {
 	Status int
 	Body   any
+
+	// Page is optional. If set, it is included in the meta of a
+	// Envelope.
+	Page *PageMeta
 }
 
 type ErrorResponse struct {
-	Status   int    `json:"-"`
-	ErrorMsg string `json:"error_msg"`
+	Status   int            `json:"-"`
+	ErrorMsg string         `json:"error_msg"`
+	Upstream *UpstreamError `json:"upstream,omitempty"`
+}
+
+// UpstreamError is the status code and detail of a failed NWS
+// API request. It is only included in a ErrorResponse for admins.
+type UpstreamError struct {
+	Status int    `json:"status"`
+	Detail string `json:"detail"`
 }
 
 func (e *ErrorResponse) AsResponse() Response {
@@ -16,3 +35,63 @@ func (e *ErrorResponse) AsResponse() Response {
 		Body:   e,
 	}
 }
+
+// EnvelopeMediaType is the media type a client sends in the Accept
+// header to receive responses wrapped in a Envelope. Other clients
+// receive the bare response body.
+const EnvelopeMediaType = "application/vnd.weather-app.envelope+json"
+
+// CAPMediaType is the media type a client sends in the Accept header
+// to receive alerts in the Common Alerting Protocol (CAP) 1.2 format
+// instead of JSON. Errors are still written as JSON.
+const CAPMediaType = "application/cap+xml"
+
+// AtomMediaType is the content type of a Atom feed.
+const AtomMediaType = "application/atom+xml"
+
+// GeoJSONMediaType is the content type of a GeoJSON document.
+const GeoJSONMediaType = "application/geo+json"
+
+// Envelope is the shape of every response to a client that accepts
+// EnvelopeMediaType. Data holds the body of a successful response and
+// Error the body of a failed response. Only one of them is set.
+type Envelope struct {
+	Data  any  `json:"data,omitempty"`
+	Error any  `json:"error,omitempty"`
+	Meta  Meta `json:"meta"`
+}
+
+// Meta is the metadata of a response in a Envelope.
+type Meta struct {
+	// The id of the request. See RequestID.
+	RequestID string `json:"request_id,omitempty"`
+
+	// The time the server wrote the response.
+	ServerTime app.Time `json:"server_time"`
+
+	// The paging of a list response.
+	Page *PageMeta `json:"page,omitempty"`
+}
+
+// envelope wraps this response in a Envelope. Responses with a
+// status code of 400 or greater are errors.
+func (r Response) envelope(requestID string, now time.Time) Response {
+	env := Envelope{
+		Meta: Meta{
+			RequestID:  requestID,
+			ServerTime: app.Time(now),
+			Page:       r.Page,
+		},
+	}
+
+	if r.Status >= http.StatusBadRequest {
+		env.Error = r.Body
+	} else {
+		env.Data = r.Body
+	}
+
+	return Response{
+		Status: r.Status,
+		Body:   env,
+	}
+}
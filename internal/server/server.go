@@ -14,50 +14,220 @@ import (
 
 	"github.com/cicconee/weather-app/internal/admin"
 	"github.com/cicconee/weather-app/internal/alert"
+	"github.com/cicconee/weather-app/internal/database"
 	"github.com/cicconee/weather-app/internal/forecast"
+	"github.com/cicconee/weather-app/internal/nws"
 	"github.com/cicconee/weather-app/internal/state"
 	"github.com/go-chi/chi/v5"
 )
 
-type Server struct {
-	Router    *chi.Mux
-	Addr      string
-	Interval  time.Duration
-	Logger    *log.Logger
+// Config is the configuration of a Server. States, Alerts, and
+// Forecasts are required. All other fields are optional.
+type Config struct {
+	// Router defaults to a new chi.Mux.
+	Router *chi.Mux
+
+	// Addr is the port the server listens on. Defaults to 8080.
+	Addr string
+
+	// Interval is how often alerts are synced. Defaults to 5
+	// seconds.
+	Interval time.Duration
+
+	// MaxInterval caps how long alerts go unsynced while syncs
+	// keep failing. The interval is doubled after each consecutive
+	// failed sync, up to MaxInterval, and reset by a successful sync.
+	// Defaults to 5 minutes, or Interval if it is longer.
+	MaxInterval time.Duration
+
+	// Logger defaults to log.Default().
+	Logger *log.Logger
+
+	// Routes is optional. If set, it is called with Router to mount
+	// additional handlers (e.g. a frontend) or middleware. It is
+	// called after the RequestID middleware is added and before any
+	// built-in route is registered, so middleware it adds wraps every
+	// route. It must not register a built-in route.
+	Routes func(r *chi.Mux)
+
 	States    *state.Service
 	Alerts    *alert.Service
 	Forecasts *forecast.Service
-	Admins    *admin.Service
 
-	handler      *Handler
-	shutdownCh   chan os.Signal
-	worker       *worker
-	workerKillCh chan<- struct{}
-	wg           *sync.WaitGroup
+	// Admins is optional. When nil the /admins routes are not
+	// registered, so states cannot be created or synced over HTTP
+	// and upstream errors are never exposed. The public read
+	// routes and the background alert sync still run.
+	Admins *admin.Service
+
+	// Metrics is optional. If set, the NWS API call counts are
+	// served at GET /admins/metrics. It should be the MetricsHook
+	// of the nws.Client used by the services.
+	Metrics *nws.CallCounter
+
+	// Freshness is optional. If set, it is updated every
+	// FreshnessInterval and served at GET /admins/metrics.
+	Freshness *forecast.FreshnessGauge
+
+	// FreshnessInterval is how often Freshness is updated.
+	// Defaults to 1 minute.
+	FreshnessInterval time.Duration
+
+	// FetchFlushInterval is how often the counted forecast fetches
+	// are written to the database. Defaults to 30 seconds.
+	FetchFlushInterval time.Duration
+
+	// RefreshInterval is how often the stored forecasts close to
+	// expiring are refreshed in the background, before they are
+	// requested. When zero they are only refreshed when requested.
+	RefreshInterval time.Duration
+
+	// Resetter is optional. If set, POST /admins/reset deletes all
+	// forecast, alert, and zone data. It must only be set for test
+	// environments. When nil the route refuses every request.
+	Resetter *database.Resetter
+
+	// RequestTimeout is how long the public read handlers have to
+	// respond before their context is canceled. Defaults to 15 seconds.
+	RequestTimeout time.Duration
+
+	// RateLimit is how many requests per second each client IP may
+	// make to the public read routes. When zero the public read routes
+	// are not rate limited. Admin routes are never rate limited, since
+	// they require a valid admin.
+	RateLimit float64
+
+	// RateBurst is how many requests a client IP may make at once
+	// before RateLimit applies. Defaults to 10.
+	RateBurst int
+
+	// ExposeUpstreamErrors includes the NWS API status code and detail
+	// in error responses for requests made by approved admins. The
+	// public never sees upstream errors.
+	ExposeUpstreamErrors bool
+
+	// SecureCookie marks the admin token cookie as Secure. It should
+	// be enabled when the server is reached over TLS.
+	SecureCookie bool
+
+	// ReadOnly disables all mutating routes and the background alert
+	// sync. Forecasts are served only from the database, so the
+	// server never calls the NWS API or writes to the database. Use it
+	// to run replicas against a shared database.
+	ReadOnly bool
 }
 
-func (s *Server) addr() string {
-	if s.Addr == "" {
-		s.Addr = "8080"
+// validate returns an error if a required dependency is missing.
+func (c *Config) validate() error {
+	if c.States == nil {
+		return errors.New("states is nil")
 	}
 
-	return fmt.Sprintf(":%s", s.Addr)
+	if c.Alerts == nil {
+		return errors.New("alerts is nil")
+	}
+
+	if c.Forecasts == nil {
+		return errors.New("forecasts is nil")
+	}
+
+	return nil
+}
+
+// withDefaults returns this Config with the defaults set for any
+// optional field that is not set.
+func (c Config) withDefaults() Config {
+	if c.Router == nil {
+		c.Router = chi.NewRouter()
+	}
+
+	if c.Addr == "" {
+		c.Addr = "8080"
+	}
+
+	if c.Interval == 0 {
+		c.Interval = 5 * time.Second
+	}
+
+	if c.MaxInterval == 0 {
+		c.MaxInterval = 5 * time.Minute
+	}
+
+	if c.MaxInterval < c.Interval {
+		c.MaxInterval = c.Interval
+	}
+
+	if c.Logger == nil {
+		c.Logger = log.Default()
+	}
+
+	if c.FreshnessInterval == 0 {
+		c.FreshnessInterval = time.Minute
+	}
+
+	if c.FetchFlushInterval == 0 {
+		c.FetchFlushInterval = 30 * time.Second
+	}
+
+	if c.RequestTimeout == 0 {
+		c.RequestTimeout = 15 * time.Second
+	}
+
+	if c.RateBurst == 0 {
+		c.RateBurst = 10
+	}
+
+	return c
 }
 
-func (s *Server) interval() time.Duration {
-	if s.Interval == 0 {
-		s.Interval = 5 * time.Second
+// Server is the HTTP server and the background alert sync. It should
+// be created with New, but a Server literal is validated and given its
+// defaults when it is started.
+type Server struct {
+	Config
+
+	handler         *Handler
+	shutdownCh      chan os.Signal
+	worker          *worker
+	workerKillCh    chan<- struct{}
+	freshnessKillCh chan struct{}
+	fetchesKillCh   chan struct{}
+	refreshKillCh   chan struct{}
+	wg              *sync.WaitGroup
+}
+
+// New returns a Server configured by cfg. The defaults are set for
+// any optional field of cfg that is not set. If a required dependency
+// is missing an error is returned.
+func New(cfg Config) (*Server, error) {
+	if err := cfg.validate(); err != nil {
+		return nil, err
 	}
 
-	return s.Interval
+	return &Server{Config: cfg.withDefaults()}, nil
+}
+
+func (s *Server) addr() string {
+	return fmt.Sprintf(":%s", s.Addr)
 }
 
 func (s *Server) init() {
 	s.handler = NewHandler(s.Logger)
+	s.handler.secureCookie = s.SecureCookie
+	s.handler.exposeUpstream = s.ExposeUpstreamErrors
 	s.handler.states = s.States
 	s.handler.alerts = s.Alerts
 	s.handler.forecasts = s.Forecasts
 	s.handler.admins = s.Admins
+	s.handler.metrics = s.Metrics
+	s.handler.freshness = s.Freshness
+	s.handler.resetter = s.Resetter
+	if s.ReadOnly {
+		s.Forecasts.ReadOnly = true
+		if s.Admins != nil {
+			s.Admins.ReadOnly = true
+		}
+	}
 	s.setRoutes()
 
 	s.shutdownCh = make(chan os.Signal, 1)
@@ -67,33 +237,127 @@ func (s *Server) init() {
 	s.workerKillCh = workerKillCh
 	s.worker = &worker{
 		alerts: s.Alerts,
-		d:      s.interval(),
+		d:      s.Interval,
+		maxD:   s.MaxInterval,
 		killCh: workerKillCh,
+		clock:  s.Alerts.Clock,
 	}
+	s.handler.worker = s.worker
+	s.freshnessKillCh = make(chan struct{}, 1)
+	s.fetchesKillCh = make(chan struct{}, 1)
+	s.refreshKillCh = make(chan struct{}, 1)
 
 	s.wg = &sync.WaitGroup{}
 }
 
 func (s *Server) setRoutes() {
+	s.Router.Use(RequestID)
+
+	if s.Routes != nil {
+		s.Routes(s.Router)
+	}
+
 	s.Router.Get("/", s.handler.HelloWorld())
-	s.Router.Get("/alerts", s.handler.HandleGetAlerts())
-	s.Router.Get("/forecasts", s.handler.HandleGetForecast())
 
-	// Set the admin routes.
 	adminValidater := AdminValidater{
 		admins: s.Admins,
 		logger: s.Logger,
 	}
 
+	// Set the public read routes. Each request is given a deadline
+	// so a slow downstream call cannot tie up resources. Admins are
+	// identified on these routes when upstream errors are exposed.
+	// Requests are rate limited per client IP before any work is done.
+	timeout := s.RequestTimeout
+	var limiter *RateLimiter
+	if s.RateLimit > 0 {
+		limiter = NewRateLimiter(s.RateLimit, s.RateBurst, s.Alerts.Clock, s.Logger)
+	}
+	read := func(h http.HandlerFunc) http.HandlerFunc {
+		if s.ExposeUpstreamErrors && s.Admins != nil {
+			h = adminValidater.Identify(h)
+		}
+		h = Deadline(timeout, h)
+		if limiter != nil {
+			h = limiter.Limit(h)
+		}
+		return h
+	}
+	s.Router.Get("/alerts", read(s.handler.HandleGetAlerts()))
+	s.Router.Get("/alerts/feed", read(s.handler.HandleGetAlertFeed()))
+	s.Router.Get("/alerts/count", read(s.handler.HandleGetAlertCount()))
+	s.Router.Get("/alerts/state/{state}", read(s.handler.HandleGetStateAlerts()))
+	s.Router.Get("/alerts/search", read(s.handler.HandleSearchAlerts()))
+	s.Router.Get("/alerts/nearby", read(s.handler.HandleGetNearbyAlerts()))
+	s.Router.Get("/alerts/geojson", read(s.handler.HandleGetAlertsGeoJSON()))
+	s.Router.Get("/alerts/bbox", read(s.handler.HandleGetBBoxAlerts()))
+	s.Router.Get("/alerts/{id}", read(s.handler.HandleGetAlert()))
+	s.Router.Get("/forecasts", read(s.handler.HandleGetForecast()))
+	s.Router.Get("/forecasts/compare", read(s.handler.HandleGetForecastComparison()))
+	s.Router.Get("/forecasts/now", read(s.handler.HandleGetCurrentForecast()))
+	s.Router.Get("/forecasts/grid", read(s.handler.HandleGetGridForecast()))
+
+	if s.Admins != nil {
+		s.setAdminRoutes(adminValidater)
+	}
+}
+
+func (s *Server) setAdminRoutes(adminValidater AdminValidater) {
+	// Mutating routes are rejected in read-only mode.
+	mutate := func(h http.HandlerFunc) http.HandlerFunc {
+		return h
+	}
+	if s.ReadOnly {
+		mutate = ReadOnly(s.Logger)
+	}
+
+	// Mutating admin routes are recorded in the audit log.
+	auditor := Auditor{
+		admins: s.Admins,
+		logger: s.Logger,
+	}
+
 	s.Router.Post("/admins/login", s.handler.HandlePostLogin())
-	s.Router.Post("/admins/signup", s.handler.HandlePostSignup())
-	s.Router.Post("/admins/states", adminValidater.Validate(s.handler.HandleCreateState()))
-	s.Router.Post("/admins/states/sync", adminValidater.Validate(s.handler.HandleSyncState()))
+	s.Router.Post("/admins/signup", mutate(s.handler.HandlePostSignup()))
+	s.Router.Get("/admins/audit", adminValidater.Validate(s.handler.HandleGetAudit()))
+	s.Router.Get("/admins/alerts/runs", adminValidater.Validate(s.handler.HandleGetAlertRuns()))
+	s.Router.Post("/admins/alerts/zones", mutate(adminValidater.Validate(
+		auditor.Audit("alert.zones", queryTarget("q"), s.handler.HandleRebuildAlertZones()))))
+	s.Router.Get("/admins/worker/status", adminValidater.Validate(s.handler.HandleGetWorkerStatus()))
+	if s.Metrics != nil || s.Freshness != nil {
+		s.Router.Get("/admins/metrics", adminValidater.Validate(s.handler.HandleGetMetrics()))
+	}
+	s.Router.Post("/admins/geometry/validate", adminValidater.Validate(s.handler.HandlePostValidateGeometry()))
+	s.Router.Post("/admins/states", mutate(adminValidater.Validate(
+		auditor.Audit("state.create", queryTarget("q"), s.handler.HandleCreateState()))))
+	s.Router.Post("/admins/states/sync", mutate(adminValidater.Validate(
+		auditor.Audit("state.sync", queryTarget("q"), s.handler.HandleSyncState()))))
+	s.Router.Get("/admins/states/geometry", adminValidater.Validate(s.handler.HandleGetGeometryAudit()))
+	s.Router.Get("/admins/states/{state}/zones", adminValidater.Validate(s.handler.HandleSearchZones()))
+	s.Router.Get("/admins/states/{state}/consistency", adminValidater.Validate(s.handler.HandleGetStateConsistency()))
+	s.Router.Post("/admins/states/retry", mutate(adminValidater.Validate(
+		auditor.Audit("state.retry", queryTarget("q"), s.handler.HandleRetryZones()))))
+	s.Router.Put("/admins/states/priority", mutate(adminValidater.Validate(
+		auditor.Audit("state.priority", queryTarget("q"), s.handler.HandleSetStatePriority()))))
+	s.Router.Get("/admins/forecasts/gridpoint", adminValidater.Validate(s.handler.HandleGetGridpoint()))
+	s.Router.Get("/admins/forecasts/bbox", adminValidater.Validate(s.handler.HandleGetBBoxGridpoints()))
+	s.Router.Get("/admins/forecasts/diagnose", adminValidater.Validate(s.handler.HandleGetForecastDiagnosis()))
+	s.Router.Get("/admins/forecasts/asof", adminValidater.Validate(s.handler.HandleGetForecastAsOf()))
+	s.Router.Get("/admins/forecasts/remapped", adminValidater.Validate(s.handler.HandleGetRemappedGridpoints()))
+	s.Router.Get("/admins/forecasts/overlaps", adminValidater.Validate(s.handler.HandleGetOverlappingGridpoints()))
+	s.Router.Post("/admins/forecasts/warm", mutate(adminValidater.Validate(
+		auditor.Audit("forecast.warm", noTarget, s.handler.HandlePostWarmForecasts()))))
+	s.Router.Delete("/admins/gridpoints/stale", mutate(adminValidater.Validate(
+		auditor.Audit("gridpoint.evict", queryTarget("older_than"), s.handler.HandleDeleteStaleGridpoints()))))
+	s.Router.Post("/admins/reset", mutate(adminValidater.Validate(
+		auditor.Audit("data.reset", noTarget, s.handler.HandleReset()))))
+	s.Router.Delete("/admins/{id}", mutate(adminValidater.Validate(
+		auditor.Audit("admin.delete", urlTarget("id"), s.handler.HandleDeleteAdmin()))))
 }
 
 func (s *Server) run(runFn func()) {
+	s.wg.Add(1)
 	go func() {
-		s.wg.Add(1)
 		defer s.wg.Done()
 
 		runFn()
@@ -125,6 +389,9 @@ func (s *Server) listenAndServe() error {
 
 			// Kill background worker.
 			s.workerKillCh <- struct{}{}
+			s.freshnessKillCh <- struct{}{}
+			s.fetchesKillCh <- struct{}{}
+			s.refreshKillCh <- struct{}{}
 
 			// Wait for all resources to stop.
 			s.wg.Wait()
@@ -139,43 +406,37 @@ func (s *Server) listenAndServe() error {
 	return nil
 }
 
-func (s *Server) validate() error {
-	if s.Router == nil {
-		return errors.New("router is nil")
-	}
-
-	if s.Logger == nil {
-		return errors.New("logger is nil")
-	}
-
-	if s.States == nil {
-		return errors.New("states is nil")
-	}
-
-	if s.Alerts == nil {
-		return errors.New("alerts is nil")
+// Start validates the Config of this server, sets its defaults and
+// serves until the server is shutdown. An error is returned if a
+// required dependency is missing.
+func (s *Server) Start() error {
+	if err := s.Config.validate(); err != nil {
+		return err
 	}
+	s.Config = s.Config.withDefaults()
 
-	if s.Forecasts == nil {
-		return errors.New("forecasts is nil")
-	}
+	s.init()
 
-	if s.Admins == nil {
-		return errors.New("admins is nil")
+	// A read-only server never syncs alerts or counts fetches.
+	if !s.ReadOnly {
+		s.run(func() {
+			s.worker.start()
+		})
+		s.run(func() {
+			s.Forecasts.RunFetchFlush(s.FetchFlushInterval, s.fetchesKillCh)
+		})
+		if s.RefreshInterval > 0 {
+			s.run(func() {
+				s.Forecasts.RunRefresh(s.RefreshInterval, s.refreshKillCh)
+			})
+		}
 	}
 
-	return nil
-}
-
-func (s *Server) Start() error {
-	if err := s.validate(); err != nil {
-		return err
+	if s.Freshness != nil {
+		s.run(func() {
+			s.Freshness.Run(s.FreshnessInterval, s.freshnessKillCh)
+		})
 	}
 
-	s.init()
-	s.run(func() {
-		s.worker.start()
-	})
-
 	return s.listenAndServe()
 }
@@ -3,28 +3,137 @@ package server
 import (
 	"context"
 	"log"
+	"sync"
 	"time"
 
 	"github.com/cicconee/weather-app/internal/alert"
+	"github.com/cicconee/weather-app/internal/app"
 )
 
 type worker struct {
 	alerts *alert.Service
 	d      time.Duration
+	maxD   time.Duration
 	killCh <-chan struct{}
+
+	// clock is the time syncs are recorded at. Nil uses the
+	// system time.
+	clock app.Clock
+
+	mu     sync.Mutex
+	status WorkerStatus
+}
+
+// WorkerStatus is the state of the background alert sync
+// worker.
+type WorkerStatus struct {
+	// Running is true while the worker is started.
+	Running bool `json:"running"`
+
+	// The time the last sync finished, successful or not.
+	LastRun *app.Time `json:"last_run,omitempty"`
+
+	// The time the last successful sync finished.
+	LastSuccess *app.Time `json:"last_success,omitempty"`
+
+	// The error of the last sync. It is empty if the last
+	// sync was successful.
+	LastError string `json:"last_error,omitempty"`
+
+	// The alerts written by the last successful sync.
+	LastWrites int `json:"last_writes"`
+
+	// The number of syncs that failed in a row. It is reset
+	// by a successful sync.
+	ConsecutiveFailures int `json:"consecutive_failures"`
+
+	// The time until the next sync. It grows while syncs keep
+	// failing.
+	Interval string `json:"interval"`
+}
+
+// Status returns the current status of the worker. It is
+// safe to call while the worker is running.
+func (w *worker) Status() WorkerStatus {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	return w.status
+}
+
+func (w *worker) setRunning(running bool) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.status.Running = running
+}
+
+// record records the result of a sync that finished at t.
+func (w *worker) record(t time.Time, sync alert.SyncResult, err error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	at := app.Time(t)
+	w.status.LastRun = &at
+	if err != nil {
+		w.status.LastError = err.Error()
+		w.status.ConsecutiveFailures++
+		return
+	}
+
+	w.status.LastSuccess = &at
+	w.status.LastError = ""
+	w.status.LastWrites = sync.TotalWrites
+	w.status.ConsecutiveFailures = 0
+}
+
+// next returns how long to wait before the next sync and records it
+// in the status. The interval is doubled for each consecutive failed
+// sync, up to maxD.
+func (w *worker) next() time.Duration {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	d := backoff(w.d, w.maxD, w.status.ConsecutiveFailures)
+	w.status.Interval = d.String()
+	return d
+}
+
+// backoff returns the interval d doubled for each of failures, up to
+// max. If max is less than d, d is returned.
+func backoff(d time.Duration, max time.Duration, failures int) time.Duration {
+	if max < d {
+		return d
+	}
+
+	for i := 0; i < failures && d < max; i++ {
+		d *= 2
+	}
+
+	if d > max {
+		return max
+	}
+
+	return d
 }
 
 func (w *worker) start() {
-	ticker := time.NewTicker(w.d)
+	w.setRunning(true)
+	defer w.setRunning(false)
+
+	// A timer is used instead of a ticker so the interval can
+	// back off while syncs are failing.
+	timer := time.NewTimer(w.next())
 
 	for {
 		select {
-		case <-ticker.C:
+		case <-timer.C:
 			// Execute any jobs.
 			ctx := context.Background()
 			w.syncAlerts(ctx)
+			timer.Reset(w.next())
 		case <-w.killCh:
-			ticker.Stop()
+			timer.Stop()
 			// TODO: clean up any running jobs.
 			return
 		}
@@ -33,6 +142,7 @@ func (w *worker) start() {
 
 func (w *worker) syncAlerts(ctx context.Context) {
 	sync, err := w.alerts.Sync(ctx)
+	w.record(app.Now(w.clock).UTC(), sync, err)
 	if err != nil {
 		log.Printf("failed syncing alerts: %v\n", err)
 	} else {
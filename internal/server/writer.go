@@ -1,28 +1,51 @@
 package server
 
 import (
+	"context"
 	"encoding/json"
+	"encoding/xml"
 	"errors"
 	"fmt"
+	"io"
 	"log"
+	"net"
 	"net/http"
+	"strings"
+	"time"
+
+	"github.com/cicconee/weather-app/internal/alert"
+	"github.com/cicconee/weather-app/internal/app"
 )
 
+// StatusClientClosedRequest is the non-standard status code used
+// when the client cancels a request before a response is written.
+const StatusClientClosedRequest = 499
+
 type LogWriter struct {
 	logger *log.Logger
 	rw     http.ResponseWriter
 	r      *http.Request
+
+	// upstream includes the NWS API status code and detail
+	// in error responses. It must only be set for admins.
+	upstream bool
 }
 
 func NewLogWriter(l *log.Logger, rw http.ResponseWriter, r *http.Request) *LogWriter {
-	return &LogWriter{l, rw, r}
+	return &LogWriter{logger: l, rw: rw, r: r}
 }
 
 func (l *LogWriter) log(format string, v ...any) {
 	l.logger.Println(fmt.Sprintf(format, v...))
 }
 
+// Write writes r as JSON. If the client accepts EnvelopeMediaType the
+// body is wrapped in a Envelope.
 func (l *LogWriter) Write(r Response) {
+	if l.accepts(EnvelopeMediaType) {
+		r = r.envelope(requestID(l.r.Context()), time.Now().UTC())
+	}
+
 	l.rw.Header().Set("Content-Type", "application/json")
 	l.rw.WriteHeader(r.Status)
 	if err := json.NewEncoder(l.rw).Encode(r.Body); err != nil {
@@ -30,20 +53,122 @@ func (l *LogWriter) Write(r Response) {
 	}
 }
 
+// WriteXML writes r as XML with the content type contentType. The
+// body is never wrapped in a Envelope.
+func (l *LogWriter) WriteXML(r Response, contentType string) {
+	l.rw.Header().Set("Content-Type", contentType)
+	l.rw.WriteHeader(r.Status)
+	if _, err := io.WriteString(l.rw, xml.Header); err != nil {
+		l.log("*LogWriter.WriteXML: failed to write xml header to http.ResponseWriter: %v\n", err)
+		return
+	}
+	if err := xml.NewEncoder(l.rw).Encode(r.Body); err != nil {
+		l.log("*LogWriter.WriteXML: failed to write xml to http.ResponseWriter: %v\n", err)
+	}
+}
+
+// featureWriter streams alerts to rw as a GeoJSON FeatureCollection.
+// Nothing is written until the first feature, or Close, so a error
+// before then can still be written with WriteError.
+type featureWriter struct {
+	rw      http.ResponseWriter
+	started bool
+}
+
+func (f *featureWriter) start() error {
+	f.started = true
+	f.rw.Header().Set("Content-Type", GeoJSONMediaType)
+	f.rw.WriteHeader(http.StatusOK)
+	_, err := io.WriteString(f.rw, `{"type":"FeatureCollection","features":[`)
+	return err
+}
+
+// Write writes feature to the collection.
+func (f *featureWriter) Write(feature alert.Feature) error {
+	b, err := json.Marshal(feature)
+	if err != nil {
+		return err
+	}
+
+	if !f.started {
+		if err := f.start(); err != nil {
+			return err
+		}
+	} else if _, err := io.WriteString(f.rw, ","); err != nil {
+		return err
+	}
+
+	_, err = f.rw.Write(b)
+	return err
+}
+
+// Close ends the collection. A collection without features is
+// written as empty.
+func (f *featureWriter) Close() error {
+	if !f.started {
+		if err := f.start(); err != nil {
+			return err
+		}
+	}
+
+	_, err := io.WriteString(f.rw, "]}\n")
+	return err
+}
+
+// accepts reports if the client accepts mediaType.
+func (l *LogWriter) accepts(mediaType string) bool {
+	return strings.Contains(l.r.Header.Get("Accept"), mediaType)
+}
+
 type ServerErrorResponser interface {
 	ServerErrorResponse() (int, string)
 }
 
+// WriteError writes err as a ErrorResponse. If err is a
+// ServerErrorResponser its status code and message are used.
+//
+// Errors caused by a context deadline or a network timeout
+// are written as a 504, and errors caused by the client
+// canceling the request are written as a 499. All other
+// errors are written as a 500.
+//
+// If this LogWriter writes upstream errors and err was
+// caused by a NWS API status code, the status code and
+// detail are included in the response.
 func (w *LogWriter) WriteError(err error) {
+	errResp := errorResponse(err)
+
+	var statusErr *app.NWSAPIStatusCodeError
+	if w.upstream && errors.As(err, &statusErr) {
+		errResp.Upstream = &UpstreamError{
+			Status: statusErr.StatusCode,
+			Detail: statusErr.Detail,
+		}
+	}
+
+	w.Write(errResp.AsResponse())
+}
+
+// errorResponse returns the ErrorResponse that err is written
+// as by WriteError, without any upstream error.
+func errorResponse(err error) ErrorResponse {
 	errResp := ErrorResponse{
 		Status:   http.StatusInternalServerError,
 		ErrorMsg: "Something went wrong",
 	}
 
 	var apiError ServerErrorResponser
-	if errors.As(err, &apiError) {
+	var netErr net.Error
+	switch {
+	case errors.As(err, &apiError):
 		errResp.Status, errResp.ErrorMsg = apiError.ServerErrorResponse()
+	case errors.Is(err, context.DeadlineExceeded), errors.As(err, &netErr) && netErr.Timeout():
+		errResp.Status = http.StatusGatewayTimeout
+		errResp.ErrorMsg = "Upstream timed out"
+	case errors.Is(err, context.Canceled):
+		errResp.Status = StatusClientClosedRequest
+		errResp.ErrorMsg = "Request canceled"
 	}
 
-	w.Write(errResp.AsResponse())
+	return errResp
 }
@@ -1,6 +1,11 @@
 package state
 
-import "context"
+import (
+	"context"
+	"database/sql"
+
+	"github.com/cicconee/weather-app/internal/database"
+)
 
 // AlertZone is a alert that falls in the
 // boundary of a zone and the zone is persisted
@@ -70,15 +75,16 @@ func (a *LonelyAlertCollection) Select(ctx context.Context, db Queryer, zoneURI
 	if err != nil {
 		return err
 	}
-	defer rows.Close()
 
-	for rows.Next() {
+	alerts, err := database.ScanRows(rows, func(rows *sql.Rows) (LonelyAlert, error) {
 		alert := LonelyAlert{}
-		if alert.scan(rows.Scan); err != nil {
-			return err
-		}
-		*a = append(*a, alert)
+		err := alert.scan(rows.Scan)
+		return alert, err
+	})
+	if err != nil {
+		return err
 	}
+	*a = append(*a, alerts...)
 
 	return nil
 }
@@ -0,0 +1,45 @@
+package state
+
+import (
+	"fmt"
+	"net/http"
+)
+
+// codes are the area codes the NWS API supports. These are
+// the US states, territories and marine areas.
+var codes = map[string]bool{
+	// States and the District of Columbia.
+	"AL": true, "AK": true, "AZ": true, "AR": true, "CA": true, "CO": true,
+	"CT": true, "DE": true, "DC": true, "FL": true, "GA": true, "HI": true,
+	"ID": true, "IL": true, "IN": true, "IA": true, "KS": true, "KY": true,
+	"LA": true, "ME": true, "MD": true, "MA": true, "MI": true, "MN": true,
+	"MS": true, "MO": true, "MT": true, "NE": true, "NV": true, "NH": true,
+	"NJ": true, "NM": true, "NY": true, "NC": true, "ND": true, "OH": true,
+	"OK": true, "OR": true, "PA": true, "RI": true, "SC": true, "SD": true,
+	"TN": true, "TX": true, "UT": true, "VT": true, "VA": true, "WA": true,
+	"WV": true, "WI": true, "WY": true,
+
+	// Territories and freely associated states.
+	"AS": true, "GU": true, "MP": true, "PR": true, "VI": true, "FM": true,
+	"MH": true, "PW": true,
+
+	// Marine areas.
+	"AM": true, "AN": true, "GM": true, "LC": true, "LE": true, "LH": true,
+	"LM": true, "LO": true, "LS": true, "PH": true, "PK": true, "PM": true,
+	"PS": true, "PZ": true, "SL": true,
+}
+
+// validateCode returns a Error with a 400 status code if
+// stateID is not a area code the NWS API supports. The
+// stateID must already be upper case.
+func validateCode(stateID string) error {
+	if codes[stateID] {
+		return nil
+	}
+
+	return &Error{
+		error:      fmt.Errorf("state %q is not a supported area code", stateID),
+		msg:        fmt.Sprintf("%q is not a US state, territory or marine area code", stateID),
+		statusCode: http.StatusBadRequest,
+	}
+}
@@ -4,13 +4,20 @@ type ZoneDelta struct {
 	Insert []Zone
 	Update []Zone
 	Delete []Zone
+
+	// The fields that changed for each zone in
+	// Update, keyed by URI. Geometry is not known
+	// until the zone is fetched, so it is not
+	// included.
+	Changes map[string][]string
 }
 
 func NewZoneDelta() *ZoneDelta {
 	return &ZoneDelta{
-		Insert: []Zone{},
-		Update: []Zone{},
-		Delete: []Zone{},
+		Insert:  []Zone{},
+		Update:  []Zone{},
+		Delete:  []Zone{},
+		Changes: map[string][]string{},
 	}
 }
 
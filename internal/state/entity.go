@@ -30,8 +30,13 @@ func (e *Entity) Select(ctx context.Context, db QueryRower) error {
 	)
 }
 
+// Insert writes this entity to the database. If a state
+// with the same id is already stored, such as by a
+// concurrent save, nothing is written and the result
+// reports zero rows affected.
 func (e *Entity) Insert(ctx context.Context, db Execer) (sql.Result, error) {
-	query := "INSERT INTO states(id, total_zones, created_at, updated_at) VALUES($1, $2, $3, $4)"
+	query := `INSERT INTO states(id, total_zones, created_at, updated_at) VALUES($1, $2, $3, $4) 
+			  ON CONFLICT (id) DO NOTHING`
 
 	return db.ExecContext(ctx, query,
 		e.ID,
@@ -56,3 +61,18 @@ func (e *Entity) Update(ctx context.Context, db Execer) (sql.Result, error) {
 		e.ID,
 	)
 }
+
+// UpdatePriority marks the entity as a priority state
+// at t if priority is true, otherwise the mark is removed.
+// When any states are marked, alerts are only synced
+// for the priority states.
+func (e *Entity) UpdatePriority(ctx context.Context, db Execer, priority bool, t time.Time) (sql.Result, error) {
+	if !priority {
+		return db.ExecContext(ctx, "DELETE FROM states_priority WHERE state = $1", e.ID)
+	}
+
+	query := `INSERT INTO states_priority(state, created_at) VALUES($1, $2) 
+			  ON CONFLICT (state) DO NOTHING`
+
+	return db.ExecContext(ctx, query, e.ID, t)
+}
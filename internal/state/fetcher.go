@@ -3,7 +3,6 @@ package state
 import (
 	"context"
 
-	"github.com/cicconee/weather-app/internal/nws"
 	"github.com/cicconee/weather-app/internal/pool"
 )
 
@@ -13,13 +12,13 @@ type FetchFailure struct {
 }
 
 type Fetcher struct {
-	client *nws.Client
+	client ZoneAPI
 	p      *pool.Pool
 	dataCh chan Zone
 	failCh chan FetchFailure
 }
 
-func NewFetcher(c *nws.Client, p *pool.Pool, s *Store, zoneCount int) *Fetcher {
+func NewFetcher(c ZoneAPI, p *pool.Pool, s *Store, zoneCount int) *Fetcher {
 	return &Fetcher{
 		client: c,
 		p:      p,
@@ -86,6 +85,13 @@ func (f *Fetcher) FetchEach(ctx context.Context, zones []Zone) FetchResult {
 }
 
 func (f *Fetcher) Fetch(ctx context.Context, z Zone) {
+	// The geometry was included with the zone,
+	// there is nothing to fetch.
+	if len(z.Geometry) > 0 {
+		f.finish(z)
+		return
+	}
+
 	f.p.Add(func() {
 		// Check if context has already been
 		// cancelled or timed out before executing
@@ -108,7 +114,7 @@ func (f *Fetcher) Fetch(ctx context.Context, z Zone) {
 }
 
 func (f *Fetcher) fetch(ctx context.Context, zoneType string, zoneCode string) (Zone, error) {
-	nwsZone, err := f.client.GetZone(zoneType, zoneCode)
+	nwsZone, err := f.client.GetZone(ctx, zoneType, zoneCode)
 	if err != nil {
 		return Zone{}, err
 	}
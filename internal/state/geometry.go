@@ -18,6 +18,72 @@ func (g *Geometry) Delete(ctx context.Context, db Execer, zoneID int) (sql.Resul
 	return db.ExecContext(ctx, query, zoneID)
 }
 
+// Select reads all perimeters, and their holes, in the
+// database associated with zoneID into this Geometry.
+// The perimeters are read in the order they were inserted.
+func (g *Geometry) Select(ctx context.Context, db *sql.DB, zoneID int) error {
+	query := `SELECT id, boundary FROM state_zone_perimeters WHERE sz_id = $1 ORDER BY id`
+
+	rows, err := db.QueryContext(ctx, query, zoneID)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var boundary string
+		perimeter := Perimeter{ZoneID: zoneID}
+		if err := rows.Scan(&perimeter.ID, &boundary); err != nil {
+			return err
+		}
+
+		if perimeter.Points, err = geometry.ParsePointCollection(boundary); err != nil {
+			return err
+		}
+
+		*g = append(*g, perimeter)
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for i := range *g {
+		if err := (*g)[i].Holes.Select(ctx, db, (*g)[i].ID); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Equal returns true if this Geometry and other would be
+// stored the same. Perimeters and holes are compared in
+// order using the format they are written to the database
+// in, so coordinates are compared to 6 decimal places.
+func (g Geometry) Equal(other Geometry) bool {
+	if len(g) != len(other) {
+		return false
+	}
+
+	for i := range g {
+		if g[i].Points.String() != other[i].Points.String() {
+			return false
+		}
+
+		if len(g[i].Holes) != len(other[i].Holes) {
+			return false
+		}
+
+		for j := range g[i].Holes {
+			if g[i].Holes[j].Points.String() != other[i].Holes[j].Points.String() {
+				return false
+			}
+		}
+	}
+
+	return true
+}
+
 func NewGeometry(mp geometry.MultiPolygon) Geometry {
 	g := Geometry{}
 
@@ -80,6 +146,34 @@ func NewHoleCollection(geoHoles []geometry.PointCollection) HoleCollection {
 	return h
 }
 
+// Select reads all holes in the database associated with
+// the perimeter perimeterID into this HoleCollection.
+func (h *HoleCollection) Select(ctx context.Context, db *sql.DB, perimeterID int) error {
+	query := `SELECT id, boundary FROM state_zone_holes WHERE zp_id = $1 ORDER BY id`
+
+	rows, err := db.QueryContext(ctx, query, perimeterID)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var boundary string
+		hole := Hole{PerimieterID: perimeterID}
+		if err := rows.Scan(&hole.ID, &boundary); err != nil {
+			return err
+		}
+
+		if hole.Points, err = geometry.ParsePointCollection(boundary); err != nil {
+			return err
+		}
+
+		*h = append(*h, hole)
+	}
+
+	return rows.Err()
+}
+
 type Hole struct {
 	ID           int
 	PerimieterID int
@@ -97,3 +191,64 @@ func (h *Hole) Insert(ctx context.Context, db QueryRower) error {
 		h.Points.String(),
 	).Scan(&h.ID)
 }
+
+// MinRingPoints is the fewest points a stored ring
+// needs to enclose an area.
+const MinRingPoints = 3
+
+// GeometryIssue is a zone with missing or invalid
+// geometry. A zone without perimeters can never be
+// matched to a point, so its alerts are never found.
+type GeometryIssue struct {
+	ZoneID int    `json:"zone_id"`
+	URI    string `json:"uri"`
+	Code   string `json:"code"`
+	Type   string `json:"type"`
+
+	// The number of perimeters stored for the zone.
+	Perimeters int `json:"perimeters"`
+
+	// The number of perimeters with fewer than
+	// MinRingPoints points.
+	InvalidPerimeters int `json:"invalid_perimeters"`
+}
+
+type GeometryIssueCollection []GeometryIssue
+
+// Select reads the zones of the state stateID that have
+// no perimeters or have a perimeter with fewer than
+// MinRingPoints points into this collection.
+func (g *GeometryIssueCollection) Select(ctx context.Context, db *sql.DB, stateID string) error {
+	query := `
+		SELECT z.id, z.uri, z.code, z.type, COUNT(p.id), 
+			COUNT(p.id) FILTER (WHERE npoints(p.boundary) < $2)
+		FROM state_zones AS z
+		LEFT JOIN state_zone_perimeters AS p ON p.sz_id = z.id
+		WHERE z.state = $1
+		GROUP BY z.id
+		HAVING COUNT(p.id) = 0 OR COUNT(p.id) FILTER (WHERE npoints(p.boundary) < $2) > 0
+		ORDER BY z.id`
+
+	rows, err := db.QueryContext(ctx, query, stateID, MinRingPoints)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var issue GeometryIssue
+		if err := rows.Scan(
+			&issue.ZoneID,
+			&issue.URI,
+			&issue.Code,
+			&issue.Type,
+			&issue.Perimeters,
+			&issue.InvalidPerimeters,
+		); err != nil {
+			return err
+		}
+		*g = append(*g, issue)
+	}
+
+	return rows.Err()
+}
@@ -13,6 +13,13 @@ func (s *SaveResult) TotalZones() int {
 	return len(s.Writes) + len(s.Fails)
 }
 
+// IsPartial returns true if any zones failed to
+// save. The failed zones can be retried without
+// saving the state again.
+func (s *SaveResult) IsPartial() bool {
+	return len(s.Fails) > 0
+}
+
 type SaveZoneResult struct {
 	Writes []Zone
 	Fails  []SaveZoneFailure
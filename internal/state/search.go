@@ -0,0 +1,122 @@
+package state
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/cicconee/weather-app/internal/app"
+)
+
+// ZoneMatch is a stored zone matched by a zone search.
+type ZoneMatch struct {
+	ID            int      `json:"id"`
+	URI           string   `json:"uri"`
+	Code          string   `json:"code"`
+	Type          string   `json:"type"`
+	Name          string   `json:"name"`
+	EffectiveDate app.Time `json:"effective_date"`
+	UpdatedAt     app.Time `json:"updated_at"`
+}
+
+// ZoneMatchCollection is the zones matched by a zone search.
+type ZoneMatchCollection []ZoneMatch
+
+// ZoneFilter limits the zones matched by a zone search. The zero
+// ZoneFilter matches every zone of a state.
+type ZoneFilter struct {
+	// Query is optional. If set, only the zones whose name or code
+	// contains it, ignoring case, are matched.
+	Query string
+
+	// EffectiveBefore is optional. If set, only the zones with an
+	// effective date before it are matched.
+	EffectiveBefore *time.Time
+
+	// EffectiveAfter is optional. If set, only the zones with an
+	// effective date after it are matched.
+	EffectiveAfter *time.Time
+}
+
+// where returns the conditions of this filter for the zones of the
+// state stateID and their arguments.
+func (f ZoneFilter) where(stateID string) (string, []any) {
+	where := []string{"state = $1"}
+	args := []any{stateID}
+	if f.Query != "" {
+		args = append(args, containsPattern(f.Query))
+		where = append(where, fmt.Sprintf("(name ILIKE $%[1]d OR code ILIKE $%[1]d)", len(args)))
+	}
+	if f.EffectiveBefore != nil {
+		args = append(args, *f.EffectiveBefore)
+		where = append(where, fmt.Sprintf("effective_date < $%d", len(args)))
+	}
+	if f.EffectiveAfter != nil {
+		args = append(args, *f.EffectiveAfter)
+		where = append(where, fmt.Sprintf("effective_date > $%d", len(args)))
+	}
+
+	return strings.Join(where, " AND "), args
+}
+
+// Select reads a page of the zones of the state stateID that match
+// filter into this collection. The zones are ordered by code.
+func (z *ZoneMatchCollection) Select(ctx context.Context, db Queryer, stateID string, filter ZoneFilter, limit int, offset int) error {
+	where, args := filter.where(stateID)
+	query := fmt.Sprintf(`
+		SELECT id, uri, code, type, name, effective_date, updated_at
+		FROM state_zones
+		WHERE %s
+		ORDER BY code, id
+		LIMIT $%d OFFSET $%d`, where, len(args)+1, len(args)+2)
+
+	rows, err := db.QueryContext(ctx, query, append(args, limit, offset)...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			match         ZoneMatch
+			effectiveDate time.Time
+			updatedAt     time.Time
+		)
+		if err := rows.Scan(
+			&match.ID,
+			&match.URI,
+			&match.Code,
+			&match.Type,
+			&match.Name,
+			&effectiveDate,
+			&updatedAt,
+		); err != nil {
+			return err
+		}
+		match.EffectiveDate = app.Time(effectiveDate)
+		match.UpdatedAt = app.Time(updatedAt)
+		*z = append(*z, match)
+	}
+
+	return rows.Err()
+}
+
+// Count returns the number of zones of the state stateID that match
+// filter.
+func (z *ZoneMatchCollection) Count(ctx context.Context, db QueryRower, stateID string, filter ZoneFilter) (int, error) {
+	where, args := filter.where(stateID)
+	query := fmt.Sprintf(`SELECT COUNT(*) FROM state_zones WHERE %s`, where)
+
+	var total int
+	err := db.QueryRowContext(ctx, query, args...).Scan(&total)
+
+	return total, err
+}
+
+// containsPattern returns a ILIKE pattern matching text that contains
+// q. The wildcards of q are escaped so they are matched literally.
+func containsPattern(q string) string {
+	escaper := strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+	return "%" + escaper.Replace(q) + "%"
+}
@@ -5,6 +5,7 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
+	"log"
 	"net/http"
 	"strings"
 	"time"
@@ -14,23 +15,108 @@ import (
 	"github.com/cicconee/weather-app/internal/pool"
 )
 
+// ZoneAPI is the interface that wraps the GetZone,
+// GetZoneCollection and GetZoneCollectionWithGeometry methods.
+//
+// GetZone executes a HTTP GET request to the following url:
+// https://api.weather.gov/zones/{type}/{code}
+// It returns the zone with its geometry and any errors encountered.
+//
+// GetZoneCollection executes a HTTP GET request to the following url:
+// https://api.weather.gov/zones?area={area}
+// It returns the zones of the area, without geometry, and any errors
+// encountered.
+//
+// GetZoneCollectionWithGeometry executes the same request as
+// GetZoneCollection but asks for the geometry of each zone.
+//
+// *nws.Client implements ZoneAPI.
+type ZoneAPI interface {
+	GetZone(context.Context, string, string) (nws.Zone, error)
+	GetZoneCollection(context.Context, string) ([]nws.Zone, error)
+	GetZoneCollectionWithGeometry(context.Context, string) ([]nws.Zone, error)
+}
+
 type Service struct {
-	Client *nws.Client
+	// The interface that will make network calls to the NWS API to
+	// get the zones of a state.
+	Client ZoneAPI
 	Store  *Store
 	Pool   *pool.Pool
+
+	// InlineGeometry gets the geometry of the zones in the
+	// same NWS API request as the zones of a state, rather
+	// than a request per zone. Zones returned without
+	// geometry are still fetched one at a time.
+	InlineGeometry bool
+
+	// MaxZones is the most zones a state may have. A
+	// state with more zones is rejected before any zone
+	// is fetched or written. Zero uses DefaultMaxZones.
+	MaxZones int
+
+	// Clock is the time the service reads as now, such as
+	// to record when a state or zone was written. Nil uses
+	// the system time.
+	Clock app.Clock
+}
+
+func (s *Service) now() time.Time {
+	return app.Now(s.Clock)
+}
+
+// DefaultMaxZones is the most zones a state may have
+// when a Service does not set MaxZones. It is well above
+// the zone count of any state.
+const DefaultMaxZones = 2000
+
+func (s *Service) maxZones() int {
+	if s.MaxZones <= 0 {
+		return DefaultMaxZones
+	}
+
+	return s.MaxZones
 }
 
-func New(c *nws.Client, db *sql.DB, p *pool.Pool) *Service {
+// ErrNilPool is returned when a Service is created or used
+// without a pool. The pool runs the zone requests of a state
+// and must be started before the Service is used.
+var ErrNilPool = errors.New("state: pool is nil")
+
+// ErrPoolNotStarted is returned when a Service is created with
+// a pool that has not been started. Nothing would ever run the
+// zone requests of a state added to it.
+var ErrPoolNotStarted = errors.New("state: pool is not started")
+
+// New creates a Service. It returns ErrNilPool if p is nil, and
+// ErrPoolNotStarted if p has not been started.
+func New(c ZoneAPI, db *sql.DB, p *pool.Pool) (*Service, error) {
+	if p == nil {
+		return nil, ErrNilPool
+	}
+
+	if !p.Started() {
+		return nil, ErrPoolNotStarted
+	}
+
 	return &Service{
 		Client: c,
 		Store:  NewStore(db),
 		Pool:   p,
-	}
+	}, nil
 }
 
 func (s *Service) Save(ctx context.Context, stateID string) (SaveResult, error) {
+	if s.Pool == nil {
+		return SaveResult{}, ErrNilPool
+	}
+
 	stateID = strings.ToUpper(stateID)
 
+	if err := validateCode(stateID); err != nil {
+		return SaveResult{}, err
+	}
+
 	_, err := s.Store.SelectEntity(ctx, stateID)
 	if err != nil && !errors.Is(err, sql.ErrNoRows) {
 		return SaveResult{}, fmt.Errorf("failed to select state %q: %w", stateID, err)
@@ -43,7 +129,7 @@ func (s *Service) Save(ctx context.Context, stateID string) (SaveResult, error)
 		}
 	}
 
-	zones, err := s.zones(stateID)
+	zones, err := s.zones(ctx, stateID)
 	if err != nil {
 		return SaveResult{}, fmt.Errorf("failed to get zones for %q: %w", stateID, err)
 	}
@@ -51,14 +137,29 @@ func (s *Service) Save(ctx context.Context, stateID string) (SaveResult, error)
 	state := Entity{
 		ID:         stateID,
 		TotalZones: len(zones),
-		CreatedAt:  time.Now().UTC(),
-		UpdatedAt:  time.Now().UTC(),
+		CreatedAt:  s.now().UTC(),
+		UpdatedAt:  s.now().UTC(),
 	}
-	if _, err = s.Store.InsertEntity(ctx, state); err != nil {
+	res, err := s.Store.InsertEntity(ctx, state)
+	if err != nil {
 		return SaveResult{}, fmt.Errorf("failed to insert state %q: %w", stateID, err)
 	}
 
-	w := newWorker(s.Client, s.Pool, s.Store, state.TotalZones)
+	// A concurrent save of the same state can insert it
+	// after the existence check above.
+	inserted, err := res.RowsAffected()
+	if err != nil {
+		return SaveResult{}, fmt.Errorf("failed to read rows inserted for state %q: %w", stateID, err)
+	}
+	if inserted == 0 {
+		return SaveResult{}, &Error{
+			error:      fmt.Errorf("state %q saved to database concurrently", stateID),
+			msg:        fmt.Sprintf("%s already exists", stateID),
+			statusCode: http.StatusConflict,
+		}
+	}
+
+	w := newWorker(s.Client, s.Pool, s.Store, s.Clock, state.TotalZones)
 	defer w.close()
 
 	// Fetch and write each zone to the
@@ -73,6 +174,175 @@ func (s *Service) Save(ctx context.Context, stateID string) (SaveResult, error)
 	}, nil
 }
 
+// SetPriority marks a saved state as a priority state, or
+// removes the mark if priority is false. When any states
+// are marked, alerts are only synced for the priority states.
+// Otherwise alerts are synced for all states.
+func (s *Service) SetPriority(ctx context.Context, stateID string, priority bool) error {
+	stateID = strings.ToUpper(stateID)
+
+	_, err := s.Store.SelectEntity(ctx, stateID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return &Error{
+			error:      fmt.Errorf("state %q not saved to database", stateID),
+			msg:        fmt.Sprintf("%s does not exist", stateID),
+			statusCode: http.StatusNotFound,
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("failed to select state %q: %w", stateID, err)
+	}
+
+	if _, err := s.Store.UpdatePriority(ctx, stateID, priority, s.now().UTC()); err != nil {
+		return fmt.Errorf("failed to update priority of state %q: %w", stateID, err)
+	}
+
+	return nil
+}
+
+// AuditGeometry returns the zones of a saved state that
+// have no stored perimeters or have a perimeter with too
+// few points. These zones can be repaired with RetryZones.
+func (s *Service) AuditGeometry(ctx context.Context, stateID string) (GeometryIssueCollection, error) {
+	stateID = strings.ToUpper(stateID)
+
+	if _, err := s.Store.SelectEntity(ctx, stateID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, &Error{
+				error:      fmt.Errorf("state not found in database (stateID=%q): %w", stateID, err),
+				msg:        fmt.Sprintf("%s not found", stateID),
+				statusCode: http.StatusNotFound,
+			}
+		}
+
+		return nil, fmt.Errorf("failed to select state in database (stateID=%q): %w", stateID, err)
+	}
+
+	issues, err := s.Store.AuditGeometry(ctx, stateID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to audit zone geometry (stateID=%q): %w", stateID, err)
+	}
+
+	return issues, nil
+}
+
+// Consistency compares the number of zones a state was
+// saved or synced with against the zones stored for it.
+// When they differ the last save or sync was partial and
+// the state should be resynced.
+type Consistency struct {
+	State string `json:"state"`
+
+	// The number of zones the NWS API listed for the
+	// state when it was last saved or synced.
+	TotalZones int `json:"total_zones"`
+
+	// The number of zones stored for the state.
+	WrittenZones int `json:"written_zones"`
+
+	Consistent bool   `json:"consistent"`
+	Suggestion string `json:"suggestion,omitempty"`
+}
+
+// Consistency reports if every zone of a saved state
+// was stored. A state with fewer, or more, stored zones
+// than its total zones was partially saved or synced.
+func (s *Service) Consistency(ctx context.Context, stateID string) (Consistency, error) {
+	stateID = strings.ToUpper(stateID)
+
+	state, err := s.Store.SelectEntity(ctx, stateID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return Consistency{}, &Error{
+				error:      fmt.Errorf("state not found in database (stateID=%q): %w", stateID, err),
+				msg:        fmt.Sprintf("%s not found", stateID),
+				statusCode: http.StatusNotFound,
+			}
+		}
+
+		return Consistency{}, fmt.Errorf("failed to select state in database (stateID=%q): %w", stateID, err)
+	}
+
+	c := Consistency{
+		State:        state.ID,
+		TotalZones:   state.TotalZones,
+		WrittenZones: state.WrittenZones,
+		Consistent:   state.TotalZones == state.WrittenZones,
+	}
+	if !c.Consistent {
+		c.Suggestion = fmt.Sprintf("%d of %d zones are stored, resync the state with POST /admins/states/sync?q=%s",
+			state.WrittenZones,
+			state.TotalZones,
+			state.ID)
+	}
+
+	return c, nil
+}
+
+// DefaultZoneSearchLimit is the number of zones SearchZones
+// returns when no limit is given.
+const DefaultZoneSearchLimit = 50
+
+// MaxZoneSearchLimit is the most zones SearchZones returns
+// at once.
+const MaxZoneSearchLimit = 500
+
+// ZoneSearch is a page of the zones matched by SearchZones
+// and the total number of zones that match.
+type ZoneSearch struct {
+	Zones ZoneMatchCollection
+	Total int
+}
+
+// SearchZones gets a page of the zones of a saved state
+// that match filter, skipping the first offset zones. The
+// zero ZoneFilter matches every zone of the state. A limit
+// of zero uses DefaultZoneSearchLimit, and a limit over
+// MaxZoneSearchLimit uses MaxZoneSearchLimit.
+//
+// Zones with an old effective date have not been reissued
+// by the NWS in a long time and are candidates for a
+// resync.
+func (s *Service) SearchZones(ctx context.Context, stateID string, filter ZoneFilter, limit int, offset int) (ZoneSearch, error) {
+	stateID = strings.ToUpper(stateID)
+
+	before, after := filter.EffectiveBefore, filter.EffectiveAfter
+	if before != nil && after != nil && !after.Before(*before) {
+		return ZoneSearch{}, &Error{
+			error:      fmt.Errorf("effective after %v is not before %v", *after, *before),
+			msg:        "effectiveAfter must be before effectiveBefore",
+			statusCode: http.StatusBadRequest,
+		}
+	}
+
+	if _, err := s.Store.SelectEntity(ctx, stateID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ZoneSearch{}, &Error{
+				error:      fmt.Errorf("state not found in database (stateID=%q): %w", stateID, err),
+				msg:        fmt.Sprintf("%s not found", stateID),
+				statusCode: http.StatusNotFound,
+			}
+		}
+
+		return ZoneSearch{}, fmt.Errorf("failed to select state in database (stateID=%q): %w", stateID, err)
+	}
+
+	if limit <= 0 {
+		limit = DefaultZoneSearchLimit
+	}
+	if limit > MaxZoneSearchLimit {
+		limit = MaxZoneSearchLimit
+	}
+
+	filter.Query = strings.TrimSpace(filter.Query)
+	zones, total, err := s.Store.SearchZones(ctx, stateID, filter, limit, offset)
+	if err != nil {
+		return ZoneSearch{}, fmt.Errorf("failed to search zones (stateID=%q, query=%q): %w", stateID, filter.Query, err)
+	}
+
+	return ZoneSearch{Zones: zones, Total: total}, nil
+}
+
 type SyncResult struct {
 	State     string
 	Inserts   []Zone
@@ -80,6 +350,17 @@ type SyncResult struct {
 	Deletes   []Zone
 	Fails     []SyncZoneFailure
 	UpdatedAt time.Time
+
+	// The fields that changed for each zone
+	// in Updates.
+	Changes []ZoneChange
+}
+
+// ZoneChange is the fields of a zone that were
+// changed by a sync.
+type ZoneChange struct {
+	URI    string   `json:"uri"`
+	Fields []string `json:"fields"`
 }
 
 type SyncZoneFailure struct {
@@ -89,6 +370,10 @@ type SyncZoneFailure struct {
 }
 
 func (s *Service) Sync(ctx context.Context, stateID string) (SyncResult, error) {
+	if s.Pool == nil {
+		return SyncResult{}, ErrNilPool
+	}
+
 	stateID = strings.ToUpper(stateID)
 
 	// Selext state from database to make
@@ -109,7 +394,7 @@ func (s *Service) Sync(ctx context.Context, stateID string) (SyncResult, error)
 	// Get the up to date data for zones.
 	// At this point every Zone in updatedZones
 	// has an unset Geometry.
-	updatedZones, err := s.zones(stateID)
+	updatedZones, err := s.zones(ctx, stateID)
 	if err != nil {
 		return SyncResult{}, fmt.Errorf("failed to get zones (stateID=%q): %w", stateID, err)
 	}
@@ -117,7 +402,7 @@ func (s *Service) Sync(ctx context.Context, stateID string) (SyncResult, error)
 	// Write the state updates to the
 	// database.
 	state.TotalZones = len(updatedZones)
-	if _, err = s.Store.UpdateEntity(ctx, &state); err != nil {
+	if _, err = s.Store.UpdateEntity(ctx, &state, s.now().UTC()); err != nil {
 		return SyncResult{}, fmt.Errorf("failed to update state (state.ID=%q): %w", state.ID, err)
 	}
 
@@ -139,6 +424,134 @@ func (s *Service) Sync(ctx context.Context, stateID string) (SyncResult, error)
 	}), nil
 }
 
+// MaxRetryZones is the most zones RetryZones accepts
+// at once.
+const MaxRetryZones = 100
+
+// RetryZones fetches and writes only the zones with the
+// URIs uris for a saved state. It is used to retry the
+// zones that failed in a Save or Sync without syncing the
+// whole state. Zones already stored are updated, and all
+// other zones are inserted.
+//
+// Any errors that occur while fetching or persisting a
+// zone will be recorded as a SyncZoneFailure and stored
+// in the SyncResult.Fails field. If a fetched zone is not
+// in the state, a 400 is returned and no zone is written.
+func (s *Service) RetryZones(ctx context.Context, stateID string, uris []string) (SyncResult, error) {
+	if s.Pool == nil {
+		return SyncResult{}, ErrNilPool
+	}
+
+	stateID = strings.ToUpper(stateID)
+
+	if len(uris) == 0 || len(uris) > MaxRetryZones {
+		return SyncResult{}, &Error{
+			error:      fmt.Errorf("retrying %d zones, must be between 1 and %d", len(uris), MaxRetryZones),
+			msg:        fmt.Sprintf("Between 1 and %d zone uris are required", MaxRetryZones),
+			statusCode: http.StatusBadRequest,
+		}
+	}
+
+	if _, err := s.Store.SelectEntity(ctx, stateID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return SyncResult{}, &Error{
+				error:      fmt.Errorf("state not found in database (stateID=%q): %w", stateID, err),
+				msg:        fmt.Sprintf("%s not found", stateID),
+				statusCode: http.StatusNotFound,
+			}
+		}
+
+		return SyncResult{}, fmt.Errorf("failed to select state in database (stateID=%q): %w", stateID, err)
+	}
+
+	storedZones, err := s.Store.SelectZonesWhereState(ctx, stateID)
+	if err != nil {
+		return SyncResult{}, fmt.Errorf("failed to select zones in database (stateID=%q): %w", stateID, err)
+	}
+
+	// Use the stored zone if there is one so
+	// it is updated rather than inserted.
+	zones := []Zone{}
+	for _, uri := range uris {
+		if zone, ok := storedZones[uri]; ok {
+			zones = append(zones, zone)
+			continue
+		}
+
+		zone, err := zoneFromURI(uri)
+		if err != nil {
+			return SyncResult{}, &Error{
+				error:      err,
+				msg:        fmt.Sprintf("%s is not a zone uri", uri),
+				statusCode: http.StatusBadRequest,
+			}
+		}
+		zones = append(zones, zone)
+	}
+
+	fetcher := NewFetcher(s.Client, s.Pool, s.Store, len(zones))
+	defer fetcher.close()
+
+	fetchResult := fetcher.FetchEach(ctx, zones)
+
+	// A uri can name a zone of another state. Nothing is
+	// written unless every fetched zone is in this state.
+	for _, z := range fetchResult.Zones {
+		if z.State != stateID {
+			return SyncResult{}, &Error{
+				error:      fmt.Errorf("zone %q is in state %q, not %q", z.URI, z.State, stateID),
+				msg:        fmt.Sprintf("%s is not a zone of %s", z.URI, stateID),
+				statusCode: http.StatusBadRequest,
+			}
+		}
+	}
+
+	result := SyncResult{
+		State:     stateID,
+		Inserts:   []Zone{},
+		Updates:   []Zone{},
+		Deletes:   []Zone{},
+		Fails:     []SyncZoneFailure{},
+		UpdatedAt: s.now().UTC(),
+	}
+
+	for uri, err := range fetchResult.Fails {
+		result.Fails = append(result.Fails, SyncZoneFailure{
+			URI: uri,
+			Op:  "fetch",
+			err: err,
+		})
+	}
+
+	for _, z := range fetchResult.Zones {
+		if z.ID == 0 {
+			if err := s.Store.InsertZoneTx(ctx, &z, s.now().UTC()); err != nil {
+				result.Fails = append(result.Fails, SyncZoneFailure{
+					URI: z.URI,
+					Op:  "insert",
+					err: err,
+				})
+			} else {
+				result.Inserts = append(result.Inserts, z)
+			}
+			continue
+		}
+
+		if err := s.Store.UpdateZoneTx(ctx, &z, s.now().UTC()); err != nil {
+			result.Fails = append(result.Fails, SyncZoneFailure{
+				URI: z.URI,
+				Op:  "update",
+				err: err,
+			})
+		} else {
+			result.Updates = append(result.Updates, z)
+		}
+	}
+
+	return result, nil
+}
+
 type writeDeltaParams struct {
 	stateID      string
 	updatedZones []Zone
@@ -160,6 +573,14 @@ type writeDeltaParams struct {
 func (s *Service) writeDelta(ctx context.Context, p writeDeltaParams) SyncResult {
 	delta := s.delta(p.updatedZones, p.storedZones)
 
+	// Log the size of the delta so operators can see
+	// how much the zones of a state churn between syncs.
+	log.Printf("state sync delta (state=%s): inserts=%d updates=%d deletes=%d\n",
+		p.stateID,
+		len(delta.Insert),
+		len(delta.Update),
+		len(delta.Delete))
+
 	fetcher := NewFetcher(s.Client, s.Pool, s.Store, delta.TotalInsertUpdates())
 	defer fetcher.close()
 
@@ -175,6 +596,7 @@ func (s *Service) writeDelta(ctx context.Context, p writeDeltaParams) SyncResult
 		Deletes:   []Zone{},
 		Fails:     []SyncZoneFailure{},
 		UpdatedAt: p.updatedAt,
+		Changes:   []ZoneChange{},
 	}
 
 	// Record any errors while fetching the
@@ -190,7 +612,7 @@ func (s *Service) writeDelta(ctx context.Context, p writeDeltaParams) SyncResult
 	// Insert all the new zones.
 	for _, zone := range delta.Insert {
 		if z, ok := fetchResult.Zones[zone.URI]; ok {
-			if err := s.Store.InsertZoneTx(ctx, &z); err != nil {
+			if err := s.Store.InsertZoneTx(ctx, &z, s.now().UTC()); err != nil {
 				result.Fails = append(result.Fails, SyncZoneFailure{
 					URI: z.URI,
 					Op:  "insert",
@@ -205,7 +627,27 @@ func (s *Service) writeDelta(ctx context.Context, p writeDeltaParams) SyncResult
 	// Updated all the expired zones.
 	for _, zone := range delta.Update {
 		if z, ok := fetchResult.Zones[zone.URI]; ok {
-			if err := s.Store.UpdateZoneTx(ctx, &z); err != nil {
+			fields := delta.Changes[z.URI]
+
+			stored, err := s.Store.SelectGeometry(ctx, z.ID)
+			if err != nil {
+				result.Fails = append(result.Fails, SyncZoneFailure{
+					URI: z.URI,
+					Op:  "select geometry",
+					err: err,
+				})
+				continue
+			}
+			// Most updates only bump the effective date.
+			// Skip rewriting the perimeters if the
+			// geometry is unchanged.
+			update := s.Store.UpdateZoneMetadata
+			if !stored.Equal(z.Geometry) {
+				fields = append(fields, FieldGeometry)
+				update = s.Store.UpdateZoneTx
+			}
+
+			if err := update(ctx, &z, s.now().UTC()); err != nil {
 				result.Fails = append(result.Fails, SyncZoneFailure{
 					URI: z.URI,
 					Op:  "update",
@@ -213,6 +655,10 @@ func (s *Service) writeDelta(ctx context.Context, p writeDeltaParams) SyncResult
 				})
 			} else {
 				result.Updates = append(result.Updates, z)
+				result.Changes = append(result.Changes, ZoneChange{
+					URI:    z.URI,
+					Fields: fields,
+				})
 			}
 		}
 	}
@@ -230,6 +676,13 @@ func (s *Service) writeDelta(ctx context.Context, p writeDeltaParams) SyncResult
 		}
 	}
 
+	log.Printf("state sync result (state=%s): inserted=%d updated=%d deleted=%d failed=%d\n",
+		p.stateID,
+		len(result.Inserts),
+		len(result.Updates),
+		len(result.Deletes),
+		len(result.Fails))
+
 	return result
 }
 
@@ -241,6 +694,7 @@ func (s *Service) delta(updatedZones []Zone, storedZones ZoneURIMap) *ZoneDelta
 
 		if storedZone, ok := storedZones[updatedZone.URI]; ok {
 			if storedZone.EffectiveDate.Before(updatedZone.EffectiveDate) {
+				delta.Changes[storedZone.URI] = storedZone.changedFields(updatedZone)
 				storedZone.CopyUpdateableData(updatedZone)
 				delta.Update = append(delta.Update, storedZone)
 			}
@@ -258,21 +712,33 @@ func (s *Service) delta(updatedZones []Zone, storedZones ZoneURIMap) *ZoneDelta
 	return delta
 }
 
-func (s *Service) zones(stateID string) ([]Zone, error) {
-	zones, err := s.Client.GetZoneCollection(stateID)
+func (s *Service) zones(ctx context.Context, stateID string) ([]Zone, error) {
+	getZones := s.Client.GetZoneCollection
+	if s.InlineGeometry {
+		getZones = s.Client.GetZoneCollectionWithGeometry
+	}
+
+	zones, err := getZones(ctx, stateID)
 	var statusError *app.NWSAPIStatusCodeError
 	switch {
+	case err == nil && len(zones) > s.maxZones():
+		// The zones are written by a worker sized to the
+		// zone count, so a malformed or enormous response
+		// is rejected before any work is started.
+		return nil, &Error{
+			error:      fmt.Errorf("state %q has %d zones, the most allowed is %d", stateID, len(zones), s.maxZones()),
+			msg:        fmt.Sprintf("%s has %d zones, more than the limit of %d", stateID, len(zones), s.maxZones()),
+			statusCode: http.StatusBadGateway,
+		}
 	case err == nil:
 		return zonesFromNWS(zones), nil
-	case errors.As(err, &statusError):
-		if statusError.StatusCode == 400 {
-			return nil, &Error{
-				error:      fmt.Errorf("unsupported state: %w", err),
-				msg:        fmt.Sprintf("%s is not a valid state", stateID),
-				statusCode: http.StatusNotFound,
-			}
+	case errors.Is(err, app.ErrNWSBadRequest):
+		return nil, &Error{
+			error:      fmt.Errorf("unsupported state: %w", err),
+			msg:        fmt.Sprintf("%s is not a valid state", stateID),
+			statusCode: http.StatusNotFound,
 		}
-
+	case errors.As(err, &statusError):
 		return nil, fmt.Errorf("unexpected status code: %w", err)
 	default:
 		return nil, err
@@ -42,16 +42,62 @@ func (s *Store) InsertEntity(ctx context.Context, state Entity) (sql.Result, err
 
 // UpdateEntity writes state to the database
 // as an update. The state UpdatedAt field will be
-// set to the current time in UTC format before
-// writing to the database.
+// set to t before writing to the database.
 //
 // If the state UpdatedAt field is set it will be
 // overwritten.
-func (s *Store) UpdateEntity(ctx context.Context, state *Entity) (sql.Result, error) {
-	state.UpdatedAt = time.Now().UTC()
+func (s *Store) UpdateEntity(ctx context.Context, state *Entity, t time.Time) (sql.Result, error) {
+	state.UpdatedAt = t
 	return state.Update(ctx, s.DB)
 }
 
+// UpdatePriority marks the state with the id stateID
+// as a priority state at t, or removes the mark if
+// priority is false.
+func (s *Store) UpdatePriority(ctx context.Context, stateID string, priority bool, t time.Time) (sql.Result, error) {
+	e := Entity{ID: stateID}
+	return e.UpdatePriority(ctx, s.DB, priority, t)
+}
+
+// SelectGeometry selects the Geometry stored for
+// the zone with the provided ID (zoneID).
+func (s *Store) SelectGeometry(ctx context.Context, zoneID int) (Geometry, error) {
+	g := Geometry{}
+	if err := g.Select(ctx, s.DB, zoneID); err != nil {
+		return nil, err
+	}
+
+	return g, nil
+}
+
+// AuditGeometry selects the zones for a given state
+// (stateID) that have missing or invalid geometry.
+func (s *Store) AuditGeometry(ctx context.Context, stateID string) (GeometryIssueCollection, error) {
+	issues := GeometryIssueCollection{}
+	if err := issues.Select(ctx, s.DB, stateID); err != nil {
+		return nil, err
+	}
+
+	return issues, nil
+}
+
+// SearchZones selects a page of the zones of a
+// state (stateID) that match filter, and the total
+// number of zones that match.
+func (s *Store) SearchZones(ctx context.Context, stateID string, filter ZoneFilter, limit int, offset int) (ZoneMatchCollection, int, error) {
+	matches := ZoneMatchCollection{}
+	if err := matches.Select(ctx, s.DB, stateID, filter, limit, offset); err != nil {
+		return nil, 0, err
+	}
+
+	total, err := matches.Count(ctx, s.DB, stateID, filter)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return matches, total, nil
+}
+
 // SelectZonesWhereState selects all the zones
 // for a given state (stateID) as a ZoneURIMap.
 func (s *Store) SelectZonesWhereState(ctx context.Context, stateID string) (ZoneURIMap, error) {
@@ -60,9 +106,10 @@ func (s *Store) SelectZonesWhereState(ctx context.Context, stateID string) (Zone
 }
 
 // InsertZoneTx writes zone to the database.
-// The zone ID, CreatedAt, and UpdatedAt field
-// will be set. If these are set before calling
-// the func, they will be ignored and overwritten.
+// The zone ID will be set, and the CreatedAt and
+// UpdatedAt fields will be set to t. If these are
+// set before calling the func, they will be
+// ignored and overwritten.
 //
 // When a zone is inserted, any lonely alerts
 // associated with zone will be deleted from the
@@ -74,8 +121,10 @@ func (s *Store) SelectZonesWhereState(ctx context.Context, stateID string) (Zone
 //
 // InsertZoneTx is wrapped in a database transaction.
 // If any operations fail the database will roll back.
-func (s *Store) InsertZoneTx(ctx context.Context, zone *Zone) error {
+func (s *Store) InsertZoneTx(ctx context.Context, zone *Zone, t time.Time) error {
 	return s.tx(ctx, func(tx *sql.Tx) error {
+		zone.CreatedAt = t
+		zone.UpdatedAt = t
 		if err := zone.Insert(ctx, tx); err != nil {
 			return err
 		}
@@ -109,15 +158,27 @@ func (s *Store) InsertZoneTx(ctx context.Context, zone *Zone) error {
 // UpdateZoneTx is wrapped in a database transaction.
 // If any operations fail the database will roll back.
 //
-// If the zone UpdatedAt field is set it will be
-// overwritten.
-func (s *Store) UpdateZoneTx(ctx context.Context, zone *Zone) error {
+// The zone UpdatedAt field will be set to t. If
+// it is set it will be overwritten.
+func (s *Store) UpdateZoneTx(ctx context.Context, zone *Zone, t time.Time) error {
 	return s.tx(ctx, func(tx *sql.Tx) error {
-		zone.UpdatedAt = time.Now().UTC()
+		zone.UpdatedAt = t
 		return zone.Update(ctx, tx)
 	})
 }
 
+// UpdateZoneMetadata writes zone to the database
+// as a update without rewriting its Geometry. It
+// is cheaper than UpdateZoneTx and should be used
+// when the Geometry of zone is unchanged.
+//
+// The zone UpdatedAt field will be set to t. If
+// it is set it will be overwritten.
+func (s *Store) UpdateZoneMetadata(ctx context.Context, zone *Zone, t time.Time) error {
+	zone.UpdatedAt = t
+	return zone.UpdateMetadata(ctx, s.DB)
+}
+
 // DeleteZone deletes the zone with the provided
 // ID (zoneID).
 func (s *Store) DeleteZone(ctx context.Context, zoneID int) error {
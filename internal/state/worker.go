@@ -2,24 +2,31 @@ package state
 
 import (
 	"context"
+	"sort"
 
-	"github.com/cicconee/weather-app/internal/nws"
+	"github.com/cicconee/weather-app/internal/app"
 	"github.com/cicconee/weather-app/internal/pool"
 )
 
 type worker struct {
-	client *nws.Client
+	client ZoneAPI
 	p      *pool.Pool
 	s      *Store
+
+	// clock is the time zones are written at. Nil uses the
+	// system time.
+	clock app.Clock
+
 	dataCh chan Zone
 	failCh chan SaveZoneFailure
 }
 
-func newWorker(c *nws.Client, p *pool.Pool, s *Store, zoneCount int) *worker {
+func newWorker(c ZoneAPI, p *pool.Pool, s *Store, clock app.Clock, zoneCount int) *worker {
 	return &worker{
 		client: c,
 		p:      p,
 		s:      s,
+		clock:  clock,
 		dataCh: make(chan Zone, zoneCount),
 		failCh: make(chan SaveZoneFailure, zoneCount),
 	}
@@ -62,7 +69,7 @@ func (w *worker) SaveEach(ctx context.Context, zones []Zone) SaveZoneResult {
 	for range zones {
 		select {
 		case zone := <-w.dataCh:
-			if err := w.s.InsertZoneTx(ctx, &zone); err != nil {
+			if err := w.s.InsertZoneTx(ctx, &zone, app.Now(w.clock).UTC()); err != nil {
 				fails = append(fails, zone.SaveZoneFailure(err))
 			} else {
 				writes = append(writes, zone)
@@ -72,6 +79,16 @@ func (w *worker) SaveEach(ctx context.Context, zones []Zone) SaveZoneResult {
 		}
 	}
 
+	// Zones are written in the order their fetch
+	// finished. Sort them so the result is the same
+	// on every run.
+	sort.Slice(writes, func(i, j int) bool {
+		return writes[i].URI < writes[j].URI
+	})
+	sort.Slice(fails, func(i, j int) bool {
+		return fails[i].URI < fails[j].URI
+	})
+
 	return SaveZoneResult{
 		Writes: writes,
 		Fails:  fails,
@@ -79,6 +96,13 @@ func (w *worker) SaveEach(ctx context.Context, zones []Zone) SaveZoneResult {
 }
 
 func (w *worker) Fetch(ctx context.Context, z Zone) {
+	// The geometry was included with the zone,
+	// there is nothing to fetch.
+	if len(z.Geometry) > 0 {
+		w.finish(z)
+		return
+	}
+
 	w.p.Add(func() {
 		// Check if context has already been
 		// cancelled or timed out before executing
@@ -88,7 +112,7 @@ func (w *worker) Fetch(ctx context.Context, z Zone) {
 			return
 		}
 
-		zone, err := w.client.GetZone(z.Type, z.Code)
+		zone, err := w.client.GetZone(ctx, z.Type, z.Code)
 		if err != nil {
 			w.fail(z, err)
 			return
@@ -3,7 +3,12 @@ package state
 import (
 	"context"
 	"database/sql"
+	"fmt"
+	"strings"
 	"time"
+
+	"github.com/cicconee/weather-app/internal/database"
+	"github.com/cicconee/weather-app/internal/nws"
 )
 
 type Zone struct {
@@ -29,6 +34,55 @@ func (z *Zone) CopyUpdateableData(c Zone) {
 	z.Geometry = c.Geometry
 }
 
+// zoneFromURI returns a Zone with the URI, Type and
+// Code set from a NWS zone URI (e.g.
+// "https://api.weather.gov/zones/forecast/TXZ001").
+func zoneFromURI(uri string) (Zone, error) {
+	prefix := nws.API + "/zones/"
+	if !strings.HasPrefix(uri, prefix) {
+		return Zone{}, fmt.Errorf("not a zone uri: %q", uri)
+	}
+
+	parts := strings.Split(strings.TrimPrefix(uri, prefix), "/")
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return Zone{}, fmt.Errorf("not a zone uri: %q", uri)
+	}
+
+	return Zone{URI: uri, Type: parts[0], Code: parts[1]}, nil
+}
+
+// The fields of a zone that can change between syncs.
+const (
+	FieldCode          = "code"
+	FieldType          = "type"
+	FieldName          = "name"
+	FieldEffectiveDate = "effective_date"
+	FieldState         = "state"
+	FieldGeometry      = "geometry"
+)
+
+// changedFields returns the fields, other than Geometry,
+// that differ between this zone and c.
+func (z *Zone) changedFields(c Zone) []string {
+	fields := []string{}
+	if z.Code != c.Code {
+		fields = append(fields, FieldCode)
+	}
+	if z.Type != c.Type {
+		fields = append(fields, FieldType)
+	}
+	if z.Name != c.Name {
+		fields = append(fields, FieldName)
+	}
+	if !z.EffectiveDate.Equal(c.EffectiveDate) {
+		fields = append(fields, FieldEffectiveDate)
+	}
+	if z.State != c.State {
+		fields = append(fields, FieldState)
+	}
+	return fields
+}
+
 func (z *Zone) SaveZoneFailure(err error) SaveZoneFailure {
 	return SaveZoneFailure{
 		URI: z.URI,
@@ -42,9 +96,6 @@ func (z *Zone) Insert(ctx context.Context, db QueryRower) error {
 		VALUES($1, $2, $3, $4, $5, $6, $7, $8)
 		RETURNING id`
 
-	z.CreatedAt = time.Now().UTC()
-	z.UpdatedAt = time.Now().UTC()
-
 	if err := db.QueryRowContext(ctx, query,
 		z.URI,
 		z.Code,
@@ -81,6 +132,31 @@ type QueryRowExecer interface {
 //
 // Update assumes all fields are set correctly.
 func (z *Zone) Update(ctx context.Context, db QueryRowExecer) error {
+	if err := z.UpdateMetadata(ctx, db); err != nil {
+		return err
+	}
+
+	if _, err := z.Geometry.Delete(ctx, db, z.ID); err != nil {
+		return err
+	}
+
+	for _, perimeter := range z.Geometry {
+		perimeter.ZoneID = z.ID
+
+		if err := perimeter.Insert(ctx, db); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// UpdateMetadata will update this Zone in the
+// database without touching its Geometry. Use it
+// when the Geometry has not changed.
+//
+// UpdateMetadata assumes all fields are set correctly.
+func (z *Zone) UpdateMetadata(ctx context.Context, db Execer) error {
 	query := `
 		UPDATE state_zones 
 		SET uri = $1,
@@ -92,7 +168,7 @@ func (z *Zone) Update(ctx context.Context, db QueryRowExecer) error {
 			updated_at = $7
 		WHERE id = $8`
 
-	if _, err := db.ExecContext(ctx, query,
+	_, err := db.ExecContext(ctx, query,
 		z.URI,
 		z.Code,
 		z.Type,
@@ -101,23 +177,9 @@ func (z *Zone) Update(ctx context.Context, db QueryRowExecer) error {
 		z.State,
 		z.UpdatedAt,
 		z.ID,
-	); err != nil {
-		return err
-	}
-
-	if _, err := z.Geometry.Delete(ctx, db, z.ID); err != nil {
-		return err
-	}
-
-	for _, perimeter := range z.Geometry {
-		perimeter.ZoneID = z.ID
-
-		if err := perimeter.Insert(ctx, db); err != nil {
-			return err
-		}
-	}
+	)
 
-	return nil
+	return err
 }
 
 // Delete will delete this zone from the
@@ -156,14 +218,17 @@ func (z ZoneURIMap) Select(ctx context.Context, db *sql.DB, state string) error
 	if err != nil {
 		return err
 	}
-	defer rows.Close()
 
-	for rows.Next() {
+	zones, err := database.ScanRows(rows, func(rows *sql.Rows) (Zone, error) {
 		var e Zone
-		if err := e.scan(rows.Scan); err != nil {
-			return err
-		}
+		err := e.scan(rows.Scan)
+		return e, err
+	})
+	if err != nil {
+		return err
+	}
 
+	for _, e := range zones {
 		z[e.URI] = e
 	}
 
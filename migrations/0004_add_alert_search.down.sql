@@ -0,0 +1,2 @@
+DROP INDEX alerts_search_idx;
+ALTER TABLE alerts DROP COLUMN search;
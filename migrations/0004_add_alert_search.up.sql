@@ -0,0 +1,8 @@
+ALTER TABLE alerts ADD COLUMN search TSVECTOR GENERATED ALWAYS AS (
+    setweight(to_tsvector('english', coalesce(event, '')), 'A') ||
+    setweight(to_tsvector('english', coalesce(headline, '')), 'B') ||
+    setweight(to_tsvector('english', coalesce(area_desc, '')), 'C') ||
+    setweight(to_tsvector('english', coalesce(description, '')), 'D')
+) STORED;
+
+CREATE INDEX alerts_search_idx ON alerts USING GIN(search);
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS state_zone_perimeters_boundary_idx;
+DROP INDEX IF EXISTS alerts_boundary_idx;
+DROP INDEX IF EXISTS gridpoints_boundary_idx;
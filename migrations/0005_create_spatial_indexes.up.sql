@@ -0,0 +1,3 @@
+CREATE INDEX IF NOT EXISTS gridpoints_boundary_idx ON gridpoints USING GIST(boundary);
+CREATE INDEX IF NOT EXISTS alerts_boundary_idx ON alerts USING GIST(boundary);
+CREATE INDEX IF NOT EXISTS state_zone_perimeters_boundary_idx ON state_zone_perimeters USING GIST(boundary);
@@ -0,0 +1,2 @@
+ALTER TABLE admins DROP COLUMN deactivated;
+ALTER TABLE admins DROP COLUMN superadmin;
@@ -0,0 +1,2 @@
+ALTER TABLE admins ADD COLUMN superadmin BOOL NOT NULL DEFAULT false;
+ALTER TABLE admins ADD COLUMN deactivated BOOL NOT NULL DEFAULT false;
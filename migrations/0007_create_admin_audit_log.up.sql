@@ -0,0 +1,11 @@
+CREATE TABLE admin_audit_log(
+    id SERIAL PRIMARY KEY,
+    admin_id INTEGER NOT NULL,
+    action VARCHAR(255) NOT NULL,
+    target TEXT NOT NULL,
+    result VARCHAR(255) NOT NULL,
+    status INTEGER NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX admin_audit_log_created_at_idx ON admin_audit_log(created_at);
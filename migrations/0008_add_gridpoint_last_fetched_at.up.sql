@@ -0,0 +1 @@
+ALTER TABLE gridpoints ADD COLUMN last_fetched_at TIMESTAMPTZ NOT NULL DEFAULT now();
@@ -0,0 +1 @@
+DROP TABLE states_priority;
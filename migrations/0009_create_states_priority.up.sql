@@ -0,0 +1,5 @@
+CREATE TABLE states_priority (
+    state CHAR(2) PRIMARY KEY,
+    created_at TIMESTAMPTZ NOT NULL,
+    FOREIGN KEY(state) REFERENCES states(id) ON DELETE CASCADE
+);
@@ -0,0 +1,9 @@
+CREATE TABLE alert_sync_runs(
+    id SERIAL PRIMARY KEY,
+    states TEXT[] NOT NULL,
+    total_writes INTEGER NOT NULL,
+    fails INTEGER NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX alert_sync_runs_created_at_idx ON alert_sync_runs(created_at);
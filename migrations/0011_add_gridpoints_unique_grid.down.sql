@@ -0,0 +1 @@
+ALTER TABLE gridpoints DROP CONSTRAINT gridpoints_grid_key;
@@ -0,0 +1,4 @@
+DELETE FROM gridpoints a USING gridpoints b
+WHERE a.grid_id = b.grid_id AND a.grid_x = b.grid_x AND a.grid_y = b.grid_y AND a.id > b.id;
+
+ALTER TABLE gridpoints ADD CONSTRAINT gridpoints_grid_key UNIQUE(grid_id, grid_x, grid_y);
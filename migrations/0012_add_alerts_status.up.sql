@@ -0,0 +1 @@
+ALTER TABLE alerts ADD COLUMN status VARCHAR(255) NOT NULL DEFAULT 'Actual';
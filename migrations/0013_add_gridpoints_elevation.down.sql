@@ -0,0 +1 @@
+ALTER TABLE gridpoints DROP COLUMN elevation;
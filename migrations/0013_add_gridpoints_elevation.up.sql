@@ -0,0 +1 @@
+ALTER TABLE gridpoints ADD COLUMN elevation DOUBLE PRECISION;
@@ -0,0 +1,2 @@
+ALTER TABLE gridpoints DROP COLUMN relative_location_city,
+    DROP COLUMN relative_location_state;
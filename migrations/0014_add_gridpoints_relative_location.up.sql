@@ -0,0 +1,2 @@
+ALTER TABLE gridpoints ADD COLUMN relative_location_city TEXT NOT NULL DEFAULT '',
+    ADD COLUMN relative_location_state TEXT NOT NULL DEFAULT '';
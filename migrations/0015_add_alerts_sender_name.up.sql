@@ -0,0 +1 @@
+ALTER TABLE alerts ADD COLUMN sender_name VARCHAR(255) NOT NULL DEFAULT '';
@@ -0,0 +1 @@
+ALTER TABLE gridpoints ADD COLUMN last_modified TIMESTAMPTZ NULL;
@@ -0,0 +1 @@
+ALTER TABLE alerts DROP COLUMN parameters;
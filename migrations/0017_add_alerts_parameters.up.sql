@@ -0,0 +1 @@
+ALTER TABLE alerts ADD COLUMN parameters JSONB NOT NULL DEFAULT '{}';
@@ -0,0 +1 @@
+ALTER TABLE gridpoints ADD COLUMN fetch_count BIGINT NOT NULL DEFAULT 0;
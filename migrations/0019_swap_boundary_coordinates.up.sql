@@ -0,0 +1,4 @@
+UPDATE gridpoints SET boundary = regexp_replace(boundary::text, '\(([^(),]+),([^(),]+)\)', '(\2,\1)', 'g')::polygon;
+UPDATE state_zone_perimeters SET boundary = regexp_replace(boundary::text, '\(([^(),]+),([^(),]+)\)', '(\2,\1)', 'g')::polygon;
+UPDATE state_zone_holes SET boundary = regexp_replace(boundary::text, '\(([^(),]+),([^(),]+)\)', '(\2,\1)', 'g')::polygon;
+UPDATE alerts SET boundary = regexp_replace(boundary::text, '\(([^(),]+),([^(),]+)\)', '(\2,\1)', 'g')::polygon WHERE boundary IS NOT NULL;
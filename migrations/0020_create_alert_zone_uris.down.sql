@@ -0,0 +1 @@
+DROP TABLE alert_zone_uris;
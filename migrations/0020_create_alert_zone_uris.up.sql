@@ -0,0 +1,14 @@
+CREATE TABLE alert_zone_uris (
+    alert_id TEXT NOT NULL,
+    sz_uri TEXT NOT NULL,
+    FOREIGN KEY(alert_id) REFERENCES alerts(id) ON DELETE CASCADE,
+    PRIMARY KEY(alert_id, sz_uri)
+);
+
+CREATE INDEX alert_zone_uris_sz_uri_idx ON alert_zone_uris(sz_uri);
+
+INSERT INTO alert_zone_uris(alert_id, sz_uri)
+SELECT alert_id, sz_uri FROM lonely_alerts
+UNION
+SELECT alert_zones.alert_id, state_zones.uri FROM alert_zones, state_zones
+WHERE alert_zones.sz_id = state_zones.id;